@@ -0,0 +1,96 @@
+// File: branch.go
+// Package: cmd
+
+// Program Description:
+// This file handles the parsing of the branch command flags and arguments
+// "jit branch" lists the branches, marking the current one with "*". "jit branch -m [<old>] <new>"
+// renames a branch and "jit branch -c [<old>] <new>" copies it, the current branch when <old> is
+// not given; -M and -C do the same over an existing branch.
+
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"jit/internal"
+	"jit/pkg/util"
+)
+
+// branchOptions are the options of "jit branch".
+type branchOptions struct {
+	move      bool
+	forceMove bool
+	copy      bool
+	forceCopy bool
+}
+
+func newBranchFlags(options *branchOptions) *flag.FlagSet {
+	branchCmd := flag.NewFlagSet(util.Branch, flag.ContinueOnError)
+	branchCmd.BoolVar(&options.move, "m", false, "Rename a branch, with its upstream configuration")
+	branchCmd.BoolVar(&options.forceMove, "M", false, "Rename a branch even if the new name already exists")
+	branchCmd.BoolVar(&options.copy, "c", false, "Copy a branch, with its upstream configuration")
+	branchCmd.BoolVar(&options.forceCopy, "C", false, "Copy a branch even if the new name already exists")
+	return branchCmd
+}
+
+// BranchCommand runs "jit branch", "jit branch (-m | -M) [<old>] <new>" or
+// "jit branch (-c | -C) [<old>] <new>".
+func BranchCommand(streams *Streams, args []string) error {
+	var options branchOptions
+	branchCmd := newBranchFlags(&options)
+	branchCmd.SetOutput(streams.Stderr)
+	if err := parseFlags(branchCmd, args); err != nil {
+		return err
+	}
+
+	modes := 0
+	for _, set := range []bool{options.move, options.forceMove, options.copy, options.forceCopy} {
+		if set {
+			modes++
+		}
+	}
+	if modes > 1 || (modes == 0 && branchCmd.NArg() > 0) || (modes == 1 && (branchCmd.NArg() < 1 || branchCmd.NArg() > 2)) {
+		return &ExitError{Code: ExitUsage, Err: errors.New("usage: jit branch [(-m | -M | -c | -C) [<old-branch>] <new-branch>]")}
+	}
+
+	jitDir, _, discoverErr := streams.discoverRepository()
+	if discoverErr != nil {
+		return discoverErr
+	}
+
+	current, _, headErr := internal.ReadHead(jitDir)
+	if headErr != nil && !errors.Is(headErr, internal.ErrNoBranch) {
+		return headErr
+	}
+	if modes == 0 {
+		return branchList(streams, jitDir, current)
+	}
+
+	oldName, newName := current, branchCmd.Arg(0)
+	if branchCmd.NArg() == 2 {
+		oldName, newName = branchCmd.Arg(0), branchCmd.Arg(1)
+	}
+	if oldName == "" {
+		return errors.New("HEAD does not point to a branch; name the branch to rename or copy")
+	}
+	if options.copy || options.forceCopy {
+		return internal.CopyBranch(jitDir, oldName, newName, options.forceCopy)
+	}
+	return internal.RenameBranch(jitDir, oldName, newName, options.forceMove)
+}
+
+func branchList(streams *Streams, jitDir string, current string) error {
+	names, listErr := internal.ListBranches(jitDir)
+	if listErr != nil {
+		return listErr
+	}
+	for _, name := range names {
+		marker := "  "
+		if name == current {
+			marker = "* "
+		}
+		_, _ = fmt.Fprintln(streams.Stdout, marker+name)
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+// File: config.go
+// Package: cmd
+
+// Program Description:
+// This file handles the parsing of the config command flags and arguments
+// It supports the get, set, unset and list actions on the system, global or repository config file,
+// with optional --bool and --int type coercion of values.
+
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"jit/internal"
+	"jit/pkg/util"
+	"strconv"
+)
+
+// configOptions are the options of "jit config".
+type configOptions struct {
+	local  bool
+	global bool
+	system bool
+	bool   bool
+	int    bool
+	all    bool
+	add    bool
+	list   bool
+}
+
+func newConfigFlags(options *configOptions) *flag.FlagSet {
+	configCmd := flag.NewFlagSet(util.Config, flag.ContinueOnError)
+	configCmd.BoolVar(&options.local, "local", false, "Use the repository config file")
+	configCmd.BoolVar(&options.global, "global", false, "Use the config file of the current user, ~/.jitconfig")
+	configCmd.BoolVar(&options.system, "system", false, "Use the system-wide config file, /etc/jitconfig")
+	configCmd.BoolVar(&options.bool, "bool", false, "Ensure the value is a boolean and print it as true or false")
+	configCmd.BoolVar(&options.int, "int", false, "Ensure the value is a number (k, m and g suffixes allowed) and print it in decimal")
+	configCmd.BoolVar(&options.all, "all", false, "With get, print every value of a multi-valued key. With unset, remove every value")
+	configCmd.BoolVar(&options.add, "add", false, "With set, add a value to a multi-valued key instead of replacing it")
+	configCmd.BoolVar(&options.list, "list", false, "List every setting")
+	configCmd.BoolVar(&options.list, "l", false, "List every setting")
+	return configCmd
+}
+
+// ConfigCommand runs "jit config [scope] [type] <get|set|unset|list> [key] [value]".
+func ConfigCommand(streams *Streams, args []string) error {
+	var options configOptions
+	configCmd := newConfigFlags(&options)
+	configCmd.SetOutput(streams.Stderr)
+	if err := parseFlags(configCmd, args); err != nil {
+		return err
+	}
+
+	// Flags may follow the action as well as precede it.
+	action := configCmd.Arg(0)
+	if configCmd.NArg() > 0 {
+		if err := parseFlags(configCmd, configCmd.Args()[1:]); err != nil {
+			return err
+		}
+	}
+	if options.list {
+		action = "list"
+	}
+	if options.bool && options.int {
+		return errors.New("--bool and --int cannot be used together")
+	}
+
+	scope, scoped, scopeErr := configScope(options)
+	if scopeErr != nil {
+		return scopeErr
+	}
+	jitDir, _, discoverErr := discoverOptionalRepository(streams)
+	if discoverErr != nil {
+		return discoverErr
+	}
+
+	switch action {
+	case "get":
+		return configGet(streams, options, configCmd.Args(), scope, scoped, jitDir)
+	case "set":
+		return configSet(options, configCmd.Args(), scope, jitDir)
+	case "unset":
+		return configUnset(options, configCmd.Args(), scope, jitDir)
+	case "list":
+		return configListEntries(streams, scope, scoped, jitDir)
+	default:
+		return fmt.Errorf("invalid config action %s: use get, set, unset or --list", action)
+	}
+}
+
+// configScope returns the scope selected by --local, --global or --system, and whether one was given.
+// Without a flag, reads use every scope and writes use the repository file.
+func configScope(options configOptions) (internal.ConfigScope, bool, error) {
+	selected := 0
+	scope := internal.ScopeLocal
+	if options.local {
+		selected++
+	}
+	if options.global {
+		selected++
+		scope = internal.ScopeGlobal
+	}
+	if options.system {
+		selected++
+		scope = internal.ScopeSystem
+	}
+	if selected > 1 {
+		return scope, false, errors.New("only one of --local, --global and --system can be used")
+	}
+	return scope, selected == 1, nil
+}
+
+func loadEntries(scope internal.ConfigScope, scoped bool, jitDir string) (*internal.Config, error) {
+	if !scoped {
+		return internal.LoadConfig(jitDir)
+	}
+	path, pathErr := internal.ConfigPath(scope, jitDir)
+	if pathErr != nil {
+		return nil, pathErr
+	}
+	return internal.LoadConfigFile(path, scope)
+}
+
+func configGet(streams *Streams, options configOptions, args []string, scope internal.ConfigScope, scoped bool, jitDir string) error {
+	if len(args) != 1 {
+		return &ExitError{Code: ExitUsage, Err: errors.New("usage: jit config get [--all] <key>")}
+	}
+	config, loadErr := loadEntries(scope, scoped, jitDir)
+	if loadErr != nil {
+		return loadErr
+	}
+
+	values := config.GetAll(args[0])
+	if len(values) == 0 {
+		return &ExitError{Code: ExitFailure}
+	}
+	if !options.all {
+		values = values[len(values)-1:]
+	}
+	for _, value := range values {
+		coerced, coerceErr := coerceConfigValue(options, value)
+		if coerceErr != nil {
+			return coerceErr
+		}
+		_, _ = fmt.Fprintln(streams.Stdout, coerced)
+	}
+	return nil
+}
+
+func configSet(options configOptions, args []string, scope internal.ConfigScope, jitDir string) error {
+	if len(args) != 2 {
+		return &ExitError{Code: ExitUsage, Err: errors.New("usage: jit config set [--add] <key> <value>")}
+	}
+	path, pathErr := internal.ConfigPath(scope, jitDir)
+	if pathErr != nil {
+		return pathErr
+	}
+	value, coerceErr := coerceConfigValue(options, args[1])
+	if coerceErr != nil {
+		return coerceErr
+	}
+	return internal.SetConfigValue(path, args[0], value, options.add)
+}
+
+func configUnset(options configOptions, args []string, scope internal.ConfigScope, jitDir string) error {
+	if len(args) != 1 {
+		return &ExitError{Code: ExitUsage, Err: errors.New("usage: jit config unset [--all] <key>")}
+	}
+	path, pathErr := internal.ConfigPath(scope, jitDir)
+	if pathErr != nil {
+		return pathErr
+	}
+	removed, unsetErr := internal.UnsetConfigValue(path, args[0], options.all)
+	if unsetErr != nil {
+		return unsetErr
+	}
+	if removed == 0 {
+		return &ExitError{Code: ExitConfigKeyNotFound}
+	}
+	return nil
+}
+
+func configListEntries(streams *Streams, scope internal.ConfigScope, scoped bool, jitDir string) error {
+	config, loadErr := loadEntries(scope, scoped, jitDir)
+	if loadErr != nil {
+		return loadErr
+	}
+	for _, entry := range config.Entries() {
+		_, _ = fmt.Fprintf(streams.Stdout, "%s=%s\n", entry.Key, entry.Value)
+	}
+	return nil
+}
+
+// coerceConfigValue validates and normalizes a value according to --bool or --int.
+func coerceConfigValue(options configOptions, value string) (string, error) {
+	switch {
+	case options.bool:
+		parsed, parseErr := internal.ParseConfigBool(value)
+		if parseErr != nil {
+			return "", parseErr
+		}
+		return strconv.FormatBool(parsed), nil
+	case options.int:
+		parsed, parseErr := internal.ParseConfigInt(value)
+		if parseErr != nil {
+			return "", parseErr
+		}
+		return strconv.FormatInt(parsed, 10), nil
+	default:
+		return value, nil
+	}
+}
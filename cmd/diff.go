@@ -0,0 +1,295 @@
+// File: diff.go
+// Package: cmd
+
+// Program Description:
+// This file handles the parsing of the diff command flags and arguments
+// "jit diff --no-index <path> <path>" compares two files or directories on disk and prints the
+// differences as a patch. It exits with status 1 when they differ, as git diff --no-index does.
+// The diff algorithm comes from --diff-algorithm or its shorthands, then diff.algorithm.
+// -M[<n>] reports deleted and added files of similar content as renames, and -C[<n>] reports added
+// files as copies of the changed or deleted files they resemble. --word-diff[=<mode>] and
+// --color-words mark the changed words of each line instead of printing whole lines. -w, -b and
+// --ignore-blank-lines leave whitespace changes out. --binary shows binary changes as a patch that
+// can be applied, instead of "Binary files ... differ". --stat, --numstat and --shortstat print the
+// changed line counts of each file instead of the patch.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"jit/internal"
+	"jit/internal/diff"
+	"jit/pkg/util"
+	"strings"
+)
+
+// diffOptions are the options of "jit diff".
+type diffOptions struct {
+	noIndex    bool
+	algorithm  string
+	patience   bool
+	histogram  bool
+	minimal    bool
+	context    int
+	renames    similarityOption
+	copies     similarityOption
+	wordDiff   wordDiffOption
+	colorWords bool
+	whitespace diff.WhitespaceOptions
+	binary     bool
+	stat       bool
+	numstat    bool
+	shortstat  bool
+	nul        bool
+}
+
+// similarityOption is the value of -M or -C, which may be given without a threshold to use the default.
+type similarityOption struct {
+	set       bool
+	threshold string
+}
+
+func (s *similarityOption) String() string {
+	return s.threshold
+}
+
+func (s *similarityOption) Set(value string) error {
+	if value == "true" {
+		value = ""
+	}
+	s.set, s.threshold = true, value
+	return nil
+}
+
+func (s *similarityOption) IsBoolFlag() bool {
+	return true
+}
+
+// wordDiffOption is the value of --word-diff, which may be given without a mode to mean "plain".
+type wordDiffOption struct {
+	set  bool
+	mode string
+}
+
+func (w *wordDiffOption) String() string {
+	return w.mode
+}
+
+func (w *wordDiffOption) Set(value string) error {
+	if value == "true" {
+		value = ""
+	}
+	w.set, w.mode = true, value
+	return nil
+}
+
+func (w *wordDiffOption) IsBoolFlag() bool {
+	return true
+}
+
+func newDiffFlags(options *diffOptions) *flag.FlagSet {
+	diffCmd := flag.NewFlagSet(util.Diff, flag.ContinueOnError)
+	diffCmd.BoolVar(&options.noIndex, "no-index", false, "Compare the two given paths on disk")
+	diffCmd.StringVar(&options.algorithm, "diff-algorithm", "", "Use the given diff algorithm: myers (the default), minimal, patience or histogram")
+	diffCmd.BoolVar(&options.patience, "patience", false, "Use the patience diff algorithm")
+	diffCmd.BoolVar(&options.histogram, "histogram", false, "Use the histogram diff algorithm")
+	diffCmd.BoolVar(&options.minimal, "minimal", false, "Produce the smallest possible diff")
+	diffCmd.IntVar(&options.context, "U", diff.DefaultContext, "Show `n` lines of context around each change")
+	diffCmd.IntVar(&options.context, "unified", diff.DefaultContext, "Show `n` lines of context around each change")
+	diffCmd.Var(&options.renames, "M", "Detect renames of files at least n% similar (-M<n>, default 50%)")
+	diffCmd.Var(&options.renames, "find-renames", "Detect renames of files at least n% similar (-M<n>, default 50%)")
+	diffCmd.Var(&options.copies, "C", "Detect copies of changed or deleted files at least n% similar (-C<n>, default 50%); implies -M")
+	diffCmd.Var(&options.copies, "find-copies", "Detect copies of changed or deleted files at least n% similar (-C<n>, default 50%); implies -M")
+	diffCmd.Var(&options.wordDiff, "word-diff", "Show changed words, as [-removed-] and {+added+} (--word-diff=plain, the default) or in color (--word-diff=color)")
+	diffCmd.BoolVar(&options.colorWords, "color-words", false, "Show changed words in color; the same as --word-diff=color")
+	diffCmd.BoolVar(&options.whitespace.IgnoreAllSpace, "w", false, "Ignore whitespace when comparing lines")
+	diffCmd.BoolVar(&options.whitespace.IgnoreAllSpace, "ignore-all-space", false, "Ignore whitespace when comparing lines")
+	diffCmd.BoolVar(&options.whitespace.IgnoreSpaceChange, "b", false, "Ignore changes in the amount of whitespace, and whitespace at the end of lines")
+	diffCmd.BoolVar(&options.whitespace.IgnoreSpaceChange, "ignore-space-change", false, "Ignore changes in the amount of whitespace, and whitespace at the end of lines")
+	diffCmd.BoolVar(&options.whitespace.IgnoreBlankLines, "ignore-blank-lines", false, "Ignore changes whose lines are all blank")
+	diffCmd.BoolVar(&options.binary, "binary", false, "Show binary changes as a \"GIT binary patch\" instead of \"Binary files ... differ\"")
+	diffCmd.BoolVar(&options.stat, "stat", false, "Show the number of changed lines of each file with a histogram, instead of the patch")
+	diffCmd.BoolVar(&options.numstat, "numstat", false, "Show the inserted and deleted lines of each file as tab-separated numbers, instead of the patch")
+	diffCmd.BoolVar(&options.shortstat, "shortstat", false, "Show only the total number of changed files, insertions and deletions")
+	diffCmd.BoolVar(&options.nul, "z", false, "With --numstat, end each path with a NUL instead of a newline and write renames as two paths")
+	return diffCmd
+}
+
+// diffShortValueFlags are the one-letter options of diff whose value may be attached, as in -U5 or
+// -M50%.
+var diffShortValueFlags = []string{"U", "M", "C"}
+
+// splitShortValues rewrites options such as -U5 to -U=5, the form the flag package understands,
+// for the one-letter options in shortValueFlags. Options of flags that share their first letter,
+// such as -untracked-files, are left alone.
+func splitShortValues(flags *flag.FlagSet, args []string, shortValueFlags []string) []string {
+	split := make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			return append(split, args[i:]...)
+		}
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if flags.Lookup(name) != nil {
+			split = append(split, arg)
+			continue
+		}
+		for _, name := range shortValueFlags {
+			if strings.HasPrefix(arg, "-"+name) && len(arg) > 2 && arg[2] != '=' {
+				arg = "-" + name + "=" + arg[2:]
+				break
+			}
+		}
+		split = append(split, arg)
+	}
+	return split
+}
+
+// permuteFlags moves the options in args ahead of the operands, as git allows options after the
+// paths, since the flag package stops at the first operand. An option that takes a value and has
+// no "=" takes the next argument with it. Everything after "--" stays an operand.
+func permuteFlags(flags *flag.FlagSet, args []string) []string {
+	options := make([]string, 0, len(args))
+	var operands []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			operands = append(operands, args[i+1:]...)
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			operands = append(operands, arg)
+			continue
+		}
+		options = append(options, arg)
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if hasValue || i+1 == len(args) {
+			continue
+		}
+		if f := flags.Lookup(name); f != nil {
+			if boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !boolFlag.IsBoolFlag() {
+				i++
+				options = append(options, args[i])
+			}
+		}
+	}
+	if len(operands) == 0 {
+		return options
+	}
+	return append(append(options, "--"), operands...)
+}
+
+// DiffCommand runs "jit diff --no-index [<options>] <path> <path>". Options may also follow the
+// paths.
+func DiffCommand(streams *Streams, args []string) error {
+	var options diffOptions
+	diffCmd := newDiffFlags(&options)
+	diffCmd.SetOutput(streams.Stderr)
+	if err := parseFlags(diffCmd, permuteFlags(diffCmd, splitShortValues(diffCmd, args, diffShortValueFlags))); err != nil {
+		return err
+	}
+	if !options.noIndex || diffCmd.NArg() != 2 {
+		return &ExitError{Code: ExitUsage, Err: errors.New("usage: jit diff --no-index [<options>] <path> <path>")}
+	}
+	if options.context < 0 {
+		return &ExitError{Code: ExitUsage, Err: errors.New("-U must not be negative")}
+	}
+
+	algorithm, algorithmErr := diffAlgorithm(streams, options)
+	if algorithmErr != nil {
+		return algorithmErr
+	}
+	diffOpts := internal.DiffOptions{
+		Algorithm:     algorithm,
+		Context:       options.context,
+		Whitespace:    options.whitespace,
+		Binary:        options.binary,
+		FindRenames:   options.renames.set,
+		FindCopies:    options.copies.set,
+		Stat:          options.stat,
+		Numstat:       options.numstat,
+		Shortstat:     options.shortstat,
+		NulTerminated: options.nul,
+	}
+	if options.wordDiff.set && options.colorWords {
+		return &ExitError{Code: ExitUsage, Err: errors.New("--word-diff and --color-words cannot be used together")}
+	}
+	if options.colorWords {
+		diffOpts.WordDiff = diff.WordDiffColor
+	} else if options.wordDiff.set {
+		mode, modeErr := diff.ParseWordDiffMode(options.wordDiff.mode)
+		if modeErr != nil {
+			return &ExitError{Code: ExitUsage, Err: modeErr}
+		}
+		diffOpts.WordDiff = mode
+	}
+	for _, similarity := range []struct {
+		option    similarityOption
+		threshold *int
+	}{{options.renames, &diffOpts.RenameThreshold}, {options.copies, &diffOpts.CopyThreshold}} {
+		if !similarity.option.set {
+			continue
+		}
+		threshold, thresholdErr := diff.ParseSimilarityThreshold(similarity.option.threshold)
+		if thresholdErr != nil {
+			return &ExitError{Code: ExitUsage, Err: thresholdErr}
+		}
+		*similarity.threshold = threshold
+	}
+
+	pairs, pairErr := internal.NoIndexPairs(context.Background(), streams.Dir, diffCmd.Arg(0), diffCmd.Arg(1))
+	if pairErr != nil {
+		return pairErr
+	}
+	changed, writeErr := internal.WriteDiff(streams.Stdout, pairs, diffOpts)
+	if writeErr != nil {
+		return writeErr
+	}
+	if changed {
+		return &ExitError{Code: ExitFailure}
+	}
+	return nil
+}
+
+// diffAlgorithm returns the algorithm selected by the options, or by diff.algorithm when none is
+// given.
+func diffAlgorithm(streams *Streams, options diffOptions) (diff.Algorithm, error) {
+	selected := 0
+	name := options.algorithm
+	if name != "" {
+		selected++
+	}
+	for shorthand, set := range map[diff.Algorithm]bool{diff.AlgorithmPatience: options.patience, diff.AlgorithmHistogram: options.histogram, diff.AlgorithmMinimal: options.minimal} {
+		if set {
+			selected++
+			name = string(shorthand)
+		}
+	}
+	if selected > 1 {
+		return "", &ExitError{Code: ExitUsage, Err: errors.New("only one of --diff-algorithm, --patience, --histogram and --minimal can be used")}
+	}
+
+	if selected == 0 {
+		jitDir, _, discoverErr := discoverOptionalRepository(streams)
+		if discoverErr != nil {
+			return "", discoverErr
+		}
+		config, loadErr := internal.LoadConfig(jitDir)
+		if loadErr != nil {
+			return "", loadErr
+		}
+		configured, _ := config.Get("diff.algorithm")
+		algorithm, parseErr := diff.ParseAlgorithm(configured)
+		if parseErr != nil {
+			return "", fmt.Errorf("invalid diff.algorithm -> %w", parseErr)
+		}
+		return algorithm, nil
+	}
+	algorithm, parseErr := diff.ParseAlgorithm(name)
+	if parseErr != nil {
+		return "", &ExitError{Code: ExitUsage, Err: parseErr}
+	}
+	return algorithm, nil
+}
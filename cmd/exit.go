@@ -0,0 +1,64 @@
+// File: exit.go
+// Package: cmd
+
+// Program Description:
+// This file defines the exit statuses of jit, so scripts can tell failures apart.
+// Commands return errors instead of exiting; Run turns them into a status with exitCode. An
+// ExitError chooses the status explicitly, and well-known errors such as ErrNotARepository are
+// mapped to their own status. Any other error exits with ExitFailure. Hooks and shell aliases
+// make jit exit with their own status.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"jit/internal"
+	"log"
+)
+
+// Exit statuses.
+const (
+	ExitSuccess           = 0   // The command succeeded, or a check such as merge-base --is-ancestor was true.
+	ExitFailure           = 1   // The command failed, or a check such as merge-base --is-ancestor was false.
+	ExitUsage             = 2   // Invalid options or arguments, or an unknown command.
+	ExitConfigKeyNotFound = 5   // config unset found nothing to remove, as with git config.
+	ExitNotARepository    = 128 // The command needs a repository and none was found, as with git.
+)
+
+// ExitError makes jit exit with a specific status. Err, when set, is printed first.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit status %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// exitCode prints the error returned by a command and returns the status jit exits with.
+func exitCode(logger *log.Logger, err error) int {
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		if exitErr.Err != nil {
+			logger.Println(exitErr.Err)
+		}
+		return exitErr.Code
+	}
+	logger.Println(err)
+	switch {
+	case errors.Is(err, internal.ErrNotARepository):
+		return ExitNotARepository
+	case errors.Is(err, internal.ErrInvalidOption), errors.Is(err, internal.ErrInvalidRefName), errors.Is(err, internal.ErrInvalidConfigKey):
+		return ExitUsage
+	default:
+		return ExitFailure
+	}
+}
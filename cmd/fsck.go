@@ -0,0 +1,62 @@
+// File: fsck.go
+// Package: cmd
+
+// Program Description:
+// This file handles the parsing of the fsck command flags and arguments
+// "jit fsck" lists the problems found in the repository and "jit fsck --repair" also fixes the
+// ones that can be fixed safely. The command fails while a problem remains.
+
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"jit/internal"
+	"jit/pkg/util"
+)
+
+// fsckOptions are the options of "jit fsck".
+type fsckOptions struct {
+	repair bool
+}
+
+func newFsckFlags(options *fsckOptions) *flag.FlagSet {
+	fsckCmd := flag.NewFlagSet(util.Fsck, flag.ContinueOnError)
+	fsckCmd.BoolVar(&options.repair, "repair", false, "Fix the problems that can be fixed without losing data, such as a HEAD pointing to a missing branch")
+	return fsckCmd
+}
+
+// FsckCommand runs "jit fsck [--repair]".
+func FsckCommand(streams *Streams, args []string) error {
+	var options fsckOptions
+	fsckCmd := newFsckFlags(&options)
+	fsckCmd.SetOutput(streams.Stderr)
+	if err := parseFlags(fsckCmd, args); err != nil {
+		return err
+	}
+	if fsckCmd.NArg() > 0 {
+		return &ExitError{Code: ExitUsage, Err: errors.New("usage: jit fsck [--repair]")}
+	}
+
+	jitDir, _, discoverErr := streams.discoverRepository()
+	if discoverErr != nil {
+		return discoverErr
+	}
+
+	problems, checkErr := internal.CheckRepository(jitDir, options.repair)
+	remaining := 0
+	for _, problem := range problems {
+		_, _ = fmt.Fprintln(streams.Stdout, problem)
+		if !problem.Repaired {
+			remaining++
+		}
+	}
+	if checkErr != nil {
+		return checkErr
+	}
+	if remaining > 0 {
+		return &ExitError{Code: ExitFailure}
+	}
+	return nil
+}
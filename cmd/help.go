@@ -0,0 +1,267 @@
+// File: help.go
+// Package: cmd
+
+// Program Description:
+// This file generates the help page of each command from its FlagSet, so the options listed by
+// "jit help <command>" and "jit <command> -h" are always the ones the command accepts.
+// Only the synopsis, description and examples are written by hand; the pages are registered as
+// help topics so util.DisplayHelpDocs serves them alongside the embedded pages such as index.
+
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"jit/pkg/util"
+	"sort"
+	"strings"
+)
+
+// helpWidth is the width help text is wrapped to, matching the embedded pages.
+const helpWidth = 73
+
+// commandDoc is the hand-written part of a command's help page.
+type commandDoc struct {
+	summary     string
+	synopsis    []string
+	description string
+	examples    []string
+	flags       func() *flag.FlagSet
+}
+
+// commandDocs holds the help of every built-in command, keyed by command name.
+var commandDocs = map[string]commandDoc{
+	util.Init: {
+		summary:  "Create an empty jit repository",
+		synopsis: []string{"jit init [options] [<directory>]"},
+		description: "Creates a .jit directory with the initial branch, configuration and templates in " +
+			"<directory>, or in the current directory when none is given. Running it in an existing " +
+			"repository is safe: missing files and templates are restored and configuration it lacks is " +
+			"added, while existing files and HEAD are kept; --force with --initial-branch switches HEAD.",
+		examples: []string{"jit init", "jit init --initial-branch trunk project", "jit init --bare /srv/project.jit", "jit init --bare --shared=group /srv/team.jit", "jit init --force --initial-branch trunk"},
+		flags:    func() *flag.FlagSet { return newInitFlags(&initOptions{}) },
+	},
+	util.Config: {
+		summary: "Get and set repository or global options",
+		synopsis: []string{
+			"jit config [options] get [--all] <key>",
+			"jit config [options] set [--add] <key> <value>",
+			"jit config [options] unset [--all] <key>",
+			"jit config [options] list",
+		},
+		description: "Reads and writes configuration. Without a scope option, get and list read every " +
+			"file, later ones overriding earlier ones, and set and unset change the repository file.",
+		examples: []string{"jit config set user.name \"Ada Lovelace\"", "jit config --global get user.email", "jit config --list"},
+		flags:    func() *flag.FlagSet { return newConfigFlags(&configOptions{}) },
+	},
+	util.Hook: {
+		summary:     "Run or list hooks",
+		synopsis:    []string{"jit hook run [--ignore-missing] <hook-name> [-- <hook-args>]", "jit hook list"},
+		description: "Runs a hook exactly as jit commands do, so wrappers and other tools can trigger hooks themselves, or lists the hooks that would run.",
+		examples:    []string{"jit hook run pre-commit", "jit hook run --ignore-missing commit-msg -- .jit/COMMIT_EDITMSG"},
+		flags:       func() *flag.FlagSet { return newHookFlags(&hookOptions{}) },
+	},
+	util.Fsck: {
+		summary:  "Verify the integrity of the repository",
+		synopsis: []string{"jit fsck [--repair]"},
+		description: "Checks that the repository has every file and directory jit needs, that its " +
+			"configuration parses, that HEAD points to an existing branch and that no lock file is " +
+			"left behind. Exits with status 1 while a problem remains.",
+		examples: []string{"jit fsck", "jit fsck --repair"},
+		flags:    func() *flag.FlagSet { return newFsckFlags(&fsckOptions{}) },
+	},
+	util.Branch: {
+		summary:  "List, rename or copy branches",
+		synopsis: []string{"jit branch", "jit branch (-m | -M) [<old-branch>] <new-branch>", "jit branch (-c | -C) [<old-branch>] <new-branch>"},
+		description: "Lists the branches, marking the current one with \"*\". With -m, renames <old-branch>, " +
+			"or the current branch, to <new-branch>; with -c, copies it. The branch's upstream " +
+			"configuration follows it, and HEAD follows a renamed current branch.",
+		examples: []string{"jit branch", "jit branch -m master main", "jit branch -c main backup"},
+		flags:    func() *flag.FlagSet { return newBranchFlags(&branchOptions{}) },
+	},
+	util.Diff: {
+		summary:  "Show changes between files",
+		synopsis: []string{"jit diff --no-index [<options>] <path> <path>"},
+		description: "Compares two files, or two directories file by file, and prints the differences " +
+			"as a patch. A file compared with a directory is compared with the file of the same name " +
+			"in it. Exits with status 1 when the paths differ. Without an algorithm option, " +
+			"diff.algorithm is used. Options may come before or after the paths; paths after \"--\" " +
+			"are never read as options.",
+		examples: []string{"jit diff --no-index old.txt new.txt", "jit diff --no-index --histogram -U1 v1 v2", "jit diff --no-index -M75% v1 v2"},
+		flags:    func() *flag.FlagSet { return newDiffFlags(&diffOptions{}) },
+	},
+	util.Status: {
+		summary:  "Show the working tree status",
+		synopsis: []string{"jit status [-z] [-u<mode> | --untracked-files=<mode>]"},
+		description: "Shows the current branch and the untracked files of the working tree, relative to the " +
+			"current directory. Ignored files are never listed. In the normal mode, a directory holding " +
+			"no tracked files is listed once as \"dir/\" without being walked; all lists every file and no " +
+			"lists none. Without -u, status.showUntrackedFiles selects the mode. With -z, each file is " +
+			"printed as \"?? <path>\" relative to the top of the work tree and ended with a NUL.",
+		examples: []string{"jit status", "jit status -uall", "jit status --untracked-files=no", "jit status -z -uall"},
+		flags:    func() *flag.FlagSet { return newStatusFlags(&statusOptions{}) },
+	},
+	util.Help: {
+		summary:     "Display help information about jit",
+		synopsis:    []string{"jit help [<command>]"},
+		description: "Prints the help page of <command>, or the overview of jit when no command is given.",
+		examples:    []string{"jit help", "jit help config"},
+		flags:       newHelpFlags,
+	},
+}
+
+func init() {
+	for name, doc := range commandDocs {
+		name, doc := name, doc
+		util.RegisterHelpTopic(name, func(out io.Writer) error {
+			return writeCommandHelp(out, name, doc)
+		})
+	}
+}
+
+func newHelpFlags() *flag.FlagSet {
+	return flag.NewFlagSet(util.Help, flag.ContinueOnError)
+}
+
+// HelpCommand runs "jit help [<command>]".
+func HelpCommand(streams *Streams, args []string) error {
+	helpCmd := newHelpFlags()
+	helpCmd.SetOutput(streams.Stderr)
+	if err := parseFlags(helpCmd, args); err != nil {
+		return err
+	}
+	args = helpCmd.Args()
+	if len(args) == 0 {
+		return util.DisplayHelpDocs(streams.Stdout, "index")
+	}
+	if helpErr := util.DisplayHelpDocs(streams.Stdout, args[0]); helpErr != nil {
+		if errors.Is(helpErr, fs.ErrNotExist) {
+			return &ExitError{Code: ExitUsage, Err: fmt.Errorf("no help topic for %s: use jit help to list the commands", args[0])}
+		}
+		return helpErr
+	}
+	return nil
+}
+
+// setUsage makes -h print the generated help page of the command the flags belong to.
+func setUsage(flags *flag.FlagSet) {
+	doc, ok := commandDocs[flags.Name()]
+	if !ok {
+		return
+	}
+	flags.Usage = func() {
+		_ = writeCommandHelp(flags.Output(), flags.Name(), doc)
+	}
+}
+
+// writeCommandHelp writes a command's help page: its name, synopsis, description, the options of
+// its FlagSet and examples.
+func writeCommandHelp(out io.Writer, name string, doc commandDoc) error {
+	var sb strings.Builder
+	sb.WriteString("NAME\n")
+	fmt.Fprintf(&sb, "       jit-%s - %s\n\n", name, doc.summary)
+
+	sb.WriteString("SYNOPSIS\n")
+	for _, line := range doc.synopsis {
+		fmt.Fprintf(&sb, "       %s\n", line)
+	}
+	sb.WriteString("\n")
+
+	sb.WriteString("DESCRIPTION\n")
+	for _, line := range wrapText(doc.description, helpWidth-7) {
+		fmt.Fprintf(&sb, "       %s\n", line)
+	}
+	sb.WriteString("\n")
+
+	if options := flagHelp(doc.flags()); len(options) > 0 {
+		sb.WriteString("OPTIONS\n")
+		for _, option := range options {
+			sb.WriteString(option)
+			sb.WriteString("\n")
+		}
+	}
+
+	if len(doc.examples) > 0 {
+		sb.WriteString("EXAMPLES\n")
+		for _, example := range doc.examples {
+			fmt.Fprintf(&sb, "       %s\n", example)
+		}
+		sb.WriteString("\n")
+	}
+
+	_, writeErr := io.WriteString(out, sb.String())
+	return writeErr
+}
+
+// flagHelp formats the options of a FlagSet, one entry per option. Flags with the same usage
+// text, such as -q and --quiet, are aliases and are listed together, the short name first.
+func flagHelp(flags *flag.FlagSet) []string {
+	var order []string
+	names := map[string][]*flag.Flag{}
+	flags.VisitAll(func(f *flag.Flag) {
+		if _, seen := names[f.Usage]; !seen {
+			order = append(order, f.Usage)
+		}
+		names[f.Usage] = append(names[f.Usage], f)
+	})
+
+	entries := make([]string, 0, len(order))
+	for _, usage := range order {
+		aliases := names[usage]
+		sort.SliceStable(aliases, func(i, j int) bool { return len(aliases[i].Name) < len(aliases[j].Name) })
+
+		valueName, text := flag.UnquoteUsage(aliases[0])
+		if valueName == "string" {
+			valueName = "value"
+		}
+		if valueName != "" {
+			valueName = " <" + valueName + ">"
+		}
+		spellings := make([]string, 0, len(aliases))
+		for _, f := range aliases {
+			dashes := "--"
+			if len(f.Name) == 1 {
+				dashes = "-"
+			}
+			spellings = append(spellings, dashes+f.Name)
+		}
+		if def := aliases[0].DefValue; def != "" && def != "false" {
+			text = fmt.Sprintf("%s (default: %s)", text, def)
+		}
+
+		head := "       " + strings.Join(spellings, ", ") + valueName
+		lines := wrapText(text, helpWidth-21)
+		var entry strings.Builder
+		if len(head) < 20 {
+			fmt.Fprintf(&entry, "%-21s%s\n", head, lines[0])
+			lines = lines[1:]
+		} else {
+			entry.WriteString(head + "\n")
+		}
+		for _, line := range lines {
+			entry.WriteString(strings.Repeat(" ", 21) + line + "\n")
+		}
+		entries = append(entries, entry.String())
+	}
+	return entries
+}
+
+// wrapText splits text into lines of at most width characters, breaking at spaces.
+func wrapText(text string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		if line != "" && len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	return append(lines, line)
+}
@@ -0,0 +1,100 @@
+// File: hook.go
+// Package: cmd
+
+// Program Description:
+// This file handles the parsing of the hook command flags and arguments
+// "jit hook run <name> [-- <args>]" runs a hook exactly as jit commands do, so wrappers and other
+// tools can trigger hooks themselves; "jit hook list" prints the hooks that would run.
+
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"jit/internal"
+	"jit/pkg/util"
+)
+
+// hookOptions are the options of "jit hook".
+type hookOptions struct {
+	ignoreMissing bool
+}
+
+func newHookFlags(options *hookOptions) *flag.FlagSet {
+	hookCmd := flag.NewFlagSet(util.Hook, flag.ContinueOnError)
+	hookCmd.BoolVar(&options.ignoreMissing, "ignore-missing", false, "Exit successfully instead of failing when the hook does not exist")
+	return hookCmd
+}
+
+// HookCommand runs "jit hook run [--ignore-missing] <name> [-- <args>]" or "jit hook list".
+func HookCommand(streams *Streams, args []string) error {
+	var options hookOptions
+	hookCmd := newHookFlags(&options)
+	hookCmd.SetOutput(streams.Stderr)
+	if err := parseFlags(hookCmd, args); err != nil {
+		return err
+	}
+
+	action := hookCmd.Arg(0)
+	if hookCmd.NArg() > 0 {
+		if err := parseFlags(hookCmd, hookCmd.Args()[1:]); err != nil {
+			return err
+		}
+	}
+
+	jitDir, workTree, discoverErr := streams.discoverRepository()
+	if discoverErr != nil {
+		return discoverErr
+	}
+	config, loadErr := internal.LoadConfig(jitDir)
+	if loadErr != nil {
+		return loadErr
+	}
+	hooks := internal.NewHooks(jitDir, workTree, config)
+	hooks.Output = streams.Stderr
+
+	switch action {
+	case "run":
+		return hookRun(streams, hooks, hookCmd.Args(), options)
+	case "list":
+		return hookList(streams, hooks)
+	default:
+		return fmt.Errorf("invalid hook action %s: use run or list", action)
+	}
+}
+
+func hookRun(streams *Streams, hooks *internal.Hooks, args []string, options hookOptions) error {
+	if len(args) < 1 {
+		return &ExitError{Code: ExitUsage, Err: errors.New("usage: jit hook run [--ignore-missing] <hook-name> [-- <hook-args>]")}
+	}
+	name := args[0]
+	hookArgs := args[1:]
+	if len(hookArgs) > 0 && hookArgs[0] == "--" {
+		hookArgs = hookArgs[1:]
+	}
+
+	ran, runErr := hooks.Run(name, hookArgs, streams.Stdin)
+	var hookErr *internal.HookError
+	if errors.As(runErr, &hookErr) {
+		return &ExitError{Code: hookErr.ExitCode}
+	}
+	if runErr != nil {
+		return runErr
+	}
+	if !ran && !options.ignoreMissing {
+		return fmt.Errorf("cannot find a hook named %s", name)
+	}
+	return nil
+}
+
+func hookList(streams *Streams, hooks *internal.Hooks) error {
+	names, listErr := hooks.List()
+	if listErr != nil {
+		return listErr
+	}
+	for _, name := range names {
+		_, _ = fmt.Fprintln(streams.Stdout, name)
+	}
+	return nil
+}
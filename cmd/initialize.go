@@ -14,50 +14,94 @@ package cmd
 import (
 	"flag"
 	"jit/internal"
-	"log"
+	"jit/pkg/util"
 )
 
-var initCmd *flag.FlagSet
-var quiet bool
-var bare bool
-var template string
-var separateJitDir string
-var objectFormat string
-var branch string
-var permission string
-
-func init() {
-	initCmd = flag.NewFlagSet("initialize", flag.ExitOnError)
-	initCmd.BoolVar(&quiet, "quiet", false, "Only print error and warning messages; all other output will be suppressed.")
-	initCmd.BoolVar(&quiet, "q", false, "Only print error and warning messages; all other output will be suppressed.")
-	initCmd.BoolVar(&bare, "bare", false, "Create a bare repository. If JIT_DIR environment is not set, it is set to the current working directory")
-	initCmd.StringVar(&template, "template", "", "Specify the directory from which templates will be used")
-	initCmd.StringVar(&separateJitDir, "separate-jit-dir", "", "Instead of initializing the repository as a directory to either $JIT_DIR or ./.jit/, create a text file there containing the path to the actual repository")
-	initCmd.StringVar(&objectFormat, "object-format", "sha1", "Specify the given object format (hash algorithm) for the repository. The valid values are sha1 and sha256. sha1 is the default.")
-	initCmd.StringVar(&branch, "b", "main", "Use the specified name for the initial branch in the newly created repository. Default branch is main")
-	initCmd.StringVar(&branch, "initial-branch", "main", "Use the specified name for the initial branch in the newly created repository. Default branch is main")
-	initCmd.StringVar(&permission, "perm", "0755", "Specifies the directory's permission. Default is 0755")
+// initOptions are the options of "jit init".
+type initOptions struct {
+	quiet          bool
+	bare           bool
+	template       string
+	separateJitDir string
+	objectFormat   string
+	branch         string
+	permission     string
+	force          bool
+	shared         sharedOption
 }
 
-func Initialize(args []string) {
+// sharedOption is the value of --shared, which may be given without a value to mean "group".
+type sharedOption string
+
+func (s *sharedOption) String() string {
+	return string(*s)
+}
+
+func (s *sharedOption) Set(value string) error {
+	if value == "true" {
+		value = "group"
+	}
+	*s = sharedOption(value)
+	return nil
+}
+
+func (s *sharedOption) IsBoolFlag() bool {
+	return true
+}
+
+func newInitFlags(options *initOptions) *flag.FlagSet {
+	initCmd := flag.NewFlagSet(util.Init, flag.ContinueOnError)
+	initCmd.BoolVar(&options.quiet, "quiet", false, "Only print error and warning messages; all other output will be suppressed.")
+	initCmd.BoolVar(&options.quiet, "q", false, "Only print error and warning messages; all other output will be suppressed.")
+	initCmd.BoolVar(&options.bare, "bare", false, "Create a bare repository. If JIT_DIR environment is not set, it is set to the current working directory")
+	initCmd.StringVar(&options.template, "template", "", "Specify the directory from which templates will be used")
+	initCmd.StringVar(&options.separateJitDir, "separate-jit-dir", "", "Instead of initializing the repository as a directory to either $JIT_DIR or ./.jit/, create a text file there containing the path to the actual repository")
+	initCmd.StringVar(&options.objectFormat, "object-format", "sha1", "Specify the given object format (hash algorithm) for the repository. The valid values are sha1 and sha256.")
+	initCmd.StringVar(&options.branch, "b", "main", "Use the specified name for the initial branch in the newly created repository.")
+	initCmd.StringVar(&options.branch, "initial-branch", "main", "Use the specified name for the initial branch in the newly created repository.")
+	initCmd.StringVar(&options.permission, "perm", "0755", "Specifies the directory's permission")
+	initCmd.Var(&options.shared, "shared", "Make the repository writable by the group (--shared or --shared=group), also readable by everyone (--shared=all) or give its files an octal mode (--shared=0640). Directories get the setgid bit.")
+	initCmd.BoolVar(&options.force, "force", false, "When re-initializing an existing repository, switch HEAD to the branch given with --initial-branch.")
+	return initCmd
+}
+
+func Initialize(streams *Streams, args []string) error {
 	// Parse the initialize command arguments
-	if err := initCmd.Parse(args); err != nil {
-		log.Fatalln("Error parsing initialize command:", err)
+	var opts initOptions
+	initCmd := newInitFlags(&opts)
+	initCmd.SetOutput(streams.Stderr)
+	if err := parseFlags(initCmd, args); err != nil {
+		return err
 	}
 
-	// Access the first argument
+	// Access the first argument; relative paths are relative to the directory jit runs in
 	workingDirectory := initCmd.Arg(0)
-	options := map[string]any{
-		"quiet":            quiet,
-		"bare":             bare,
-		"separate-jit-dir": separateJitDir,
-		"template":         template,
-		"object-format":    objectFormat,
-		"initial-branch":   branch,
-		"perm":             permission,
+	if workingDirectory == "" && opts.bare {
+		workingDirectory = streams.JitDir
+	}
+	if workingDirectory == "" {
+		workingDirectory = streams.Dir
 	}
-	_, initErr := internal.InitializeJitRepository(options, workingDirectory)
-	if initErr != nil {
-		log.Fatalln(initErr)
+	options := map[string]any{
+		"quiet":            opts.quiet,
+		"bare":             opts.bare,
+		"separate-jit-dir": streams.path(opts.separateJitDir),
+		"template":         streams.path(opts.template),
+		"perm":             opts.permission,
+		"force":            opts.force,
 	}
+	// Re-initializing a repository only checks or changes these when they are given explicitly;
+	// otherwise InitializeJitRepository uses the same defaults as the flags.
+	initCmd.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "object-format":
+			options["object-format"] = opts.objectFormat
+		case "b", "initial-branch":
+			options["initial-branch"] = opts.branch
+		case "shared":
+			options["shared"] = string(opts.shared)
+		}
+	})
+	_, initErr := internal.InitializeJitRepository(options, streams.path(workingDirectory))
+	return initErr
 }
@@ -1,53 +1,313 @@
 package cmd
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"jit/internal"
 	"jit/pkg/util"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
 )
 
-var help bool
-var version bool
+// Streams are the standard streams a command reads from and writes to, and the directory and
+// repository it runs with. Commands resolve paths against Dir instead of the working directory of
+// the process, so -C, --jit-dir and --work-tree affect one call to Run only.
+type Streams struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
 
-func init() {
-	flag.BoolVar(&help, "help", false, "jit -h | jit --help")
-	flag.BoolVar(&help, "h", false, "jit -h | jit --help")
+	Dir      string // The directory the command runs in, after -C; empty means the process's.
+	JitDir   string // The repository from --jit-dir or JIT_DIR, or empty to search from Dir.
+	WorkTree string // The work tree from --work-tree or JIT_WORK_TREE, or empty.
+}
+
+// path resolves a path given to the command against the directory it runs in.
+func (s *Streams) path(path string) string {
+	return internal.ResolvePath(s.Dir, path)
+}
 
-	flag.BoolVar(&version, "version", false, "jit -v | jit --version")
-	flag.BoolVar(&version, "v", false, "jit -v | jit --version")
+// discoverRepository finds the repository the command operates on, searching from Dir unless
+// --jit-dir or JIT_DIR names it.
+func (s *Streams) discoverRepository() (jitDir string, workTree string, err error) {
+	return internal.DiscoverRepositoryWith(s.Dir, s.JitDir, s.WorkTree)
 }
 
-func handleCommand(command string, args []string) {
+// directories collects the -C options, which may be repeated.
+type directories []string
 
-	switch command {
-	case util.Init:
-		Initialize(args)
-		break
-	default:
-		log.Fatalf("Invalid command %s: use jit -h for help\n", command)
+func (d *directories) String() string {
+	return strings.Join(*d, " ")
+}
+
+func (d *directories) Set(dir string) error {
+	*d = append(*d, dir)
+	return nil
+}
+
+// workingDirectory returns the directory a command runs in: the current directory, moved to each
+// -C directory in turn, so a relative one is interpreted relative to the one before it. Empty
+// directories are ignored. The working directory of the process is left alone.
+func workingDirectory(dirs directories) (string, error) {
+	dir, cwdErr := os.Getwd()
+	if cwdErr != nil {
+		dir = ""
+	}
+	for _, next := range dirs {
+		if next == "" {
+			continue
+		}
+		resolved, absErr := filepath.Abs(internal.ResolvePath(dir, next))
+		if absErr != nil {
+			return "", fmt.Errorf("cannot change to %s -> %w", next, absErr)
+		}
+		info, statErr := internal.Files.Stat(resolved)
+		if statErr == nil && !info.IsDir() {
+			statErr = fmt.Errorf("%s is not a directory", resolved)
+		}
+		if statErr != nil {
+			return "", fmt.Errorf("cannot change to %s -> %w", next, statErr)
+		}
+		dir = resolved
 	}
+	return dir, nil
 }
 
-func Jit() {
-	flag.Parse()
+// commands maps every built-in command name to its handler.
+var commands = map[string]func(streams *Streams, args []string) error{
+	util.Init:   Initialize,
+	util.Config: ConfigCommand,
+	util.Hook:   HookCommand,
+	util.Help:   HelpCommand,
+	util.Fsck:   FsckCommand,
+	util.Branch: BranchCommand,
+	util.Diff:   DiffCommand,
+	util.Status: StatusCommand,
+}
+
+func isBuiltinCommand(name string) bool {
+	_, ok := commands[name]
+	return ok
+}
+
+// parseFlags parses the arguments of a command. Usage errors have already been printed by the flag
+// set and end the command with status 2; -h ends it successfully after printing the help page.
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	setUsage(flags)
+	if parseErr := flags.Parse(args); parseErr != nil {
+		if errors.Is(parseErr, flag.ErrHelp) {
+			return &ExitError{Code: ExitSuccess}
+		}
+		return &ExitError{Code: ExitUsage}
+	}
+	return nil
+}
+
+func handleCommand(streams *Streams, command string, args []string) error {
+
+	if !isBuiltinCommand(command) {
+		var aliasErr error
+		command, args, aliasErr = expandAlias(streams, command, args)
+		if aliasErr != nil {
+			return aliasErr
+		}
+	}
+
+	run, ok := commands[command]
+	if !ok {
+		return &ExitError{Code: ExitUsage, Err: fmt.Errorf("invalid command %s: use jit -h for help", command)}
+	}
+	return run(streams, args)
+}
+
+// discoverOptionalRepository finds the repository for code that also runs outside one, such as
+// alias lookup and "jit config". Not being in a repository leaves jitDir and workTree empty, and a
+// repository of an unsupported format is still returned so its config can be read; any other
+// failure to discover the repository is returned.
+func discoverOptionalRepository(streams *Streams) (jitDir string, workTree string, err error) {
+	jitDir, workTree, err = streams.discoverRepository()
+	if errors.Is(err, internal.ErrNotARepository) || errors.Is(err, internal.ErrUnsupportedFormat) {
+		return jitDir, workTree, nil
+	}
+	return jitDir, workTree, err
+}
+
+// expandAlias resolves an alias from config. Shell aliases are run here and end the command with
+// the alias's exit status.
+func expandAlias(streams *Streams, command string, args []string) (string, []string, error) {
+	jitDir, workTree, discoverErr := discoverOptionalRepository(streams)
+	if discoverErr != nil {
+		return "", nil, discoverErr
+	}
+	config, loadErr := internal.LoadConfig(jitDir)
+	if loadErr != nil {
+		return "", nil, loadErr
+	}
+
+	resolved, shellCommand, aliasErr := internal.ResolveAlias(config, command, args, isBuiltinCommand)
+	if aliasErr != nil {
+		return "", nil, aliasErr
+	}
+	if shellCommand != "" {
+		// The shell runs at the top of the work tree, or where jit runs outside a repository, and
+		// a jit it starts finds the same repository.
+		dir := workTree
+		if dir == "" {
+			dir = streams.Dir
+		}
+		var env []string
+		for name, path := range map[string]string{util.EnvJitDir: streams.JitDir, util.EnvWorkTree: streams.WorkTree} {
+			if path != "" {
+				absolute, absErr := filepath.Abs(streams.path(path))
+				if absErr != nil {
+					return "", nil, fmt.Errorf("invalid path %s -> %w", path, absErr)
+				}
+				env = append(env, name+"="+absolute)
+			}
+		}
+		exitCode, runErr := internal.RunShellAlias(command, shellCommand, args, dir, env, streams.Stdin, streams.Stdout, streams.Stderr)
+		if runErr != nil {
+			return "", nil, runErr
+		}
+		return "", nil, &ExitError{Code: exitCode}
+	}
+	return resolved[0], resolved[1:], nil
+}
+
+// enableTrace turns tracing on for --trace or JIT_TRACE.
+func enableTrace(logger *log.Logger, trace bool, args []string) {
+	target := os.Getenv(util.EnvTrace)
+	if trace {
+		target = "1"
+	}
+	if traceErr := internal.EnableTrace(target); traceErr != nil {
+		logger.Println("warning:", traceErr)
+	}
+	internal.TraceStart(args)
+}
+
+// Run runs jit with the given arguments and streams and returns its exit status.
+//
+// Args:
+//
+//	args ([]string): The command line without the program name, e.g. {"config", "get", "user.name"}.
+//	stdin (io.Reader): The standard input given to commands, hooks and shell aliases.
+//	stdout (io.Writer): Where command output is written.
+//	stderr (io.Writer): Where errors, warnings and usage messages are written.
+//
+// Returns:
+//
+//	int: The exit status, one of the Exit* codes, or the status of a hook or shell alias.
+//
+// Usage:
+//
+//	var stdout, stderr bytes.Buffer
+//	if code := cmd.Run([]string{"config", "--list"}, strings.NewReader(""), &stdout, &stderr); code != 0 {
+//	    log.Fatalf("jit failed: %s", stderr.String())
+//	}
+//
+// Note:
+//   - Run never exits the process, so it can be embedded in other programs and tests.
+//   - -C, --jit-dir and --work-tree apply to this call only: paths are resolved against the
+//     directory -C selects, and the working directory and environment of the process are left
+//     alone.
+//   - Messages logged by the internal package go to stderr, and the verbosity is set from -q and
+//     --verbose, through process-wide state that is restored on return. Overlapping calls to Run
+//     therefore share their log output and verbosity.
+func Run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	streams := &Streams{Stdin: stdin, Stdout: stdout, Stderr: stderr}
+	logger := log.New(stderr, "", log.LstdFlags)
+	previousOutput := log.Writer()
+	log.SetOutput(stderr)
+	defer log.SetOutput(previousOutput)
+
+	var help, version, trace, quiet, verbose bool
+	var dirs directories
+	var jitDir, workTree string
+	globalFlags := flag.NewFlagSet("jit", flag.ContinueOnError)
+	globalFlags.SetOutput(stderr)
+	globalFlags.BoolVar(&help, "help", false, "jit -h | jit --help")
+	globalFlags.BoolVar(&help, "h", false, "jit -h | jit --help")
+	globalFlags.BoolVar(&version, "version", false, "jit -v | jit --version")
+	globalFlags.BoolVar(&version, "v", false, "jit -v | jit --version")
+	globalFlags.BoolVar(&quiet, "quiet", false, "Only print errors and warnings")
+	globalFlags.BoolVar(&quiet, "q", false, "Only print errors and warnings")
+	globalFlags.BoolVar(&verbose, "verbose", false, "Also print the details of what the command is doing")
+	globalFlags.BoolVar(&trace, "trace", false, "Write a trace of the command to stderr, as JIT_TRACE=1 does")
+	globalFlags.Var(&dirs, "C", "Run as if jit was started in the given directory instead of the current one")
+	globalFlags.StringVar(&jitDir, "jit-dir", "", "Use the given repository directory, as JIT_DIR does")
+	globalFlags.StringVar(&workTree, "work-tree", "", "Use the given working tree, as JIT_WORK_TREE does")
+	if parseErr := parseFlags(globalFlags, args); parseErr != nil {
+		return exitCode(logger, parseErr)
+	}
+
+	previousVerbosity := internal.CurrentVerbosity
+	defer func() { internal.CurrentVerbosity = previousVerbosity }()
+	switch {
+	case quiet && verbose:
+		logger.Println("--quiet and --verbose cannot be used together")
+		return ExitUsage
+	case quiet:
+		internal.CurrentVerbosity = internal.VerbosityQuiet
+	case verbose:
+		internal.CurrentVerbosity = internal.VerbosityVerbose
+	default:
+		internal.CurrentVerbosity = internal.VerbosityNormal
+	}
+
+	dir, dirErr := workingDirectory(dirs)
+	if dirErr != nil {
+		return exitCode(logger, dirErr)
+	}
+	if jitDir == "" {
+		jitDir = os.Getenv(util.EnvJitDir)
+	}
+	if workTree == "" {
+		workTree = os.Getenv(util.EnvWorkTree)
+	}
+	streams.Dir, streams.JitDir, streams.WorkTree = dir, jitDir, workTree
+	enableTrace(logger, trace, args)
 
 	if help {
-		util.DisplayHelpDocs("index")
-		return
+		if helpErr := util.DisplayHelpDocs(stdout, "index"); helpErr != nil {
+			return exitCode(logger, helpErr)
+		}
+		return 0
 	}
 
 	if version {
-		fmt.Printf("Jit Version %s", util.JitVersion)
-		return
+		_, _ = fmt.Fprintf(stdout, "Jit Version %s", util.JitVersion)
+		return 0
 	}
 
 	// Additional command handling
-	if len(flag.Args()) > 0 {
-		command := flag.Arg(0)
-		commandArgs := flag.Args()[1:]
-		handleCommand(command, commandArgs)
-	} else {
-		log.Fatalln("No command provided: use jit -h for help")
+	if globalFlags.NArg() == 0 {
+		logger.Println("No command provided: use jit -h for help")
+		return ExitUsage
+	}
+	command := globalFlags.Arg(0)
+	leave := internal.TraceRegion("command", command)
+	err := handleCommand(streams, command, globalFlags.Args()[1:])
+	leave()
+	code := 0
+	if err != nil {
+		code = exitCode(logger, err)
+	}
+	internal.TraceExit(code)
+	return code
+}
+
+// Jit runs jit with the arguments and streams of the process, exiting with its status on failure.
+// An interrupted command releases its locks and undoes its partial changes before exiting.
+func Jit() {
+	stop := internal.HandleSignals(os.Exit)
+	code := Run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	stop()
+	if code != 0 {
+		os.Exit(code)
 	}
 }
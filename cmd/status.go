@@ -0,0 +1,161 @@
+// File: status.go
+// Package: cmd
+
+// Program Description:
+// This file handles the parsing of the status command flags and arguments
+// "jit status" shows the current branch and the untracked files of the working tree, relative to
+// the current directory. -u/--untracked-files=no|normal|all, or status.showUntrackedFiles, selects
+// how they are listed; -u alone means all. -z prints the short "?? <path>" form instead, each
+// path relative to the top of the work tree and ended with a NUL, for scripts. The stage holds no
+// entries yet, so every file that is not ignored is untracked.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"jit/internal"
+	"jit/pkg/util"
+	"path/filepath"
+	"strings"
+)
+
+// statusOptions are the options of "jit status".
+type statusOptions struct {
+	untracked untrackedOption
+	nul       bool
+}
+
+// untrackedOption is the value of --untracked-files, which may be given without a mode to mean
+// "all".
+type untrackedOption struct {
+	set  bool
+	mode string
+}
+
+func (u *untrackedOption) String() string {
+	return u.mode
+}
+
+func (u *untrackedOption) Set(value string) error {
+	if value == "true" {
+		value = "all"
+	}
+	u.set, u.mode = true, value
+	return nil
+}
+
+func (u *untrackedOption) IsBoolFlag() bool {
+	return true
+}
+
+func newStatusFlags(options *statusOptions) *flag.FlagSet {
+	statusCmd := flag.NewFlagSet(util.Status, flag.ContinueOnError)
+	statusCmd.Var(&options.untracked, "u", "List untracked files: no, normal (directories without tracked files as one entry) or all (-u alone)")
+	statusCmd.Var(&options.untracked, "untracked-files", "List untracked files: no, normal (directories without tracked files as one entry) or all (-u alone)")
+	statusCmd.BoolVar(&options.nul, "z", false, "Print each untracked file as \"?? <path>\" ended with a NUL, relative to the top of the work tree")
+	return statusCmd
+}
+
+// statusShortValueFlags are the one-letter options of status whose value may be attached, as in -uno.
+var statusShortValueFlags = []string{"u"}
+
+// StatusCommand runs "jit status [-z] [-u<mode> | --untracked-files=<mode>]".
+func StatusCommand(streams *Streams, args []string) error {
+	var options statusOptions
+	statusCmd := newStatusFlags(&options)
+	statusCmd.SetOutput(streams.Stderr)
+	if err := parseFlags(statusCmd, splitShortValues(statusCmd, args, statusShortValueFlags)); err != nil {
+		return err
+	}
+	if statusCmd.NArg() > 0 {
+		return &ExitError{Code: ExitUsage, Err: errors.New("usage: jit status [-z] [-u<mode> | --untracked-files=<mode>]")}
+	}
+
+	jitDir, workTree, discoverErr := streams.discoverRepository()
+	if discoverErr != nil {
+		return discoverErr
+	}
+	config, loadErr := internal.LoadConfig(jitDir)
+	if loadErr != nil {
+		return loadErr
+	}
+
+	mode, modeErr := untrackedMode(options, config)
+	if modeErr != nil {
+		return modeErr
+	}
+	matcher, matcherErr := internal.NewIgnoreMatcher(workTree, jitDir, internal.DefaultGlobalIgnoreFile(), internal.ReadGitFiles(config))
+	if matcherErr != nil {
+		return matcherErr
+	}
+	untracked, untrackedErr := internal.UntrackedFiles(context.Background(), workTree, nil, mode, matcher)
+	if untrackedErr != nil {
+		return untrackedErr
+	}
+	if options.nul {
+		for _, path := range untracked {
+			_, _ = fmt.Fprintf(streams.Stdout, "?? %s\x00", path)
+		}
+		return nil
+	}
+
+	branch, _, headErr := internal.ReadHead(jitDir)
+	switch {
+	case errors.Is(headErr, internal.ErrNoBranch):
+		_, _ = fmt.Fprintln(streams.Stdout, "Not currently on any branch.")
+	case headErr != nil:
+		return headErr
+	default:
+		_, _ = fmt.Fprintf(streams.Stdout, "On branch %s\n", branch)
+	}
+
+	if len(untracked) == 0 {
+		if mode == internal.UntrackedNo {
+			_, _ = fmt.Fprintln(streams.Stdout, "\nnothing to commit (use -u to show untracked files)")
+		} else {
+			_, _ = fmt.Fprintln(streams.Stdout, "\nnothing to commit, working tree clean")
+		}
+		return nil
+	}
+	_, _ = fmt.Fprintln(streams.Stdout, "\nUntracked files:")
+	for _, path := range untracked {
+		_, _ = fmt.Fprintf(streams.Stdout, "\t%s\n", relativeToCwd(streams.Dir, workTree, path))
+	}
+	return nil
+}
+
+// untrackedMode returns the mode selected by -u, or by status.showUntrackedFiles when it is not
+// given.
+func untrackedMode(options statusOptions, config *internal.Config) (internal.UntrackedMode, error) {
+	if options.untracked.set {
+		mode, parseErr := internal.ParseUntrackedMode(options.untracked.mode)
+		if parseErr != nil {
+			return mode, &ExitError{Code: ExitUsage, Err: parseErr}
+		}
+		return mode, nil
+	}
+
+	configured, _ := config.Get("status.showUntrackedFiles")
+	mode, parseErr := internal.ParseUntrackedMode(configured)
+	if parseErr != nil {
+		return mode, fmt.Errorf("invalid status.showUntrackedFiles -> %w", parseErr)
+	}
+	return mode, nil
+}
+
+// relativeToCwd shows a slash-separated work tree path relative to the current directory, keeping
+// the trailing "/" of a collapsed directory.
+func relativeToCwd(cwd string, workTree string, path string) string {
+	rel, relErr := filepath.Rel(cwd, filepath.Join(workTree, filepath.FromSlash(path)))
+	if relErr != nil {
+		return path
+	}
+	rel = filepath.ToSlash(rel)
+	if strings.HasSuffix(path, "/") {
+		rel += "/"
+	}
+	return rel
+}
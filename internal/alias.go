@@ -0,0 +1,159 @@
+// File: alias.go
+// Package: internal
+
+// Program Description:
+// This file expands command aliases defined in the [alias] section of the config.
+// An alias either expands to another jit command with arguments ("co = checkout") or, when its
+// value starts with "!", to a shell command run in the work tree ("lg = !jit log --oneline | head").
+
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// ResolveAlias expands a command name through the [alias] section of the config.
+//
+// Args:
+//
+//	config (*Config): The configuration to read alias.<name> entries from.
+//	command (string): The command name given on the command line.
+//	args ([]string): The arguments following the command name.
+//	isBuiltin (func(string) bool): Reports whether a name is a built-in command. Built-in commands
+//	                               are never replaced by aliases.
+//
+// Returns:
+//
+//	resolved ([]string): The expanded command name followed by its arguments, or nil for shell aliases.
+//	shellCommand (string): The shell command of a "!" alias (without the "!"), empty otherwise.
+//	err (error): An error if an alias value cannot be split or aliases expand into each other in a loop.
+//
+// The function performs the following steps:
+//  1. Returns the command unchanged if it is built in or not an alias.
+//  2. Splits the alias value into words, honoring quotes, and prepends them to the arguments.
+//  3. Repeats with the new command name, so aliases can refer to other aliases.
+//
+// Usage:
+//
+//	resolved, shell, err := ResolveAlias(config, "co", []string{"main"}, isBuiltin)
+//	// with "co = checkout", resolved is ["checkout", "main"]
+func ResolveAlias(config *Config, command string, args []string, isBuiltin func(string) bool) (resolved []string, shellCommand string, err error) {
+	seen := map[string]bool{}
+	for !isBuiltin(command) {
+		value, ok := config.Get("alias." + command)
+		if !ok {
+			break
+		}
+		if seen[command] {
+			return nil, "", fmt.Errorf("alias loop detected: expansion of '%s' does not terminate", command)
+		}
+		seen[command] = true
+
+		if shell, isShell := strings.CutPrefix(value, "!"); isShell {
+			return nil, shell, nil
+		}
+		words, splitErr := SplitCommandLine(value)
+		if splitErr != nil {
+			return nil, "", fmt.Errorf("bad alias.%s string: %w", command, splitErr)
+		}
+		if len(words) == 0 {
+			return nil, "", fmt.Errorf("empty alias for %s", command)
+		}
+		command, args = words[0], append(words[1:], args...)
+	}
+	return append([]string{command}, args...), "", nil
+}
+
+// SplitCommandLine splits a string into words the way a shell would for simple cases: whitespace
+// separates words, single quotes preserve everything, double quotes and backslashes escape.
+func SplitCommandLine(line string) (words []string, err error) {
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inWord = true, true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errors.New("unterminated quote or escape")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// RunShellAlias runs the shell command of a "!" alias with the remaining arguments appended as
+// positional parameters.
+//
+// Args:
+//
+//	name (string): The alias name, passed to the shell as $0.
+//	shellCommand (string): The command, without the leading "!".
+//	args ([]string): The arguments given after the alias on the command line.
+//	dir (string): The directory to run in, normally the top of the work tree. Empty means the
+//	              current directory.
+//	env ([]string): "NAME=value" variables set for the command on top of the environment of jit,
+//	                such as JIT_DIR for --jit-dir.
+//	stdin (io.Reader), stdout (io.Writer), stderr (io.Writer): The standard streams of the command.
+//
+// Returns:
+//
+//	exitCode (int): The exit status of the shell command.
+//	err (error): An error if the shell could not be started.
+func RunShellAlias(name string, shellCommand string, args []string, dir string, env []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) (exitCode int, err error) {
+	shellArgs := append([]string{"-c", shellCommand + ` "$@"`, name}, args...)
+	aliasCmd := exec.Command("sh", shellArgs...)
+	aliasCmd.Dir = dir
+	if len(env) > 0 {
+		aliasCmd.Env = append(os.Environ(), env...)
+	}
+	aliasCmd.Stdin = stdin
+	aliasCmd.Stdout = stdout
+	aliasCmd.Stderr = stderr
+
+	runErr := aliasCmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(runErr, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	if runErr != nil {
+		return 0, runErr
+	}
+	return 0, nil
+}
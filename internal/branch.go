@@ -0,0 +1,152 @@
+// File: branch.go
+// Package: internal
+
+// Program Description:
+// This file renames and copies branches, for "jit branch -m" and "jit branch -c".
+// The branch files are moved in a single RefTransaction, so another process sees either the old
+// name or the new one, never both or neither. The upstream configuration in the [branch "<name>"]
+// section follows the branch, and HEAD is updated when the current branch is renamed. Should the
+// configuration fail to update, the branch files are put back. jit keeps no reflog yet, so there
+// is no log to move.
+
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"jit/pkg/util"
+	"path/filepath"
+	"strings"
+)
+
+// ErrBranchNotFound is returned when the branch to rename or copy does not exist.
+var ErrBranchNotFound = errors.New("no such branch")
+
+// ErrBranchExists is returned when the new name of a branch is taken and the operation is not
+// forced.
+var ErrBranchExists = errors.New("a branch with that name already exists")
+
+// RenameBranch renames a branch, with its upstream configuration.
+//
+// Args:
+//
+//	jitDir (string): The jit directory of the repository.
+//	oldName (string): The branch to rename.
+//	newName (string): Its new name.
+//	force (bool): Whether to replace an existing branch named newName, as -M does.
+//
+// Returns:
+//
+//	err (error): ErrInvalidRefName for an invalid name, ErrBranchNotFound if oldName does not
+//	             exist, ErrBranchExists if newName does and force is false, or the error met
+//	             updating the refs, the configuration or HEAD.
+//
+// Usage:
+//
+//	if err := RenameBranch(jitDir, "master", "main", false); err != nil {
+//	    log.Fatalln(err)
+//	}
+func RenameBranch(jitDir string, oldName string, newName string, force bool) (err error) {
+	return moveBranch(jitDir, oldName, newName, force, false)
+}
+
+// CopyBranch creates a branch pointing where another one does, with a copy of its upstream
+// configuration. It takes the same arguments and returns the same errors as RenameBranch; HEAD is
+// never changed.
+func CopyBranch(jitDir string, oldName string, newName string, force bool) (err error) {
+	return moveBranch(jitDir, oldName, newName, force, true)
+}
+
+// moveBranch renames or copies a branch.
+//
+// The function performs the following steps:
+//  1. Validates both names and reads the target of oldName.
+//  2. Refuses to replace an existing newName unless forced, and to replace the current branch.
+//  3. Creates or updates newName and, unless keepOld is set for a copy, deletes oldName, in one
+//     transaction.
+//  4. Moves or copies the configuration section, undoing step 3 if that fails.
+//  5. Points HEAD at newName if oldName was the current branch and it was renamed.
+func moveBranch(jitDir string, oldName string, newName string, force bool, keepOld bool) error {
+	for _, name := range []string{oldName, newName} {
+		if nameErr := CheckRefName(name); nameErr != nil {
+			return nameErr
+		}
+	}
+	target, exists, readErr := readBranch(jitDir, oldName)
+	if readErr != nil {
+		return readErr
+	}
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrBranchNotFound, oldName)
+	}
+	if oldName == newName && force {
+		return nil
+	}
+
+	current, _, headErr := ReadHead(jitDir)
+	if headErr != nil && !errors.Is(headErr, ErrNoBranch) {
+		return headErr
+	}
+
+	transaction := NewRefTransaction(jitDir)
+	undo := NewRefTransaction(jitDir)
+	replaced, replacing, replacedErr := readBranch(jitDir, newName)
+	switch {
+	case replacedErr != nil:
+		return replacedErr
+	case replacing && !force:
+		return fmt.Errorf("%w: %s", ErrBranchExists, newName)
+	case replacing && newName == current:
+		return fmt.Errorf("cannot force update the current branch %s", newName)
+	case replacing:
+		transaction.Update(newName, target, replaced)
+		undo.Update(newName, replaced, target)
+	default:
+		transaction.Create(newName, target)
+		undo.Delete(newName, target)
+	}
+	if !keepOld {
+		transaction.Delete(oldName, target)
+		undo.Create(oldName, target)
+	}
+	if commitErr := transaction.Commit(); commitErr != nil {
+		return commitErr
+	}
+
+	configPath := filepath.Join(jitDir, util.CONFIG)
+	var configErr error
+	if keepOld {
+		configErr = CopyBranchConfig(configPath, oldName, newName)
+	} else {
+		configErr = RenameBranchConfig(configPath, oldName, newName)
+	}
+	if configErr != nil {
+		if undoErr := undo.Commit(); undoErr != nil {
+			return fmt.Errorf("cannot update the configuration of %s -> %w; restoring the branches failed -> %w", newName, configErr, undoErr)
+		}
+		return fmt.Errorf("cannot update the configuration of %s -> %w", newName, configErr)
+	}
+
+	if !keepOld && current == oldName {
+		return WriteHead(jitDir, newName)
+	}
+	return nil
+}
+
+// readBranch returns the target of a branch and whether it exists.
+func readBranch(jitDir string, name string) (target string, exists bool, err error) {
+	content, readErr := Files.ReadFile(branchPath(jitDir, name))
+	if readErr == nil {
+		return strings.TrimSpace(string(content)), true, nil
+	}
+	if info, statErr := Files.Stat(branchPath(jitDir, name)); errors.Is(statErr, fs.ErrNotExist) || (statErr == nil && info.IsDir()) {
+		return "", false, nil
+	}
+	return "", false, readErr
+}
+
+// ListBranches returns the sorted names of the branches of a repository, e.g. "feature/login".
+func ListBranches(jitDir string) (names []string, err error) {
+	return listBranches(jitDir, "")
+}
@@ -0,0 +1,310 @@
+// File: config.go
+// Package: internal
+
+// Program Description:
+// This file handles reading and writing configuration.
+// Settings are read from three files, each overriding the one before it: the system file
+// (/etc/jitconfig), the global file of the current user (~/.jitconfig) and the config file of the
+// repository. A key set at several levels takes the value of the most specific one. Writes always
+// target a single file.
+
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"jit/pkg/util"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ConfigScope identifies the file a setting comes from.
+type ConfigScope string
+
+const (
+	ScopeSystem ConfigScope = "system" // The machine-wide file, /etc/jitconfig.
+	ScopeGlobal ConfigScope = "global" // The file of the current user, ~/.jitconfig.
+	ScopeLocal  ConfigScope = "local"  // The config file of the repository.
+)
+
+// configScopes lists the scopes from the lowest to the highest precedence.
+var configScopes = []ConfigScope{ScopeSystem, ScopeGlobal, ScopeLocal}
+
+// ConfigEntry is a single key/value pair read from a config file.
+type ConfigEntry struct {
+	Key   string      // The canonical key: "section.key" or "section.subsection.key", section and key lowercased.
+	Value string      // The value, with surrounding whitespace removed.
+	Scope ConfigScope // The file the entry was read from.
+}
+
+// Config holds the entries of every config file in order of increasing precedence.
+type Config struct {
+	entries []ConfigEntry
+}
+
+// ConfigPath returns the path of the config file for a scope.
+//
+// Args:
+//
+//	scope (ConfigScope): The scope whose file is wanted.
+//	jitDir (string): The .jit directory of the repository, only used for ScopeLocal.
+//
+// Returns:
+//
+//	path (string): The path of the config file. The file does not need to exist.
+//	err (error): An error if the scope is unknown or the home directory cannot be determined for
+//	             ScopeGlobal, and one wrapping ErrNotARepository if jitDir is empty for ScopeLocal.
+//
+// Note:
+//   - JIT_CONFIG_GLOBAL, when set, replaces ~/.jitconfig as the global file.
+func ConfigPath(scope ConfigScope, jitDir string) (path string, err error) {
+	switch scope {
+	case ScopeSystem:
+		return util.SystemConfigFile, nil
+	case ScopeGlobal:
+		if override := os.Getenv(util.EnvConfigGlobal); override != "" {
+			return override, nil
+		}
+		home, homeErr := os.UserHomeDir()
+		if homeErr != nil {
+			return "", homeErr
+		}
+		return filepath.Join(home, util.GlobalConfigFile), nil
+	case ScopeLocal:
+		if jitDir == "" {
+			return "", fmt.Errorf("no local config -> %w", ErrNotARepository)
+		}
+		return filepath.Join(jitDir, util.CONFIG), nil
+	default:
+		return "", fmt.Errorf("unknown config scope -> %s", scope)
+	}
+}
+
+// LoadConfig reads the system, global and repository config files.
+//
+// Args:
+//
+//	jitDir (string): The .jit directory of the repository, or an empty string outside a repository,
+//	                 in which case only the system and global files are read.
+//
+// Returns:
+//
+//	config (*Config): The merged configuration.
+//	err (error): An error if a config file exists but cannot be read or parsed.
+//
+// Usage:
+//
+//	config, err := LoadConfig(jitDir)
+//	if err != nil {
+//	    log.Fatalln(err)
+//	}
+//	editor, ok := config.Get("core.editor")
+//
+// Note:
+//   - Missing config files are skipped; a missing home directory only skips the global file.
+func LoadConfig(jitDir string) (config *Config, err error) {
+	config = &Config{}
+	for _, scope := range configScopes {
+		if scope == ScopeLocal && jitDir == "" {
+			continue
+		}
+		path, pathErr := ConfigPath(scope, jitDir)
+		if pathErr != nil {
+			continue
+		}
+		entries, readErr := ReadConfigFile(path, scope)
+		if readErr != nil {
+			return nil, readErr
+		}
+		config.entries = append(config.entries, entries...)
+	}
+	return config, nil
+}
+
+// LoadConfigFile reads a single config file into a Config, for commands limited to one scope.
+func LoadConfigFile(path string, scope ConfigScope) (config *Config, err error) {
+	entries, readErr := ReadConfigFile(path, scope)
+	if readErr != nil {
+		return nil, readErr
+	}
+	return &Config{entries: entries}, nil
+}
+
+// ReadConfigFile reads the entries of a single config file.
+//
+// Args:
+//
+//	path (string): The path of the config file.
+//	scope (ConfigScope): The scope recorded on every entry.
+//
+// Returns:
+//
+//	entries ([]ConfigEntry): The entries in file order with canonical keys ("section.key" or
+//	                         "section.subsection.key"), or none if the file does not exist.
+//	err (error): An error if the file cannot be read or contains a malformed line.
+func ReadConfigFile(path string, scope ConfigScope) (entries []ConfigEntry, err error) {
+	content, readErr := Files.ReadFile(path)
+	if errors.Is(readErr, os.ErrNotExist) {
+		return nil, nil
+	}
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	file, parseErr := parseConfigFile(string(content), path)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	return file.entries(scope), nil
+}
+
+// editConfigFile parses a config file (treating a missing file as empty), applies edit to it and
+// writes it back, creating the parent directory if needed. The file is locked from the read to the
+// write, so concurrent edits cannot lose each other's changes.
+func editConfigFile(path string, edit func(file *configFile) error) error {
+	if mkErr := Files.MkdirAll(filepath.Dir(path), 0755); mkErr != nil {
+		return mkErr
+	}
+	lock, lockErr := LockFile(path)
+	if lockErr != nil {
+		return lockErr
+	}
+	defer func() { _ = lock.Rollback() }()
+
+	content, readErr := Files.ReadFile(path)
+	if readErr != nil && !errors.Is(readErr, os.ErrNotExist) {
+		return readErr
+	}
+	file, parseErr := parseConfigFile(string(content), path)
+	if parseErr != nil {
+		return parseErr
+	}
+	if editErr := edit(file); editErr != nil {
+		return editErr
+	}
+	return lock.Commit([]byte(file.String()))
+}
+
+// Get returns the value of a key from the most specific scope that sets it. Section and key names
+// are case-insensitive; subsection names are not.
+func (c *Config) Get(key string) (value string, ok bool) {
+	key = normalizeConfigKey(key)
+	for i := len(c.entries) - 1; i >= 0; i-- {
+		if c.entries[i].Key == key {
+			return c.entries[i].Value, true
+		}
+	}
+	return "", false
+}
+
+// GetAll returns every value of a key in order of increasing precedence.
+func (c *Config) GetAll(key string) (values []string) {
+	key = normalizeConfigKey(key)
+	for _, entry := range c.entries {
+		if entry.Key == key {
+			values = append(values, entry.Value)
+		}
+	}
+	return values
+}
+
+// Entries returns all entries in order of increasing precedence.
+func (c *Config) Entries() []ConfigEntry {
+	return c.entries
+}
+
+// SetConfigValue sets a key in a single config file.
+//
+// Args:
+//
+//	path (string): The config file to modify, as returned by ConfigPath. It is created if missing.
+//	key (string): The key to set, e.g. "user.name" or "branch.main.remote".
+//	value (string): The new value.
+//	add (bool): When true, the value is added as one more value of a multi-valued key instead of
+//	            replacing the existing value.
+//
+// Returns:
+//
+//	err (error): An error if the key has no section, has several values and add is false, or if
+//	             the file cannot be read or written.
+//
+// Usage:
+//
+//	path, _ := ConfigPath(ScopeGlobal, "")
+//	if err := SetConfigValue(path, "core.editor", "vim", false); err != nil {
+//	    log.Fatalln(err)
+//	}
+//
+// Note:
+//   - Only the line of the key changes; comments and formatting of the rest of the file are kept.
+func SetConfigValue(path string, key string, value string, add bool) (err error) {
+	return editConfigFile(path, func(file *configFile) error {
+		return file.set(key, value, add)
+	})
+}
+
+// UnsetConfigValue removes a key from a single config file.
+//
+// Args:
+//
+//	path (string): The config file to modify, as returned by ConfigPath.
+//	key (string): The key to remove, e.g. "remote.origin.url".
+//	all (bool): When true, every value of a multi-valued key is removed.
+//
+// Returns:
+//
+//	removed (int): The number of entries removed; 0 if the key is not set in the file.
+//	err (error): An error if the key has several values and all is false, or if the file cannot
+//	             be read or written.
+func UnsetConfigValue(path string, key string, all bool) (removed int, err error) {
+	if _, statErr := Files.Stat(path); errors.Is(statErr, os.ErrNotExist) {
+		return 0, nil
+	}
+	err = editConfigFile(path, func(file *configFile) error {
+		var unsetErr error
+		removed, unsetErr = file.unset(key, all)
+		return unsetErr
+	})
+	return removed, err
+}
+
+// ParseConfigBool interprets a config value as a boolean. "true", "yes", "on" and "1" are true;
+// "false", "no", "off", "0" and the empty string are false. Case is ignored.
+func ParseConfigBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "true", "yes", "on", "1":
+		return true, nil
+	case "false", "no", "off", "0", "":
+		return false, nil
+	default:
+		return false, fmt.Errorf("bad boolean config value -> %s", value)
+	}
+}
+
+// ParseConfigInt interprets a config value as an integer, accepting a k, m or g suffix that
+// multiplies it by 1024, 1024^2 or 1024^3. A value that does not fit in an int64 once multiplied is
+// an error.
+func ParseConfigInt(value string) (int64, error) {
+	digits := value
+	multiplier := int64(1)
+	switch strings.ToLower(value[max(0, len(value)-1):]) {
+	case "k":
+		multiplier = 1 << 10
+	case "m":
+		multiplier = 1 << 20
+	case "g":
+		multiplier = 1 << 30
+	}
+	if multiplier > 1 {
+		digits = value[:len(value)-1]
+	}
+
+	number, parseErr := strconv.ParseInt(digits, 10, 64)
+	if parseErr != nil || number > math.MaxInt64/multiplier || number < math.MinInt64/multiplier {
+		return 0, fmt.Errorf("bad numeric config value -> %s", value)
+	}
+	return number * multiplier, nil
+}
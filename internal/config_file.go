@@ -0,0 +1,426 @@
+// File: config_file.go
+// Package: internal
+
+// Program Description:
+// This file parses and writes the INI-style config file format.
+// A file is made of [section] and [section "subsection"] headers followed by "key = value" lines,
+// with # and ; comments, double-quoted values and backslash escapes. Every line is kept with its
+// original text so editing one setting leaves the rest of the file, including comments and
+// indentation, untouched.
+
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// configLine is one line of a config file, or several lines for a value continued with a backslash.
+type configLine struct {
+	raw        string // The original text, including the newline.
+	header     bool   // True for section headers.
+	section    string // The section the line belongs to, lowercased.
+	subsection string // The subsection the line belongs to, case preserved.
+	name       string // The key name, lowercased; empty for headers, comments and blank lines.
+	value      string // The parsed value of a key line.
+}
+
+// configFile is a parsed config file.
+type configFile struct {
+	lines []configLine
+}
+
+// configSyntaxError reports a malformed config file.
+func configSyntaxError(content string, pos int, path string) error {
+	line := strings.Count(content[:min(pos, len(content))], "\n") + 1
+	return fmt.Errorf("bad config line %d in %s", line, path)
+}
+
+// parseConfigFile parses the content of a config file; path is only used in error messages.
+func parseConfigFile(content string, path string) (*configFile, error) {
+	file := &configFile{}
+	section, subsection := "", ""
+
+	for pos := 0; pos < len(content); {
+		start := pos
+		for pos < len(content) && (content[pos] == ' ' || content[pos] == '\t' || content[pos] == '\r') {
+			pos++
+		}
+
+		switch {
+		case pos == len(content) || content[pos] == '\n' || content[pos] == '#' || content[pos] == ';':
+			pos = endOfLine(content, pos)
+			file.lines = append(file.lines, configLine{raw: content[start:pos], section: section, subsection: subsection})
+
+		case content[pos] == '[':
+			name, sub, end, ok := parseSectionHeader(content, pos+1)
+			if !ok {
+				return nil, configSyntaxError(content, pos, path)
+			}
+			section, subsection = name, sub
+			pos = endOfLine(content, end)
+			if strings.TrimSpace(stripComment(content[end:pos])) != "" {
+				return nil, configSyntaxError(content, end, path)
+			}
+			file.lines = append(file.lines, configLine{raw: content[start:pos], header: true, section: section, subsection: subsection})
+
+		case isConfigNameStart(content[pos]):
+			if section == "" {
+				return nil, configSyntaxError(content, pos, path)
+			}
+			nameStart := pos
+			for pos < len(content) && isConfigNameChar(content[pos]) {
+				pos++
+			}
+			name := strings.ToLower(content[nameStart:pos])
+			for pos < len(content) && (content[pos] == ' ' || content[pos] == '\t') {
+				pos++
+			}
+
+			// A key without "=" is a boolean set to true.
+			value := "true"
+			if pos < len(content) && content[pos] == '=' {
+				parsed, end, err := parseConfigValue(content, pos+1)
+				if err != nil {
+					return nil, configSyntaxError(content, pos, path)
+				}
+				value, pos = parsed, end
+			} else if rest := stripComment(content[pos:endOfLine(content, pos)]); strings.TrimSpace(rest) != "" {
+				return nil, configSyntaxError(content, pos, path)
+			} else {
+				pos = endOfLine(content, pos)
+			}
+			file.lines = append(file.lines, configLine{raw: content[start:pos], section: section, subsection: subsection, name: name, value: value})
+
+		default:
+			return nil, configSyntaxError(content, pos, path)
+		}
+	}
+	return file, nil
+}
+
+// endOfLine returns the position just after the newline ending the line containing pos.
+func endOfLine(content string, pos int) int {
+	if end := strings.IndexByte(content[pos:], '\n'); end >= 0 {
+		return pos + end + 1
+	}
+	return len(content)
+}
+
+func stripComment(text string) string {
+	if i := strings.IndexAny(text, "#;"); i >= 0 {
+		return text[:i]
+	}
+	return text
+}
+
+func isConfigNameStart(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isConfigNameChar(c byte) bool {
+	return isConfigNameStart(c) || (c >= '0' && c <= '9') || c == '-'
+}
+
+// parseSectionHeader parses a header starting after its "[": either [section], [section "subsection"]
+// or the legacy [section.subsection]. It returns the position after the closing "]".
+func parseSectionHeader(content string, pos int) (section string, subsection string, end int, ok bool) {
+	nameStart := pos
+	for pos < len(content) && (isConfigNameChar(content[pos]) || content[pos] == '.') {
+		pos++
+	}
+	section = strings.ToLower(content[nameStart:pos])
+	if section == "" || pos == len(content) {
+		return "", "", 0, false
+	}
+
+	if content[pos] == ']' {
+		if name, legacy, found := strings.Cut(section, "."); found {
+			return name, legacy, pos + 1, true
+		}
+		return section, "", pos + 1, true
+	}
+
+	for pos < len(content) && (content[pos] == ' ' || content[pos] == '\t') {
+		pos++
+	}
+	if pos == len(content) || content[pos] != '"' || strings.Contains(section, ".") {
+		return "", "", 0, false
+	}
+	var sb strings.Builder
+	for pos++; pos < len(content); pos++ {
+		switch c := content[pos]; {
+		case c == '\n':
+			return "", "", 0, false
+		case c == '\\' && pos+1 < len(content) && content[pos+1] != '\n':
+			pos++
+			sb.WriteByte(content[pos])
+		case c == '"':
+			if pos+1 < len(content) && content[pos+1] == ']' {
+				return section, sb.String(), pos + 2, true
+			}
+			return "", "", 0, false
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", "", 0, false
+}
+
+// parseConfigValue parses a value starting after its "=" and returns it with the position after
+// the line it ends on. Whitespace outside quotes is trimmed at both ends, comments end the value,
+// and a backslash at the end of a line continues the value on the next one.
+func parseConfigValue(content string, pos int) (value string, end int, err error) {
+	var sb strings.Builder
+	quoted := false
+	spaces := 0
+	for ; pos < len(content); pos++ {
+		c := content[pos]
+		switch {
+		case c == '\n':
+			if quoted {
+				return "", 0, errors.New("unterminated quote")
+			}
+			return sb.String(), pos + 1, nil
+		case !quoted && (c == '#' || c == ';'):
+			return sb.String(), endOfLine(content, pos), nil
+		case !quoted && (c == ' ' || c == '\t' || c == '\r'):
+			if sb.Len() > 0 {
+				spaces++
+			}
+			continue
+		}
+
+		sb.WriteString(strings.Repeat(" ", spaces))
+		spaces = 0
+		switch c {
+		case '"':
+			quoted = !quoted
+		case '\\':
+			pos++
+			if pos == len(content) {
+				return "", 0, errors.New("bad escape")
+			}
+			switch content[pos] {
+			case '\n':
+				// Line continuation.
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case 'b':
+				sb.WriteByte('\b')
+			case '"', '\\':
+				sb.WriteByte(content[pos])
+			default:
+				return "", 0, errors.New("bad escape")
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	if quoted {
+		return "", 0, errors.New("unterminated quote")
+	}
+	return sb.String(), pos, nil
+}
+
+// ErrInvalidConfigKey is returned for a key that cannot be written to a config file, e.g. one
+// without a section or whose section contains a space.
+var ErrInvalidConfigKey = errors.New("invalid config key")
+
+// splitConfigKey splits "section.key" or "section.subsection.key" into its parts, lowercasing the
+// section and key name. The subsection keeps its case and may itself contain dots.
+//
+// The parts follow the rules of parseConfigFile, so that any key accepted here reads back the same:
+// the section is made of letters, digits and "-", the key name of the same but starting with a
+// letter, and the subsection, which is written quoted, may hold anything but a newline.
+func splitConfigKey(key string) (section string, subsection string, name string, err error) {
+	first := strings.IndexByte(key, '.')
+	last := strings.LastIndexByte(key, '.')
+	if first <= 0 || last == len(key)-1 {
+		return "", "", "", fmt.Errorf("%w: key does not contain a section: %s", ErrInvalidConfigKey, key)
+	}
+	section, name = strings.ToLower(key[:first]), strings.ToLower(key[last+1:])
+	if first != last {
+		subsection = key[first+1 : last]
+	}
+	for i := 0; i < len(section); i++ {
+		if !isConfigNameChar(section[i]) {
+			return "", "", "", fmt.Errorf("%w: invalid section name: %s", ErrInvalidConfigKey, key)
+		}
+	}
+	if strings.ContainsAny(subsection, "\n\x00") {
+		return "", "", "", fmt.Errorf("%w: invalid subsection name: %s", ErrInvalidConfigKey, key)
+	}
+	for i := 0; i < len(name); i++ {
+		if !isConfigNameChar(name[i]) || !isConfigNameStart(name[0]) {
+			return "", "", "", fmt.Errorf("%w: invalid key name: %s", ErrInvalidConfigKey, key)
+		}
+	}
+	return section, subsection, name, nil
+}
+
+// normalizeConfigKey returns the canonical form of a key, used to compare keys. Keys that are not
+// valid are only lowercased.
+func normalizeConfigKey(key string) string {
+	section, subsection, name, err := splitConfigKey(key)
+	if err != nil {
+		return strings.ToLower(key)
+	}
+	return joinConfigKey(section, subsection, name)
+}
+
+func joinConfigKey(section string, subsection string, name string) string {
+	if subsection == "" {
+		return section + "." + name
+	}
+	return section + "." + subsection + "." + name
+}
+
+// entries returns the key lines of the file as ConfigEntry values with canonical keys.
+func (f *configFile) entries(scope ConfigScope) (entries []ConfigEntry) {
+	for _, line := range f.lines {
+		if line.name != "" {
+			entries = append(entries, ConfigEntry{Key: joinConfigKey(line.section, line.subsection, line.name), Value: line.value, Scope: scope})
+		}
+	}
+	return entries
+}
+
+// set sets key to value, replacing its existing value unless add is true. New keys are placed at
+// the end of the last matching section, or in a new section at the end of the file.
+func (f *configFile) set(key string, value string, add bool) error {
+	section, subsection, name, err := splitConfigKey(key)
+	if err != nil {
+		return err
+	}
+
+	var matches []int
+	insertAt := -1
+	for i, line := range f.lines {
+		if line.section != section || line.subsection != subsection {
+			continue
+		}
+		if line.header || line.name != "" {
+			insertAt = i + 1
+		}
+		if line.name == name {
+			matches = append(matches, i)
+		}
+	}
+
+	// The line is written with the key name as given; lookups ignore its case.
+	written := key[strings.LastIndexByte(key, '.')+1:]
+	newLine := configLine{raw: formatConfigLine(written, value), section: section, subsection: subsection, name: name, value: value}
+	switch {
+	case !add && len(matches) > 1:
+		return fmt.Errorf("cannot overwrite multiple values of %s with a single value, use --add or unset --all first", key)
+	case !add && len(matches) == 1:
+		f.lines[matches[0]] = newLine
+	case insertAt >= 0:
+		if previous := &f.lines[insertAt-1]; !strings.HasSuffix(previous.raw, "\n") {
+			previous.raw += "\n"
+		}
+		f.lines = append(f.lines[:insertAt], append([]configLine{newLine}, f.lines[insertAt:]...)...)
+	default:
+		if n := len(f.lines); n > 0 && !strings.HasSuffix(f.lines[n-1].raw, "\n") {
+			f.lines[n-1].raw += "\n"
+		}
+		header := configLine{raw: formatSectionHeader(section, subsection), header: true, section: section, subsection: subsection}
+		f.lines = append(f.lines, header, newLine)
+	}
+	return nil
+}
+
+// unset removes key and returns the number of lines removed. The headers of the key's section are
+// removed as well when nothing, not even a comment, is left under them; other sections are kept
+// as they are, empty or not.
+func (f *configFile) unset(key string, all bool) (removed int, err error) {
+	section, subsection, name, splitErr := splitConfigKey(key)
+	if splitErr != nil {
+		return 0, splitErr
+	}
+
+	kept := make([]configLine, 0, len(f.lines))
+	for _, line := range f.lines {
+		if line.name == name && line.section == section && line.subsection == subsection {
+			removed++
+			continue
+		}
+		kept = append(kept, line)
+	}
+	if removed > 1 && !all {
+		return 0, fmt.Errorf("%s has multiple values, use unset --all to remove them", key)
+	}
+
+	f.lines = f.lines[:0]
+	for i, line := range kept {
+		emptied := removed > 0 && line.section == section && line.subsection == subsection
+		if emptied && line.header && (i+1 == len(kept) || kept[i+1].header) {
+			continue
+		}
+		f.lines = append(f.lines, line)
+	}
+	return removed, nil
+}
+
+// String renders the file.
+func (f *configFile) String() string {
+	var sb strings.Builder
+	for _, line := range f.lines {
+		sb.WriteString(line.raw)
+	}
+	return sb.String()
+}
+
+func formatSectionHeader(section string, subsection string) string {
+	if subsection == "" {
+		return "[" + section + "]\n"
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(subsection)
+	return "[" + section + " \"" + escaped + "\"]\n"
+}
+
+// formatConfigLine renders a key line, quoting the value when it would not survive parsing as is.
+func formatConfigLine(name string, value string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\b", `\b`).Replace(value)
+	if value != strings.TrimSpace(value) || strings.ContainsAny(value, "#;") {
+		escaped = `"` + escaped + `"`
+	}
+	return "\t" + name + " = " + escaped + "\n"
+}
+
+// removeSection removes every header and line of a section and returns whether any was found.
+func (f *configFile) removeSection(section string, subsection string) bool {
+	kept := f.lines[:0]
+	for _, line := range f.lines {
+		if line.section != section || line.subsection != subsection {
+			kept = append(kept, line)
+		}
+	}
+	found := len(kept) != len(f.lines)
+	f.lines = kept
+	return found
+}
+
+// renameSection moves every line of a section to another one, rewriting its headers.
+func (f *configFile) renameSection(section string, subsection string, newSection string, newSubsection string) (found bool, err error) {
+	for _, line := range f.lines {
+		if line.section == newSection && line.subsection == newSubsection {
+			return false, fmt.Errorf("section already exists -> %s", strings.TrimSuffix(joinConfigKey(newSection, newSubsection, ""), "."))
+		}
+	}
+	for i, line := range f.lines {
+		if line.section != section || line.subsection != subsection {
+			continue
+		}
+		found = true
+		f.lines[i].section, f.lines[i].subsection = newSection, newSubsection
+		if line.header {
+			f.lines[i].raw = formatSectionHeader(newSection, newSubsection)
+		}
+	}
+	return found, nil
+}
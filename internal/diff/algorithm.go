@@ -0,0 +1,84 @@
+// File: algorithm.go
+// Package: diff
+
+// Program Description:
+// This file selects the line diff algorithm.
+// Myers is the default; patience and histogram anchor the diff on lines that are rare in the
+// input, which keeps reordered blocks of code readable; minimal always produces the smallest
+// possible edit script.
+
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Algorithm names a line diff algorithm, as accepted by --diff-algorithm and diff.algorithm.
+type Algorithm string
+
+const (
+	AlgorithmMyers     Algorithm = "myers"
+	AlgorithmMinimal   Algorithm = "minimal"
+	AlgorithmPatience  Algorithm = "patience"
+	AlgorithmHistogram Algorithm = "histogram"
+)
+
+// ParseAlgorithm validates an algorithm name.
+//
+// Args:
+//
+//	name (string): The algorithm name, case-insensitive. "default" and the empty string select Myers.
+//
+// Returns:
+//
+//	algorithm (Algorithm): The selected algorithm.
+//	err (error): An error naming the valid choices if the name is unknown.
+//
+// Usage:
+//
+//	algorithm, err := ParseAlgorithm(diffAlgorithmFlag)
+//	if err != nil {
+//	    log.Fatalln(err)
+//	}
+//	edits := Compute(algorithm, oldLines, newLines)
+func ParseAlgorithm(name string) (algorithm Algorithm, err error) {
+	switch Algorithm(strings.ToLower(name)) {
+	case "", "default", AlgorithmMyers:
+		return AlgorithmMyers, nil
+	case AlgorithmMinimal:
+		return AlgorithmMinimal, nil
+	case AlgorithmPatience:
+		return AlgorithmPatience, nil
+	case AlgorithmHistogram:
+		return AlgorithmHistogram, nil
+	default:
+		return "", fmt.Errorf("unknown diff algorithm %s: use one of myers, minimal, patience or histogram", name)
+	}
+}
+
+// Compute runs the given algorithm on two sequences of lines.
+//
+// Args:
+//
+//	algorithm (Algorithm): The algorithm to use. Unknown values fall back to Myers.
+//	a ([]string): The lines of the old version, as returned by SplitLines.
+//	b ([]string): The lines of the new version, as returned by SplitLines.
+//
+// Returns:
+//
+//	[]Edit: The edit script.
+//
+// Note:
+//   - The linear-space Myers implementation never trades minimality for speed, so minimal and
+//     myers produce the same output.
+func Compute(algorithm Algorithm, a []string, b []string) []Edit {
+	switch algorithm {
+	case AlgorithmPatience:
+		return Patience(a, b)
+	case AlgorithmHistogram:
+		return Histogram(a, b)
+	default:
+		return Myers(a, b)
+	}
+}
@@ -0,0 +1,236 @@
+// File: binary.go
+// Package: diff
+
+// Program Description:
+// This file handles binary content in diffs.
+// Binary files are summarized as "Binary files ... differ" by default; with --binary they are
+// emitted as a "GIT binary patch" made of zlib-compressed, base85-encoded literal hunks that can be
+// applied to reproduce the new content exactly (and reversed to reproduce the old content).
+
+package diff
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// binarySniffLength is how much of a file is inspected when deciding whether it is binary.
+const binarySniffLength = 8000
+
+// binaryLineLength is the maximum number of decoded bytes on one line of a binary patch.
+const binaryLineLength = 52
+
+const base85Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz!#$%&()*+-;<=>?@^_`{|}~"
+
+// IsBinary reports whether content should be treated as binary: it contains a NUL byte within
+// the first 8000 bytes.
+func IsBinary(content []byte) bool {
+	if len(content) > binarySniffLength {
+		content = content[:binarySniffLength]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
+
+// BinarySummary returns the line printed instead of a textual diff for binary files.
+func BinarySummary(oldName string, newName string) string {
+	return fmt.Sprintf("Binary files %s and %s differ\n", oldName, newName)
+}
+
+// BinaryPatch encodes a change to a binary file in the "GIT binary patch" format.
+//
+// Args:
+//
+//	oldContent ([]byte): The old version of the file, empty for added files.
+//	newContent ([]byte): The new version of the file, empty for deleted files.
+//
+// Returns:
+//
+//	patch (string): The patch: a forward literal hunk holding the new content and a reverse literal
+//	                hunk holding the old content, each zlib-compressed and base85-encoded.
+//	err (error): Any error returned while compressing the content, nil otherwise.
+//
+// Usage:
+//
+//	patch, err := BinaryPatch(oldBlob, newBlob)
+//	if err != nil {
+//	    log.Fatalln(err)
+//	}
+//	fmt.Print("diff --jit a/logo.png b/logo.png\n" + patch)
+func BinaryPatch(oldContent []byte, newContent []byte) (patch string, err error) {
+	var sb strings.Builder
+	sb.WriteString("GIT binary patch\n")
+	if err = writeLiteralHunk(&sb, newContent); err != nil {
+		return "", err
+	}
+	if err = writeLiteralHunk(&sb, oldContent); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func writeLiteralHunk(sb *strings.Builder, content []byte) error {
+	var compressed bytes.Buffer
+	writer := zlib.NewWriter(&compressed)
+	if _, writeErr := writer.Write(content); writeErr != nil {
+		return writeErr
+	}
+	if closeErr := writer.Close(); closeErr != nil {
+		return closeErr
+	}
+
+	sb.WriteString(fmt.Sprintf("literal %d\n", len(content)))
+	data := compressed.Bytes()
+	for len(data) > 0 {
+		n := min(binaryLineLength, len(data))
+		sb.WriteByte(encodeLineLength(n))
+		sb.WriteString(encodeBase85(data[:n]))
+		sb.WriteString("\n")
+		data = data[n:]
+	}
+	sb.WriteString("\n")
+	return nil
+}
+
+// ParseBinaryPatch decodes a patch produced by BinaryPatch.
+//
+// Args:
+//
+//	patch (string): The patch text, starting at the "GIT binary patch" line.
+//
+// Returns:
+//
+//	newContent ([]byte): The content of the forward hunk, i.e. the new version of the file.
+//	oldContent ([]byte): The content of the reverse hunk, i.e. the old version of the file.
+//	err (error): An error if the patch is malformed or a decoded hunk does not have its declared size.
+//
+// Note:
+//   - Only literal hunks are supported; delta hunks are rejected with an error.
+func ParseBinaryPatch(patch string) (newContent []byte, oldContent []byte, err error) {
+	lines := strings.Split(patch, "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "GIT binary patch" {
+		return nil, nil, errors.New("missing 'GIT binary patch' header")
+	}
+	lines = lines[1:]
+
+	newContent, lines, err = readLiteralHunk(lines)
+	if err != nil {
+		return nil, nil, err
+	}
+	oldContent, _, err = readLiteralHunk(lines)
+	if err != nil {
+		return nil, nil, err
+	}
+	return newContent, oldContent, nil
+}
+
+func readLiteralHunk(lines []string) (content []byte, rest []string, err error) {
+	if len(lines) == 0 {
+		return nil, nil, errors.New("truncated binary patch")
+	}
+	kind, sizeText, _ := strings.Cut(lines[0], " ")
+	if kind != "literal" {
+		return nil, nil, fmt.Errorf("unsupported binary hunk '%s'", kind)
+	}
+	size, sizeErr := strconv.Atoi(sizeText)
+	if sizeErr != nil {
+		return nil, nil, fmt.Errorf("invalid binary hunk size '%s'", sizeText)
+	}
+
+	var compressed []byte
+	lines = lines[1:]
+	for len(lines) > 0 && lines[0] != "" {
+		n, lengthErr := decodeLineLength(lines[0][0])
+		if lengthErr != nil {
+			return nil, nil, lengthErr
+		}
+		decoded, decodeErr := decodeBase85(lines[0][1:], n)
+		if decodeErr != nil {
+			return nil, nil, decodeErr
+		}
+		compressed = append(compressed, decoded...)
+		lines = lines[1:]
+	}
+	if len(lines) > 0 {
+		lines = lines[1:]
+	}
+
+	reader, zlibErr := zlib.NewReader(bytes.NewReader(compressed))
+	if zlibErr != nil {
+		return nil, nil, zlibErr
+	}
+	content, err = io.ReadAll(reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(content) != size {
+		return nil, nil, fmt.Errorf("binary hunk decodes to %d bytes, expected %d", len(content), size)
+	}
+	return content, lines, nil
+}
+
+// encodeLineLength encodes the number of bytes on a line: 'A'-'Z' for 1-26 and 'a'-'z' for 27-52.
+func encodeLineLength(n int) byte {
+	if n <= 26 {
+		return byte('A' + n - 1)
+	}
+	return byte('a' + n - 27)
+}
+
+func decodeLineLength(c byte) (int, error) {
+	switch {
+	case c >= 'A' && c <= 'Z':
+		return int(c-'A') + 1, nil
+	case c >= 'a' && c <= 'z':
+		return int(c-'a') + 27, nil
+	default:
+		return 0, fmt.Errorf("invalid binary patch line length '%c'", c)
+	}
+}
+
+// encodeBase85 encodes data in groups of four bytes as five characters, zero-padding the last group.
+func encodeBase85(data []byte) string {
+	var sb strings.Builder
+	for i := 0; i < len(data); i += 4 {
+		var group uint32
+		for j := 0; j < 4; j++ {
+			group <<= 8
+			if i+j < len(data) {
+				group |= uint32(data[i+j])
+			}
+		}
+		var chars [5]byte
+		for j := 4; j >= 0; j-- {
+			chars[j] = base85Alphabet[group%85]
+			group /= 85
+		}
+		sb.Write(chars[:])
+	}
+	return sb.String()
+}
+
+func decodeBase85(text string, n int) ([]byte, error) {
+	if len(text) != (n+3)/4*5 {
+		return nil, fmt.Errorf("base85 line has %d characters, expected %d", len(text), (n+3)/4*5)
+	}
+	decoded := make([]byte, 0, len(text)/5*4)
+	for i := 0; i < len(text); i += 5 {
+		var group uint64
+		for j := 0; j < 5; j++ {
+			value := strings.IndexByte(base85Alphabet, text[i+j])
+			if value < 0 {
+				return nil, fmt.Errorf("invalid base85 character '%c'", text[i+j])
+			}
+			group = group*85 + uint64(value)
+		}
+		if group > 0xffffffff {
+			return nil, errors.New("base85 group overflows")
+		}
+		decoded = append(decoded, byte(group>>24), byte(group>>16), byte(group>>8), byte(group))
+	}
+	return decoded[:n], nil
+}
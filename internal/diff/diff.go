@@ -0,0 +1,119 @@
+// File: diff.go
+// Package: diff
+
+// Program Description:
+// This file defines the line-based edit script shared by the diff engine.
+// Content is split into lines, the lines are compared by an algorithm (Myers by default), and the
+// resulting edit script is grouped into hunks with context lines for display.
+
+package diff
+
+import (
+	"strings"
+)
+
+// OpKind is the kind of a single edit in an edit script.
+type OpKind int
+
+const (
+	Equal  OpKind = iota // The line is present in both versions.
+	Delete               // The line is only present in the old version.
+	Insert               // The line is only present in the new version.
+)
+
+// Edit is one line of an edit script.
+type Edit struct {
+	Kind    OpKind
+	OldLine int    // The 0-based line index in the old version, -1 for inserts.
+	NewLine int    // The 0-based line index in the new version, -1 for deletes.
+	Text    string // The line, including its terminating newline if it has one.
+	Ignored bool   // The change only touches lines the whitespace options ignore; it does not start a hunk.
+}
+
+// IsChange reports whether the edit is a delete or insert that is not ignored.
+func (e Edit) IsChange() bool {
+	return e.Kind != Equal && !e.Ignored
+}
+
+// SplitLines splits content into lines, keeping the terminating newline on each line.
+//
+// Args:
+//
+//	content (string): The text to split.
+//
+// Returns:
+//
+//	lines ([]string): The lines of the content. A final line without a newline is kept as is,
+//	                  and empty content yields no lines.
+func SplitLines(content string) (lines []string) {
+	for content != "" {
+		end := strings.IndexByte(content, '\n')
+		if end < 0 {
+			lines = append(lines, content)
+			break
+		}
+		lines = append(lines, content[:end+1])
+		content = content[end+1:]
+	}
+	return lines
+}
+
+// Lines computes the edit script that turns the old content into the new content.
+//
+// Args:
+//
+//	oldContent (string): The old version of the content.
+//	newContent (string): The new version of the content.
+//
+// Returns:
+//
+//	[]Edit: The line edit script, as produced by Myers.
+func Lines(oldContent string, newContent string) []Edit {
+	return Myers(SplitLines(oldContent), SplitLines(newContent))
+}
+
+// intern maps every distinct line to a small integer so the algorithms compare ints, not strings.
+func intern(a []string, b []string) (ia []int, ib []int) {
+	ids := map[string]int{}
+	lookup := func(line string) int {
+		id, ok := ids[line]
+		if !ok {
+			id = len(ids)
+			ids[line] = id
+		}
+		return id
+	}
+
+	ia = make([]int, len(a))
+	for i, line := range a {
+		ia[i] = lookup(line)
+	}
+	ib = make([]int, len(b))
+	for i, line := range b {
+		ib[i] = lookup(line)
+	}
+	return ia, ib
+}
+
+// buildEdits turns a list of matched (old, new) line pairs, in increasing order, into an edit script.
+func buildEdits(a []string, b []string, matches [][2]int) []Edit {
+	edits := make([]Edit, 0, len(a)+len(b))
+	x, y := 0, 0
+	emitUntil := func(toX int, toY int) {
+		for ; x < toX; x++ {
+			edits = append(edits, Edit{Kind: Delete, OldLine: x, NewLine: -1, Text: a[x]})
+		}
+		for ; y < toY; y++ {
+			edits = append(edits, Edit{Kind: Insert, OldLine: -1, NewLine: y, Text: b[y]})
+		}
+	}
+
+	for _, match := range matches {
+		emitUntil(match[0], match[1])
+		edits = append(edits, Edit{Kind: Equal, OldLine: x, NewLine: y, Text: a[x]})
+		x++
+		y++
+	}
+	emitUntil(len(a), len(b))
+	return edits
+}
@@ -0,0 +1,101 @@
+// File: histogram.go
+// Package: diff
+
+// Program Description:
+// This file implements the histogram diff algorithm.
+// It extends patience diff to lines that are merely rare instead of unique: the common region
+// built around the least frequent line of the old version is used to split the problem, and the
+// regions on either side are diffed recursively, falling back to Myers when no line is rare enough.
+
+package diff
+
+// maxHistogramChain bounds how often a line may occur in the old version and still be used as an anchor.
+const maxHistogramChain = 64
+
+// Histogram computes an edit script between two sequences of lines with the histogram algorithm.
+//
+// Args:
+//
+//	a ([]string): The lines of the old version, as returned by SplitLines.
+//	b ([]string): The lines of the new version, as returned by SplitLines.
+//
+// Returns:
+//
+//	[]Edit: The edit script.
+func Histogram(a []string, b []string) []Edit {
+	ia, ib := intern(a, b)
+	var matches [][2]int
+	histogramCompare(ia, ib, 0, len(ia), 0, len(ib), &matches)
+	return buildEdits(a, b, matches)
+}
+
+func histogramCompare(a []int, b []int, aLo int, aHi int, bLo int, bHi int, matches *[][2]int) {
+	for aLo < aHi && bLo < bHi && a[aLo] == b[bLo] {
+		*matches = append(*matches, [2]int{aLo, bLo})
+		aLo++
+		bLo++
+	}
+	suffixEnd := aHi
+	for aLo < aHi && bLo < bHi && a[aHi-1] == b[bHi-1] {
+		aHi--
+		bHi--
+	}
+
+	if aLo < aHi && bLo < bHi {
+		x, y, length, found := histogramRegion(a, b, aLo, aHi, bLo, bHi)
+		if !found {
+			myersCompare(a, b, aLo, aHi, bLo, bHi, matches)
+		} else {
+			histogramCompare(a, b, aLo, x, bLo, y, matches)
+			for i := 0; i < length; i++ {
+				*matches = append(*matches, [2]int{x + i, y + i})
+			}
+			histogramCompare(a, b, x+length, aHi, y+length, bHi, matches)
+		}
+	}
+
+	for i := 0; aHi+i < suffixEnd; i++ {
+		*matches = append(*matches, [2]int{aHi + i, bHi + i})
+	}
+}
+
+// histogramRegion finds the common region whose rarest line has the lowest occurrence count in
+// a[aLo:aHi], preferring longer regions on ties, and returns its start in both versions and its length.
+func histogramRegion(a []int, b []int, aLo int, aHi int, bLo int, bHi int) (x int, y int, length int, found bool) {
+	counts := map[int]int{}
+	positions := map[int][]int{}
+	for i := aLo; i < aHi; i++ {
+		counts[a[i]]++
+		positions[a[i]] = append(positions[a[i]], i)
+	}
+
+	lowest := maxHistogramChain + 1
+	for j := bLo; j < bHi; {
+		next := j + 1
+		if count := counts[b[j]]; count > 0 && count <= lowest {
+			for _, i := range positions[b[j]] {
+				startA, startB := i, j
+				for startA > aLo && startB > bLo && a[startA-1] == b[startB-1] {
+					startA--
+					startB--
+				}
+				endA, endB := i+1, j+1
+				for endA < aHi && endB < bHi && a[endA] == b[endB] {
+					endA++
+					endB++
+				}
+
+				regionCount := count
+				for k := startA; k < endA; k++ {
+					regionCount = min(regionCount, counts[a[k]])
+				}
+				if regionCount < lowest || (regionCount == lowest && endA-startA > length) {
+					x, y, length, lowest, found = startA, startB, endA-startA, regionCount, true
+				}
+				next = max(next, endB)
+			}
+		}
+		j = next
+	}
+	return x, y, length, found
+}
@@ -0,0 +1,152 @@
+// File: hunk.go
+// Package: diff
+
+// Program Description:
+// This file groups an edit script into hunks surrounded by context lines and renders
+// them in the unified diff format.
+
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultContext is the number of unchanged lines shown around each change.
+const DefaultContext = 3
+
+// Hunk is a contiguous region of an edit script with its surrounding context.
+type Hunk struct {
+	OldStart int    // The 0-based index of the first old line covered by the hunk.
+	OldLines int    // The number of old lines covered by the hunk.
+	NewStart int    // The 0-based index of the first new line covered by the hunk.
+	NewLines int    // The number of new lines covered by the hunk.
+	Edits    []Edit // The edits of the hunk, including context lines.
+}
+
+// MakeHunks groups the changes of an edit script into hunks.
+//
+// Args:
+//
+//	edits ([]Edit): The edit script, as returned by Myers.
+//	context (int): The number of unchanged lines to keep before and after each change.
+//
+// Returns:
+//
+//	hunks ([]Hunk): The hunks in order. Changes separated by at most 2*context unchanged lines are
+//	                merged into a single hunk. An edit script without changes yields no hunks.
+//	                Ignored changes are shown when they fall inside a hunk but never start one.
+func MakeHunks(edits []Edit, context int) (hunks []Hunk) {
+	if context < 0 {
+		context = 0
+	}
+
+	// oldPos[i] and newPos[i] are the old and new line positions reached before edits[i].
+	oldPos := make([]int, len(edits)+1)
+	newPos := make([]int, len(edits)+1)
+	for i, edit := range edits {
+		oldPos[i+1], newPos[i+1] = oldPos[i], newPos[i]
+		if edit.Kind != Insert {
+			oldPos[i+1]++
+		}
+		if edit.Kind != Delete {
+			newPos[i+1]++
+		}
+	}
+
+	for i := 0; i < len(edits); {
+		if !edits[i].IsChange() {
+			i++
+			continue
+		}
+
+		start := max(0, i-context)
+		lastChange := i
+		for j := i + 1; j < len(edits) && j <= lastChange+2*context+1; j++ {
+			if edits[j].IsChange() {
+				lastChange = j
+			}
+		}
+		end := min(len(edits), lastChange+context+1)
+
+		hunks = append(hunks, Hunk{
+			OldStart: oldPos[start],
+			OldLines: oldPos[end] - oldPos[start],
+			NewStart: newPos[start],
+			NewLines: newPos[end] - newPos[start],
+			Edits:    edits[start:end],
+		})
+		i = end
+	}
+	return hunks
+}
+
+// Header returns the "@@ -l,s +l,s @@" line of the hunk, without a trailing newline.
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", formatRange(h.OldStart, h.OldLines), formatRange(h.NewStart, h.NewLines))
+}
+
+// formatRange renders a hunk range the way unified diffs do: 1-based, the count is omitted when it
+// is one, and an empty range points at the line before it.
+func formatRange(start int, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, count)
+	}
+}
+
+// Unified renders an edit script in the unified diff format.
+//
+// Args:
+//
+//	oldName (string): The name printed on the "---" line, e.g. "a/file.txt" or "/dev/null".
+//	newName (string): The name printed on the "+++" line, e.g. "b/file.txt" or "/dev/null".
+//	edits ([]Edit): The edit script, as returned by Myers.
+//	context (int): The number of context lines around each change.
+//
+// Returns:
+//
+//	string: The unified diff, or an empty string if the edit script contains no changes.
+//
+// Usage:
+//
+//	fmt.Print(Unified("a/main.go", "b/main.go", Lines(oldContent, newContent), DefaultContext))
+//
+// Note:
+//   - A line without a terminating newline is followed by "\ No newline at end of file".
+func Unified(oldName string, newName string, edits []Edit, context int) string {
+	hunks := MakeHunks(edits, context)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("--- " + oldName + "\n")
+	sb.WriteString("+++ " + newName + "\n")
+	for _, hunk := range hunks {
+		sb.WriteString(hunk.Header() + "\n")
+		for _, edit := range hunk.Edits {
+			writeLine(&sb, edit)
+		}
+	}
+	return sb.String()
+}
+
+func writeLine(sb *strings.Builder, edit Edit) {
+	switch edit.Kind {
+	case Equal:
+		sb.WriteString(" ")
+	case Delete:
+		sb.WriteString("-")
+	case Insert:
+		sb.WriteString("+")
+	}
+	sb.WriteString(edit.Text)
+	if !strings.HasSuffix(edit.Text, "\n") {
+		sb.WriteString("\n\\ No newline at end of file\n")
+	}
+}
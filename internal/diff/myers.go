@@ -0,0 +1,123 @@
+// File: myers.go
+// Package: diff
+
+// Program Description:
+// This file implements the O(ND) difference algorithm by Eugene W. Myers.
+// The linear-space variant is used: the middle snake of an optimal edit path is found by searching
+// forwards and backwards at the same time, and the two halves around it are solved recursively.
+
+package diff
+
+// Myers computes a shortest edit script between two sequences of lines.
+//
+// Args:
+//
+//	a ([]string): The lines of the old version, as returned by SplitLines.
+//	b ([]string): The lines of the new version, as returned by SplitLines.
+//
+// Returns:
+//
+//	[]Edit: The edit script. Deletes are emitted before inserts within a changed region.
+//
+// Usage:
+//
+//	edits := Myers(SplitLines(oldContent), SplitLines(newContent))
+//	fmt.Print(Unified("a/file.txt", "b/file.txt", edits, 3))
+//
+// Note:
+//   - The algorithm runs in O((N+M)D) time and O(N+M) space, where D is the size of the edit script.
+func Myers(a []string, b []string) []Edit {
+	ia, ib := intern(a, b)
+	var matches [][2]int
+	myersCompare(ia, ib, 0, len(ia), 0, len(ib), &matches)
+	return buildEdits(a, b, matches)
+}
+
+// myersCompare appends to matches, in order, the matched line pairs of an optimal alignment of
+// a[aLo:aHi] and b[bLo:bHi].
+func myersCompare(a []int, b []int, aLo int, aHi int, bLo int, bHi int, matches *[][2]int) {
+	for aLo < aHi && bLo < bHi && a[aLo] == b[bLo] {
+		*matches = append(*matches, [2]int{aLo, bLo})
+		aLo++
+		bLo++
+	}
+
+	suffixEnd := aHi
+	for aLo < aHi && bLo < bHi && a[aHi-1] == b[bHi-1] {
+		aHi--
+		bHi--
+	}
+
+	if aLo < aHi && bLo < bHi {
+		x, y, u, v := middleSnake(a, b, aLo, aHi, bLo, bHi)
+		myersCompare(a, b, aLo, x, bLo, y, matches)
+		for i := 0; i < u-x; i++ {
+			*matches = append(*matches, [2]int{x + i, y + i})
+		}
+		myersCompare(a, b, u, aHi, v, bHi, matches)
+	}
+
+	for i := 0; aHi+i < suffixEnd; i++ {
+		*matches = append(*matches, [2]int{aHi + i, bHi + i})
+	}
+}
+
+// middleSnake finds the middle snake of an optimal edit path through a[aLo:aHi] and b[bLo:bHi]
+// and returns its start (x, y) and end (u, v) in absolute line indexes.
+func middleSnake(a []int, b []int, aLo int, aHi int, bLo int, bHi int) (x int, y int, u int, v int) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	odd := delta%2 != 0
+	maxD := (n + m + 1) / 2
+	offset := maxD + 1
+
+	forward := make([]int, 2*maxD+3)
+	backward := make([]int, 2*maxD+3)
+
+	for d := 0; d <= maxD; d++ {
+		for k := -d; k <= d; k += 2 {
+			var fx int
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				fx = forward[offset+k+1]
+			} else {
+				fx = forward[offset+k-1] + 1
+			}
+			fy := fx - k
+			startX, startY := fx, fy
+			for fx < n && fy < m && a[aLo+fx] == b[bLo+fy] {
+				fx++
+				fy++
+			}
+			forward[offset+k] = fx
+
+			reverseK := delta - k
+			if odd && reverseK >= -(d-1) && reverseK <= d-1 && fx+backward[offset+reverseK] >= n {
+				return aLo + startX, bLo + startY, aLo + fx, bLo + fy
+			}
+		}
+
+		for k := -d; k <= d; k += 2 {
+			var bx int
+			if k == -d || (k != d && backward[offset+k-1] < backward[offset+k+1]) {
+				bx = backward[offset+k+1]
+			} else {
+				bx = backward[offset+k-1] + 1
+			}
+			by := bx - k
+			startX, startY := bx, by
+			for bx < n && by < m && a[aHi-1-bx] == b[bHi-1-by] {
+				bx++
+				by++
+			}
+			backward[offset+k] = bx
+
+			forwardK := delta - k
+			if !odd && forwardK >= -d && forwardK <= d && bx+forward[offset+forwardK] >= n {
+				return aHi - bx, bHi - by, aHi - startX, bHi - startY
+			}
+		}
+	}
+
+	// Unreachable for non-empty inputs: the searches always meet by maxD.
+	return aLo, bLo, aLo, bLo
+}
@@ -0,0 +1,126 @@
+// File: patience.go
+// Package: diff
+
+// Program Description:
+// This file implements the patience diff algorithm.
+// Lines that occur exactly once on both sides are used as anchors; the longest increasing
+// sequence of anchors is kept, and the regions between them are diffed recursively, falling
+// back to Myers where no unique lines remain.
+
+package diff
+
+import (
+	"sort"
+)
+
+// Patience computes an edit script between two sequences of lines with the patience algorithm.
+//
+// Args:
+//
+//	a ([]string): The lines of the old version, as returned by SplitLines.
+//	b ([]string): The lines of the new version, as returned by SplitLines.
+//
+// Returns:
+//
+//	[]Edit: The edit script.
+func Patience(a []string, b []string) []Edit {
+	ia, ib := intern(a, b)
+	var matches [][2]int
+	patienceCompare(ia, ib, 0, len(ia), 0, len(ib), &matches)
+	return buildEdits(a, b, matches)
+}
+
+func patienceCompare(a []int, b []int, aLo int, aHi int, bLo int, bHi int, matches *[][2]int) {
+	for aLo < aHi && bLo < bHi && a[aLo] == b[bLo] {
+		*matches = append(*matches, [2]int{aLo, bLo})
+		aLo++
+		bLo++
+	}
+	suffixEnd := aHi
+	for aLo < aHi && bLo < bHi && a[aHi-1] == b[bHi-1] {
+		aHi--
+		bHi--
+	}
+
+	if aLo < aHi && bLo < bHi {
+		anchors := uniqueAnchors(a, b, aLo, aHi, bLo, bHi)
+		if len(anchors) == 0 {
+			myersCompare(a, b, aLo, aHi, bLo, bHi, matches)
+		} else {
+			x, y := aLo, bLo
+			for _, anchor := range anchors {
+				patienceCompare(a, b, x, anchor[0], y, anchor[1], matches)
+				*matches = append(*matches, anchor)
+				x, y = anchor[0]+1, anchor[1]+1
+			}
+			patienceCompare(a, b, x, aHi, y, bHi, matches)
+		}
+	}
+
+	for i := 0; aHi+i < suffixEnd; i++ {
+		*matches = append(*matches, [2]int{aHi + i, bHi + i})
+	}
+}
+
+// uniqueAnchors returns the longest increasing sequence of line pairs that occur exactly once in
+// a[aLo:aHi] and exactly once in b[bLo:bHi].
+func uniqueAnchors(a []int, b []int, aLo int, aHi int, bLo int, bHi int) [][2]int {
+	type occurrence struct {
+		countA, countB int
+		posA, posB     int
+	}
+	seen := map[int]*occurrence{}
+	for i := aLo; i < aHi; i++ {
+		occ, ok := seen[a[i]]
+		if !ok {
+			occ = &occurrence{}
+			seen[a[i]] = occ
+		}
+		occ.countA++
+		occ.posA = i
+	}
+	for j := bLo; j < bHi; j++ {
+		if occ, ok := seen[b[j]]; ok {
+			occ.countB++
+			occ.posB = j
+		}
+	}
+
+	var pairs [][2]int
+	for _, occ := range seen {
+		if occ.countA == 1 && occ.countB == 1 {
+			pairs = append(pairs, [2]int{occ.posA, occ.posB})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i][0] < pairs[j][0] })
+	return longestIncreasing(pairs)
+}
+
+// longestIncreasing returns the longest subsequence of pairs (sorted by old position) whose new
+// positions are increasing, using patience sorting.
+func longestIncreasing(pairs [][2]int) [][2]int {
+	if len(pairs) == 0 {
+		return nil
+	}
+	var tails []int // tails[k] is the index in pairs of the smallest tail of an increasing run of length k+1.
+	previous := make([]int, len(pairs))
+	for i, pair := range pairs {
+		k := sort.Search(len(tails), func(k int) bool { return pairs[tails[k]][1] >= pair[1] })
+		if k > 0 {
+			previous[i] = tails[k-1]
+		} else {
+			previous[i] = -1
+		}
+		if k == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[k] = i
+		}
+	}
+
+	result := make([][2]int, len(tails))
+	for i, k := tails[len(tails)-1], len(tails)-1; k >= 0; i, k = previous[i], k-1 {
+		result[k] = pairs[i]
+	}
+	return result
+}
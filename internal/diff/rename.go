@@ -0,0 +1,226 @@
+// File: rename.go
+// Package: diff
+
+// Program Description:
+// This file implements rename and copy detection.
+// Deleted and added files are paired by content similarity so that a file moved (and possibly
+// edited) is reported as a rename instead of an unrelated deletion and addition, and added files
+// are matched against existing files so substantial copies can be reported with their origin.
+
+package diff
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultRenameThreshold is the similarity percentage a pair needs to be reported as a rename or copy.
+const DefaultRenameThreshold = 50
+
+// Rename pairs a deleted path with the added path it was renamed to.
+type Rename struct {
+	OldPath    string
+	NewPath    string
+	Similarity int // The similarity of the two versions as a percentage, 100 for exact renames.
+}
+
+// ParseSimilarityThreshold parses the argument of -M (and -C) into a percentage.
+//
+// Args:
+//
+//	value (string): The text after -M. Empty selects the default of 50%. A value ending in "%" is a
+//	                percentage ("75%"); otherwise the digits are read as a decimal fraction, so "5"
+//	                means 50% and "05" means 5%.
+//
+// Returns:
+//
+//	threshold (int): The threshold as a percentage between 0 and 100.
+//	err (error): An error if the value is not a valid threshold.
+func ParseSimilarityThreshold(value string) (threshold int, err error) {
+	if value == "" {
+		return DefaultRenameThreshold, nil
+	}
+
+	if percent, isPercent := strings.CutSuffix(value, "%"); isPercent {
+		threshold, err = strconv.Atoi(percent)
+		if err != nil || threshold < 0 || threshold > 100 {
+			return 0, fmt.Errorf("invalid similarity threshold -> %s", value)
+		}
+		return threshold, nil
+	}
+
+	fraction, parseErr := strconv.ParseFloat("0."+value, 64)
+	if parseErr != nil || strings.ContainsAny(value, ".-+eE") {
+		return 0, fmt.Errorf("invalid similarity threshold -> %s", value)
+	}
+	return int(fraction*100 + 0.5), nil
+}
+
+// Similarity scores how similar two versions of a file are.
+//
+// Args:
+//
+//	oldContent (string): The content of the first version.
+//	newContent (string): The content of the second version.
+//
+// Returns:
+//
+//	int: A percentage between 0 and 100: the number of bytes in lines the two versions have in
+//	     common, divided by the size of the larger version. Identical content scores 100.
+func Similarity(oldContent string, newContent string) int {
+	if oldContent == newContent {
+		return 100
+	}
+	larger := max(len(oldContent), len(newContent))
+	if larger == 0 {
+		return 100
+	}
+
+	available := map[string]int{}
+	for _, line := range SplitLines(oldContent) {
+		available[line]++
+	}
+	shared := 0
+	for _, line := range SplitLines(newContent) {
+		if available[line] > 0 {
+			available[line]--
+			shared += len(line)
+		}
+	}
+	// Never report a non-identical pair as 100% similar.
+	return min(99, shared*100/larger)
+}
+
+type renameCandidate struct {
+	oldPath, newPath string
+	score            int
+	sameBase         bool
+}
+
+// DetectRenames pairs deleted files with added files by content similarity.
+//
+// Args:
+//
+//	deleted (map[string]string): The content of every deleted path, keyed by path.
+//	added (map[string]string): The content of every added path, keyed by path.
+//	threshold (int): The minimum similarity percentage for a pair to count as a rename.
+//
+// Returns:
+//
+//	renames ([]Rename): The detected renames, sorted by new path.
+//	remainingDeleted ([]string): The deleted paths that were not paired, sorted.
+//	remainingAdded ([]string): The added paths that were not paired, sorted.
+//
+// The function performs the following steps:
+//  1. Scores every deleted/added pair with Similarity, skipping pairs below the threshold.
+//  2. Orders candidates by score, preferring pairs that keep the same file name on ties.
+//  3. Greedily accepts the best candidates so each path takes part in at most one rename.
+//
+// Usage:
+//
+//	renames, deleted, added := DetectRenames(deletedFiles, addedFiles, DefaultRenameThreshold)
+func DetectRenames(deleted map[string]string, added map[string]string, threshold int) (renames []Rename, remainingDeleted []string, remainingAdded []string) {
+	candidates := scoreCandidates(deleted, added, threshold)
+
+	usedOld := map[string]bool{}
+	usedNew := map[string]bool{}
+	for _, candidate := range candidates {
+		if usedOld[candidate.oldPath] || usedNew[candidate.newPath] {
+			continue
+		}
+		usedOld[candidate.oldPath] = true
+		usedNew[candidate.newPath] = true
+		renames = append(renames, Rename{OldPath: candidate.oldPath, NewPath: candidate.newPath, Similarity: candidate.score})
+	}
+	sort.Slice(renames, func(i, j int) bool { return renames[i].NewPath < renames[j].NewPath })
+
+	return renames, unusedPaths(deleted, usedOld), unusedPaths(added, usedNew)
+}
+
+// scoreCandidates scores every old/new pair and returns those at or above the threshold, best first.
+func scoreCandidates(oldFiles map[string]string, newFiles map[string]string, threshold int) (candidates []renameCandidate) {
+	for oldPath, oldContent := range oldFiles {
+		for newPath, newContent := range newFiles {
+			score := Similarity(oldContent, newContent)
+			if score < threshold {
+				continue
+			}
+			candidates = append(candidates, renameCandidate{
+				oldPath:  oldPath,
+				newPath:  newPath,
+				score:    score,
+				sameBase: path.Base(oldPath) == path.Base(newPath),
+			})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.sameBase != b.sameBase {
+			return a.sameBase
+		}
+		if a.newPath != b.newPath {
+			return a.newPath < b.newPath
+		}
+		return a.oldPath < b.oldPath
+	})
+	return candidates
+}
+
+func unusedPaths(files map[string]string, used map[string]bool) (paths []string) {
+	for filePath := range files {
+		if !used[filePath] {
+			paths = append(paths, filePath)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Copy pairs an added path with the existing path it was copied from.
+type Copy struct {
+	OldPath    string
+	NewPath    string
+	Similarity int // The similarity of the two versions as a percentage, 100 for exact copies.
+}
+
+// DetectCopies finds added files that are substantially copies of existing files.
+//
+// Args:
+//
+//	sources (map[string]string): The content of every path that may have been copied from, keyed by
+//	                             path. With -C these are the modified and deleted paths; with
+//	                             --find-copies-harder every path of the old version is a source.
+//	added (map[string]string): The content of every added path not already reported as a rename.
+//	threshold (int): The minimum similarity percentage for a pair to count as a copy.
+//
+// Returns:
+//
+//	copies ([]Copy): The detected copies, sorted by new path.
+//	remainingAdded ([]string): The added paths that were not paired, sorted.
+//
+// Note:
+//   - Unlike renames, a single source may be the origin of several copies.
+//   - Run DetectRenames first and pass its remaining added paths, so a moved file is reported as a
+//     rename rather than a copy.
+func DetectCopies(sources map[string]string, added map[string]string, threshold int) (copies []Copy, remainingAdded []string) {
+	candidates := scoreCandidates(sources, added, threshold)
+
+	usedNew := map[string]bool{}
+	for _, candidate := range candidates {
+		if usedNew[candidate.newPath] {
+			continue
+		}
+		usedNew[candidate.newPath] = true
+		copies = append(copies, Copy{OldPath: candidate.oldPath, NewPath: candidate.newPath, Similarity: candidate.score})
+	}
+	sort.Slice(copies, func(i, j int) bool { return copies[i].NewPath < copies[j].NewPath })
+
+	return copies, unusedPaths(added, usedNew)
+}
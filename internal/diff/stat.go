@@ -0,0 +1,192 @@
+// File: stat.go
+// Package: diff
+
+// Program Description:
+// This file renders diff statistics: the --stat summary with a histogram bar per file, the
+// machine-readable --numstat table, in lines or NUL-terminated for -z, and the one-line
+// --shortstat totals.
+
+package diff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultStatWidth is the total width of --stat output when the terminal width is unknown.
+const DefaultStatWidth = 80
+
+// FileStat holds the change counts of one file.
+type FileStat struct {
+	Path       string // The path shown, e.g. "src/main.go" or "old.txt => new.txt" for renames.
+	OldPath    string // For a rename or copy, the path of the old side; empty otherwise.
+	NewPath    string // For a rename or copy, the path of the new side; empty otherwise.
+	Insertions int    // The number of added lines.
+	Deletions  int    // The number of removed lines.
+	Binary     bool   // True for binary files, which have sizes instead of line counts.
+	OldSize    int    // The size of the old version in bytes, used for binary files.
+	NewSize    int    // The size of the new version in bytes, used for binary files.
+}
+
+// CountChanges counts the inserted and deleted lines of an edit script, skipping ignored changes.
+func CountChanges(edits []Edit) (insertions int, deletions int) {
+	for _, edit := range edits {
+		if !edit.IsChange() {
+			continue
+		}
+		if edit.Kind == Insert {
+			insertions++
+		} else {
+			deletions++
+		}
+	}
+	return insertions, deletions
+}
+
+// Stat renders the --stat summary.
+//
+// Args:
+//
+//	stats ([]FileStat): The statistics of every changed file, in display order.
+//	width (int): The total width available, usually the terminal width. Values below 40 use DefaultStatWidth.
+//
+// Returns:
+//
+//	string: One " path | count +++---" line per file followed by the Shortstat line, or an empty
+//	        string if there are no files.
+//
+// The function performs the following steps:
+//  1. Sizes the path column to the longest path, truncating long paths from the left with "...".
+//  2. Prints the total number of changed lines and a bar of "+" and "-" for each file, scaling the
+//     bars down proportionally when the largest change does not fit in the remaining width.
+//  3. Prints "Bin <old> -> <new> bytes" instead of a bar for binary files.
+//
+// Usage:
+//
+//	fmt.Print(Stat(stats, DefaultStatWidth))
+func Stat(stats []FileStat, width int) string {
+	if len(stats) == 0 {
+		return ""
+	}
+	if width < 40 {
+		width = DefaultStatWidth
+	}
+
+	nameWidth, maxChange := 0, 0
+	for _, stat := range stats {
+		nameWidth = max(nameWidth, utf8.RuneCountInString(stat.Path))
+		if !stat.Binary {
+			maxChange = max(maxChange, stat.Insertions+stat.Deletions)
+		}
+	}
+	countWidth := max(len(strconv.Itoa(maxChange)), len("Bin"))
+	nameWidth = min(nameWidth, width*5/8)
+
+	// Leave room for the leading space, " | ", the count and the space before the bar.
+	barWidth := max(1, width-nameWidth-countWidth-5)
+
+	var sb strings.Builder
+	for _, stat := range stats {
+		name := truncatePath(stat.Path, nameWidth)
+		padding := strings.Repeat(" ", nameWidth-utf8.RuneCountInString(name))
+		if stat.Binary {
+			sb.WriteString(fmt.Sprintf(" %s%s | %*s %d -> %d bytes\n", name, padding, countWidth, "Bin", stat.OldSize, stat.NewSize))
+			continue
+		}
+
+		plus, minus := stat.Insertions, stat.Deletions
+		if maxChange > barWidth {
+			total := scaleLinear(plus+minus, barWidth, maxChange)
+			plus = scaleLinear(plus, barWidth, maxChange)
+			minus = total - plus
+		}
+		sb.WriteString(fmt.Sprintf(" %s%s | %*d", name, padding, countWidth, stat.Insertions+stat.Deletions))
+		if plus+minus > 0 {
+			sb.WriteString(" " + strings.Repeat("+", plus) + strings.Repeat("-", minus))
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString(Shortstat(stats))
+	return sb.String()
+}
+
+// scaleLinear scales a count to the bar width, keeping every non-zero count visible.
+func scaleLinear(count int, width int, maxChange int) int {
+	if count == 0 {
+		return 0
+	}
+	return 1 + count*(width-1)/maxChange
+}
+
+func truncatePath(path string, width int) string {
+	runes := []rune(path)
+	if len(runes) <= width {
+		return path
+	}
+	return "..." + string(runes[len(runes)-width+3:])
+}
+
+// Numstat renders the --numstat table: insertions, deletions and path separated by tabs, with "-"
+// for the counts of binary files.
+func Numstat(stats []FileStat) string {
+	var sb strings.Builder
+	for _, stat := range stats {
+		if stat.Binary {
+			sb.WriteString(fmt.Sprintf("-\t-\t%s\n", stat.Path))
+		} else {
+			sb.WriteString(fmt.Sprintf("%d\t%d\t%s\n", stat.Insertions, stat.Deletions, stat.Path))
+		}
+	}
+	return sb.String()
+}
+
+// NumstatZ renders the --numstat table for -z: every path is followed by a NUL instead of a
+// newline and left unabbreviated, and a rename or copy is written as an empty path followed by the
+// old and the new path, e.g. "1\t1\t\x00old.txt\x00new.txt\x00".
+func NumstatZ(stats []FileStat) string {
+	var sb strings.Builder
+	for _, stat := range stats {
+		if stat.Binary {
+			sb.WriteString("-\t-\t")
+		} else {
+			sb.WriteString(fmt.Sprintf("%d\t%d\t", stat.Insertions, stat.Deletions))
+		}
+		if stat.OldPath != "" {
+			sb.WriteString("\x00" + stat.OldPath + "\x00" + stat.NewPath + "\x00")
+		} else {
+			sb.WriteString(stat.Path + "\x00")
+		}
+	}
+	return sb.String()
+}
+
+// Shortstat renders the --shortstat line, e.g. " 2 files changed, 5 insertions(+), 1 deletion(-)".
+// Zero counts are omitted unless both are zero.
+func Shortstat(stats []FileStat) string {
+	if len(stats) == 0 {
+		return ""
+	}
+	insertions, deletions := 0, 0
+	for _, stat := range stats {
+		insertions += stat.Insertions
+		deletions += stat.Deletions
+	}
+
+	line := " " + plural(len(stats), "file changed", "files changed")
+	if insertions > 0 || deletions == 0 {
+		line += ", " + plural(insertions, "insertion(+)", "insertions(+)")
+	}
+	if deletions > 0 || insertions == 0 {
+		line += ", " + plural(deletions, "deletion(-)", "deletions(-)")
+	}
+	return line + "\n"
+}
+
+func plural(count int, singular string, pluralForm string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, singular)
+	}
+	return fmt.Sprintf("%d %s", count, pluralForm)
+}
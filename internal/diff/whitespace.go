@@ -0,0 +1,116 @@
+// File: whitespace.go
+// Package: diff
+
+// Program Description:
+// This file implements the whitespace-insensitive diff options (-w, -b and --ignore-blank-lines).
+// Lines are compared through a normalized key so re-indented or re-spaced lines match, while the
+// edit script still carries the original text for display.
+
+package diff
+
+import (
+	"strings"
+	"unicode"
+)
+
+// WhitespaceOptions selects which whitespace differences the diff ignores.
+type WhitespaceOptions struct {
+	IgnoreAllSpace    bool // -w: ignore all whitespace when comparing lines.
+	IgnoreSpaceChange bool // -b: ignore changes in the amount of whitespace and whitespace at end of line.
+	IgnoreBlankLines  bool // --ignore-blank-lines: ignore changes whose lines are all blank.
+}
+
+// CompareLines runs a diff algorithm with whitespace options applied.
+//
+// Args:
+//
+//	algorithm (Algorithm): The algorithm to use, as for Compute.
+//	a ([]string): The lines of the old version, as returned by SplitLines.
+//	b ([]string): The lines of the new version, as returned by SplitLines.
+//	ws (WhitespaceOptions): The whitespace differences to ignore.
+//
+// Returns:
+//
+//	edits ([]Edit): The edit script. Text always holds the original lines; equal lines show the old
+//	                version. With IgnoreBlankLines, runs of changes made only of blank lines are
+//	                marked Ignored so they do not produce hunks of their own.
+//
+// Usage:
+//
+//	ws := WhitespaceOptions{IgnoreAllSpace: true}
+//	fmt.Print(Unified("a/main.go", "b/main.go", CompareLines(AlgorithmMyers, oldLines, newLines, ws), DefaultContext))
+func CompareLines(algorithm Algorithm, a []string, b []string, ws WhitespaceOptions) (edits []Edit) {
+	if !ws.IgnoreAllSpace && !ws.IgnoreSpaceChange {
+		edits = Compute(algorithm, a, b)
+	} else {
+		edits = Compute(algorithm, ws.normalizeAll(a), ws.normalizeAll(b))
+		for i, edit := range edits {
+			if edit.Kind == Insert {
+				edits[i].Text = b[edit.NewLine]
+			} else {
+				edits[i].Text = a[edit.OldLine]
+			}
+		}
+	}
+
+	if ws.IgnoreBlankLines {
+		markBlankChanges(edits)
+	}
+	return edits
+}
+
+func (ws WhitespaceOptions) normalizeAll(lines []string) []string {
+	normalized := make([]string, len(lines))
+	for i, line := range lines {
+		normalized[i] = ws.normalize(line)
+	}
+	return normalized
+}
+
+// normalize returns the key a line is compared by. The line terminator counts as whitespace, so a
+// missing newline at the end of the file is ignored as well.
+func (ws WhitespaceOptions) normalize(line string) string {
+	if ws.IgnoreAllSpace {
+		return strings.Map(func(r rune) rune {
+			if unicode.IsSpace(r) {
+				return -1
+			}
+			return r
+		}, line)
+	}
+	// Collapse every run of whitespace into a single space and drop whitespace at the end of the line.
+	var sb strings.Builder
+	inSpace := false
+	for _, r := range strings.TrimRightFunc(line, unicode.IsSpace) {
+		if unicode.IsSpace(r) {
+			inSpace = true
+			continue
+		}
+		if inSpace {
+			sb.WriteByte(' ')
+			inSpace = false
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// markBlankChanges marks every maximal run of changes whose lines are all blank as Ignored.
+func markBlankChanges(edits []Edit) {
+	for start := 0; start < len(edits); {
+		if edits[start].Kind == Equal {
+			start++
+			continue
+		}
+		end, blank := start, true
+		for ; end < len(edits) && edits[end].Kind != Equal; end++ {
+			blank = blank && strings.TrimSpace(edits[end].Text) == ""
+		}
+		if blank {
+			for i := start; i < end; i++ {
+				edits[i].Ignored = true
+			}
+		}
+		start = end
+	}
+}
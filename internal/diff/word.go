@@ -0,0 +1,246 @@
+// File: word.go
+// Package: diff
+
+// Program Description:
+// This file implements word-level diff output (--word-diff and --color-words).
+// Changed regions of a line diff are split into words and whitespace and diffed again, so
+// intra-line changes are highlighted inline instead of showing whole lines as removed and added.
+
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// WordDiffMode selects how word-level changes are marked.
+type WordDiffMode string
+
+const (
+	WordDiffPlain WordDiffMode = "plain" // Removed words as [-word-] and added words as {+word+}.
+	WordDiffColor WordDiffMode = "color" // Removed words in red and added words in green, as --color-words.
+)
+
+const (
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+	colorReset = "\x1b[m"
+)
+
+// ParseWordDiffMode validates the argument of --word-diff.
+//
+// Args:
+//
+//	mode (string): "plain", "color", or empty for plain.
+//
+// Returns:
+//
+//	WordDiffMode: The selected mode.
+//	error: An error if the mode is unknown.
+func ParseWordDiffMode(mode string) (WordDiffMode, error) {
+	switch WordDiffMode(mode) {
+	case "", WordDiffPlain:
+		return WordDiffPlain, nil
+	case WordDiffColor:
+		return WordDiffColor, nil
+	default:
+		return "", fmt.Errorf("unknown word diff mode %s: use plain or color", mode)
+	}
+}
+
+// wordWriter collects word diff output and remembers the last byte written, which decides whether
+// a deletion needs a separating space, without materializing the output so far.
+type wordWriter struct {
+	strings.Builder
+	last byte
+}
+
+func (w *wordWriter) WriteString(text string) (int, error) {
+	if text != "" {
+		w.last = text[len(text)-1]
+	}
+	return w.Builder.WriteString(text)
+}
+
+// splitWords splits text into alternating runs of whitespace and non-whitespace. Every newline is
+// a token of its own so line structure survives the word diff.
+func splitWords(text string) (tokens []string) {
+	start := 0
+	var previous rune
+	for i, r := range text {
+		if i > start && (r == '\n' || previous == '\n' || unicode.IsSpace(r) != unicode.IsSpace(previous)) {
+			tokens = append(tokens, text[start:i])
+			start = i
+		}
+		previous = r
+	}
+	if start < len(text) {
+		tokens = append(tokens, text[start:])
+	}
+	return tokens
+}
+
+// WordDiff renders an edit script with word-level change markers.
+//
+// Args:
+//
+//	oldName (string): The name printed on the "---" line.
+//	newName (string): The name printed on the "+++" line.
+//	edits ([]Edit): The line edit script, as returned by Myers.
+//	mode (WordDiffMode): How removed and added words are marked.
+//	context (int): The number of context lines around each change.
+//
+// Returns:
+//
+//	string: The word diff, or an empty string if the edit script contains no changes.
+//
+// The function performs the following steps:
+//  1. Groups the line edit script into hunks, exactly like Unified.
+//  2. Prints unchanged lines as they are, without the leading space of the unified format.
+//  3. Re-diffs every run of removed and added lines word by word and marks the changed words.
+//     Whitespace-only changes are not marked; the new whitespace is printed.
+//
+// Usage:
+//
+//	fmt.Print(WordDiff("a/README.md", "b/README.md", Lines(oldContent, newContent), WordDiffPlain, DefaultContext))
+func WordDiff(oldName string, newName string, edits []Edit, mode WordDiffMode, context int) string {
+	hunks := MakeHunks(edits, context)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var sb wordWriter
+	sb.WriteString("--- " + oldName + "\n")
+	sb.WriteString("+++ " + newName + "\n")
+	for _, hunk := range hunks {
+		sb.WriteString(hunk.Header() + "\n")
+
+		var removed, added strings.Builder
+		flush := func() {
+			if removed.Len() > 0 || added.Len() > 0 {
+				writeWordChanges(&sb, removed.String(), added.String(), mode)
+				removed.Reset()
+				added.Reset()
+			}
+		}
+		for _, edit := range hunk.Edits {
+			switch edit.Kind {
+			case Equal:
+				flush()
+				sb.WriteString(edit.Text)
+			case Delete:
+				removed.WriteString(edit.Text)
+			case Insert:
+				added.WriteString(edit.Text)
+			}
+		}
+		flush()
+	}
+	return sb.String()
+}
+
+func writeWordChanges(sb *wordWriter, oldText string, newText string, mode WordDiffMode) {
+	// A run that only removes lines has no new line structure to follow; keep the old one.
+	if newText == "" && strings.HasSuffix(oldText, "\n") {
+		newText = "\n"
+	}
+
+	// Only words are compared; the whitespace printed is always the whitespace of the new version.
+	oldWords := strings.Fields(oldText)
+	newTokens := splitWords(newText)
+	var newWords []string
+	var newWordIndex []int
+	for i, token := range newTokens {
+		if strings.TrimSpace(token) != "" {
+			newWords = append(newWords, token)
+			newWordIndex = append(newWordIndex, i)
+		}
+	}
+
+	written := 0
+	writeTokensUpTo := func(end int) {
+		for ; written < end; written++ {
+			sb.WriteString(newTokens[written])
+		}
+	}
+
+	var removed []string
+	firstAdded, lastAdded := -1, -1
+	flush := func(next int) {
+		if firstAdded >= 0 {
+			writeTokensUpTo(firstAdded)
+			if len(removed) > 0 {
+				sb.WriteString(markWords(strings.Join(removed, " "), false, mode))
+			}
+			writeInserted(sb, strings.Join(newTokens[firstAdded:lastAdded+1], ""), mode)
+			written = lastAdded + 1
+		} else if len(removed) > 0 {
+			// Place a pure deletion before the line break that follows it, separated by single spaces.
+			whitespace := strings.Join(newTokens[written:next], "")
+			head, rest, _ := strings.Cut(whitespace, "\n")
+			if rest != "" || strings.HasSuffix(whitespace, "\n") {
+				rest = "\n" + rest
+			}
+			sb.WriteString(head)
+			if sb.last != 0 && sb.last != ' ' && sb.last != '\n' {
+				sb.WriteString(" ")
+			}
+			sb.WriteString(markWords(strings.Join(removed, " "), false, mode))
+			if rest == "" && next < len(newTokens) {
+				sb.WriteString(" ")
+			}
+			sb.WriteString(rest)
+			written = next
+		}
+		removed, firstAdded, lastAdded = nil, -1, -1
+	}
+
+	for _, edit := range Myers(oldWords, newWords) {
+		switch edit.Kind {
+		case Equal:
+			flush(newWordIndex[edit.NewLine])
+			writeTokensUpTo(newWordIndex[edit.NewLine] + 1)
+		case Delete:
+			removed = append(removed, edit.Text)
+		case Insert:
+			if firstAdded < 0 {
+				firstAdded = newWordIndex[edit.NewLine]
+			}
+			lastAdded = newWordIndex[edit.NewLine]
+		}
+	}
+	flush(len(newTokens))
+	writeTokensUpTo(len(newTokens))
+}
+
+// writeInserted marks the non-whitespace part of every line of inserted text, printing the
+// whitespace and newlines around it unmarked.
+func writeInserted(sb *wordWriter, text string, mode WordDiffMode) {
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			sb.WriteString(line)
+			continue
+		}
+		leading := line[:strings.Index(line, trimmed)]
+		trailing := line[len(leading)+len(trimmed):]
+		sb.WriteString(leading + markWords(trimmed, true, mode) + trailing)
+	}
+}
+
+func markWords(text string, inserted bool, mode WordDiffMode) string {
+	switch {
+	case mode == WordDiffColor && inserted:
+		return colorGreen + text + colorReset
+	case mode == WordDiffColor:
+		return colorRed + text + colorReset
+	case inserted:
+		return "{+" + text + "+}"
+	default:
+		return "[-" + text + "-]"
+	}
+}
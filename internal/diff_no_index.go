@@ -0,0 +1,425 @@
+// File: diff_no_index.go
+// Package: internal
+
+// Program Description:
+// This file compares two paths on disk, for "jit diff --no-index".
+// Two files are compared directly. Two directories are walked and their files paired by relative
+// path, so a file present on one side only is shown as added or deleted; a file compared with a
+// directory is compared with the file of the same name inside it. Files are read the way they would
+// be recorded: symbolic links by their target, and the mode from the executable bit.
+// With -M, deleted and added files are paired into renames by content similarity; with -C, added
+// files are also matched against the changed and deleted files they may have been copied from.
+// --word-diff and --color-words show changed lines word by word instead of as whole lines, and -w,
+// -b and --ignore-blank-lines leave out whitespace changes; a file with nothing else is not shown.
+// --stat, --numstat and --shortstat print the changed line counts instead of the patch.
+
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"jit/internal/diff"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DevNull is the name printed for the missing side of an added or deleted file.
+const DevNull = "/dev/null"
+
+// DiffOptions controls how file pairs are compared and shown.
+type DiffOptions struct {
+	Algorithm  diff.Algorithm         // The line diff algorithm, from --diff-algorithm or diff.algorithm.
+	Context    int                    // The number of context lines around each change, from -U.
+	WordDiff   diff.WordDiffMode      // How to mark changed words, from --word-diff; empty for a line diff.
+	Whitespace diff.WhitespaceOptions // The whitespace differences to ignore, from -w, -b and --ignore-blank-lines.
+	Binary     bool                   // Show binary changes as a "GIT binary patch", from --binary.
+
+	FindRenames     bool // Whether to pair deleted and added files into renames, from -M.
+	RenameThreshold int  // The similarity percentage a rename needs.
+	FindCopies      bool // Whether to report added files copied from changed or deleted files, from -C.
+	CopyThreshold   int  // The similarity percentage a copy needs.
+
+	Stat      bool // Show the --stat summary instead of the patch.
+	Numstat   bool // Show the --numstat table instead of the patch.
+	Shortstat bool // Show the --shortstat totals instead of the patch; implied by Stat.
+	// NulTerminated writes the --numstat table with diff.NumstatZ, from -z.
+	NulTerminated bool
+}
+
+// DiffFile is one side of a compared pair.
+type DiffFile struct {
+	Path    string   // The slash-separated path shown in the diff; empty when the file does not exist.
+	Content []byte   // The content, or the target of a symbolic link.
+	Mode    FileMode // The recorded mode, or 0 when the file does not exist.
+}
+
+// DiffPair is a file as found on the old and the new side.
+type DiffPair struct {
+	Old DiffFile
+	New DiffFile
+
+	Renamed    bool // Whether New is Old moved to another path.
+	Copied     bool // Whether New is a copy of Old, which is still present.
+	Similarity int  // For a rename or copy, how similar the two sides are as a percentage.
+}
+
+// NoIndexPairs pairs the files of two paths for "jit diff --no-index".
+//
+// Args:
+//
+//	ctx (context.Context): Stops the walk of two directories when canceled.
+//	dir (string): The directory relative paths are read from; the paths shown stay as given. Empty
+//	              means the current directory.
+//	oldPath (string): The file or directory shown as the old version.
+//	newPath (string): The file or directory shown as the new version.
+//
+// Returns:
+//
+//	pairs ([]DiffPair): The files of both sides, sorted by relative path. A file present on one side
+//	                    only has a zero DiffFile on the other.
+//	err (error): An error if either path cannot be read.
+//
+// Usage:
+//
+//	pairs, err := NoIndexPairs(ctx, "", "old", "new")
+//	if err != nil {
+//	    log.Fatalln(err)
+//	}
+//	changed, err := WriteDiff(os.Stdout, pairs, DiffOptions{Context: diff.DefaultContext})
+func NoIndexPairs(ctx context.Context, dir string, oldPath string, newPath string) (pairs []DiffPair, err error) {
+	oldInfo, oldErr := Files.Stat(ResolvePath(dir, oldPath))
+	if oldErr != nil {
+		return nil, fmt.Errorf("cannot read %s -> %w", oldPath, oldErr)
+	}
+	newInfo, newErr := Files.Stat(ResolvePath(dir, newPath))
+	if newErr != nil {
+		return nil, fmt.Errorf("cannot read %s -> %w", newPath, newErr)
+	}
+
+	switch {
+	case oldInfo.IsDir() && newInfo.IsDir():
+		return pairDirectories(ctx, dir, oldPath, newPath)
+	case oldInfo.IsDir():
+		oldPath = filepath.Join(oldPath, filepath.Base(newPath))
+	case newInfo.IsDir():
+		newPath = filepath.Join(newPath, filepath.Base(oldPath))
+	}
+	oldFile, readErr := readDiffFile(dir, oldPath)
+	if readErr != nil {
+		return nil, readErr
+	}
+	newFile, readErr := readDiffFile(dir, newPath)
+	if readErr != nil {
+		return nil, readErr
+	}
+	return []DiffPair{{Old: oldFile, New: newFile}}, nil
+}
+
+// pairDirectories walks two directories and pairs their files by relative path.
+func pairDirectories(ctx context.Context, dir string, oldDir string, newDir string) ([]DiffPair, error) {
+	files := map[string]*DiffPair{}
+	for _, side := range []struct {
+		dir string
+		old bool
+	}{{oldDir, true}, {newDir, false}} {
+		entries, walkErr := WalkWorkTree(ctx, ResolvePath(dir, side.dir), WalkOptions{})
+		if walkErr != nil {
+			return nil, walkErr
+		}
+		for _, entry := range entries {
+			file, readErr := readDiffFile(dir, filepath.Join(side.dir, filepath.FromSlash(entry.Path)))
+			if readErr != nil {
+				return nil, readErr
+			}
+			if files[entry.Path] == nil {
+				files[entry.Path] = &DiffPair{}
+			}
+			if side.old {
+				files[entry.Path].Old = file
+			} else {
+				files[entry.Path].New = file
+			}
+		}
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]DiffPair, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, *files[name])
+	}
+	return pairs, nil
+}
+
+// readDiffFile reads one side of a pair, resolving a relative filePath against dir. A path that does
+// not exist is returned as a zero DiffFile.
+func readDiffFile(dir string, filePath string) (DiffFile, error) {
+	content, mode, readErr := ReadWorkTreeContent(ResolvePath(dir, filePath))
+	if errors.Is(readErr, fs.ErrNotExist) {
+		return DiffFile{}, nil
+	}
+	if readErr != nil {
+		return DiffFile{}, fmt.Errorf("cannot read %s -> %w", filePath, readErr)
+	}
+	return DiffFile{Path: strings.TrimPrefix(filepath.ToSlash(filePath), "/"), Content: content, Mode: mode}, nil
+}
+
+// WriteDiff writes the differences between the two sides of every pair in the git patch format.
+//
+// Args:
+//
+//	out (io.Writer): Where the diff is written.
+//	pairs ([]DiffPair): The files to compare, as returned by NoIndexPairs.
+//	opts (DiffOptions): How to compare the pairs and what to show.
+//
+// Returns:
+//
+//	changed (bool): True if any pair differs in mode, or in content beyond the whitespace
+//	                opts.Whitespace ignores.
+//	err (error): Any error returned while writing, nil otherwise.
+//
+// Each pair that differs is shown as:
+//  1. A "diff --git a/<old> b/<new>" line.
+//  2. "new file mode", "deleted file mode", or "old mode" and "new mode" lines when the mode changed.
+//  3. "similarity index", then "rename from" and "rename to" (or "copy from" and "copy to") lines
+//     for a rename or copy.
+//  4. "Binary files ... differ" for binary content, or its "GIT binary patch" when opts.Binary is
+//     set, or the unified diff of the lines, or their word diff when opts.WordDiff is set.
+//
+// A change between a symbolic link and a regular file is shown as a deletion followed by an
+// addition, as the two cannot be compared line by line.
+//
+// With opts.Numstat, opts.Stat or opts.Shortstat the patches are replaced by diff.Numstat (or
+// diff.NumstatZ with opts.NulTerminated), diff.Stat and diff.Shortstat, in that order; Stat
+// already ends with the Shortstat line.
+func WriteDiff(out io.Writer, pairs []DiffPair, opts DiffOptions) (changed bool, err error) {
+	var files []DiffPair
+	for _, pair := range DetectPairRenames(pairs, opts) {
+		if IsTypeChange(pair.Old.Mode, pair.New.Mode) {
+			files = append(files, DiffPair{Old: pair.Old}, DiffPair{New: pair.New})
+		} else {
+			files = append(files, pair)
+		}
+	}
+
+	summary := opts.Stat || opts.Numstat || opts.Shortstat
+	var stats []diff.FileStat
+	for _, file := range files {
+		patch, stat, formatErr := formatFilePatch(file, opts)
+		if formatErr != nil {
+			return changed, formatErr
+		}
+		if patch == "" {
+			continue
+		}
+		changed = true
+		if summary {
+			stats = append(stats, stat)
+			continue
+		}
+		if _, writeErr := io.WriteString(out, patch); writeErr != nil {
+			return changed, writeErr
+		}
+	}
+	if !summary {
+		return changed, nil
+	}
+
+	var sb strings.Builder
+	if opts.Numstat && opts.NulTerminated {
+		sb.WriteString(diff.NumstatZ(stats))
+	} else if opts.Numstat {
+		sb.WriteString(diff.Numstat(stats))
+	}
+	if opts.Stat {
+		sb.WriteString(diff.Stat(stats, diff.DefaultStatWidth))
+	} else if opts.Shortstat {
+		sb.WriteString(diff.Shortstat(stats))
+	}
+	_, err = io.WriteString(out, sb.String())
+	return changed, err
+}
+
+// DetectPairRenames pairs the deleted and added files of pairs into renames and copies, as selected
+// by opts.FindRenames and opts.FindCopies.
+//
+// Args:
+//
+//	pairs ([]DiffPair): The files to compare, as returned by NoIndexPairs.
+//	opts (DiffOptions): Which detection to run, and the thresholds to use.
+//
+// Returns:
+//
+//	detected ([]DiffPair): The pairs, with every rename taking the place of its added file and
+//	                       dropping its deleted one, and every copy taking the place of its added
+//	                       file. pairs is returned unchanged when no detection is selected.
+//
+// Copy detection implies rename detection, so a moved file is reported as a rename rather than a
+// copy; the sources of copies are the files changed or deleted by pairs, except those renamed.
+func DetectPairRenames(pairs []DiffPair, opts DiffOptions) (detected []DiffPair) {
+	if !opts.FindRenames && !opts.FindCopies {
+		return pairs
+	}
+	renameThreshold := opts.RenameThreshold
+	if !opts.FindRenames {
+		renameThreshold = diff.DefaultRenameThreshold
+	}
+
+	deleted, added, sources := map[string]string{}, map[string]string{}, map[string]string{}
+	oldFiles, newFiles := map[string]DiffFile{}, map[string]DiffFile{}
+	for _, pair := range pairs {
+		switch {
+		case pair.Old.Mode == 0:
+			added[pair.New.Path] = string(pair.New.Content)
+			newFiles[pair.New.Path] = pair.New
+		case pair.New.Mode == 0:
+			deleted[pair.Old.Path] = string(pair.Old.Content)
+			sources[pair.Old.Path] = string(pair.Old.Content)
+			oldFiles[pair.Old.Path] = pair.Old
+		case pair.Old.Mode != pair.New.Mode || string(pair.Old.Content) != string(pair.New.Content):
+			sources[pair.Old.Path] = string(pair.Old.Content)
+			oldFiles[pair.Old.Path] = pair.Old
+		}
+	}
+
+	replaced := map[string]DiffPair{}
+	renamedFrom := map[string]bool{}
+	renames, _, remainingAdded := diff.DetectRenames(deleted, added, renameThreshold)
+	for _, rename := range renames {
+		replaced[rename.NewPath] = DiffPair{Old: oldFiles[rename.OldPath], New: newFiles[rename.NewPath], Renamed: true, Similarity: rename.Similarity}
+		renamedFrom[rename.OldPath] = true
+		delete(sources, rename.OldPath)
+	}
+	if opts.FindCopies {
+		remaining := map[string]string{}
+		for _, addedPath := range remainingAdded {
+			remaining[addedPath] = added[addedPath]
+		}
+		copies, _ := diff.DetectCopies(sources, remaining, opts.CopyThreshold)
+		for _, copied := range copies {
+			replaced[copied.NewPath] = DiffPair{Old: oldFiles[copied.OldPath], New: newFiles[copied.NewPath], Copied: true, Similarity: copied.Similarity}
+		}
+	}
+
+	detected = make([]DiffPair, 0, len(pairs))
+	for _, pair := range pairs {
+		if pair.Old.Mode == 0 {
+			if found, ok := replaced[pair.New.Path]; ok {
+				pair = found
+			}
+		} else if pair.New.Mode == 0 && renamedFrom[pair.Old.Path] {
+			continue
+		}
+		detected = append(detected, pair)
+	}
+	return detected
+}
+
+// formatFilePatch renders the patch of one pair, or an empty string if both sides are the same,
+// with the counts of its changed lines.
+func formatFilePatch(pair DiffPair, opts DiffOptions) (string, diff.FileStat, error) {
+	oldFile, newFile := pair.Old, pair.New
+	stat := diff.FileStat{Path: newFile.Path}
+	switch {
+	case newFile.Mode == 0:
+		stat.Path = oldFile.Path
+	case oldFile.Mode != 0 && oldFile.Path != newFile.Path:
+		stat.Path = statRenamePath(oldFile.Path, newFile.Path)
+		stat.OldPath, stat.NewPath = oldFile.Path, newFile.Path
+	}
+	if !pair.Renamed && !pair.Copied && oldFile.Mode == newFile.Mode && string(oldFile.Content) == string(newFile.Content) {
+		return "", stat, nil
+	}
+
+	oldName, newName := DevNull, DevNull
+	headerOld, headerNew := newFile.Path, newFile.Path
+	if oldFile.Mode != 0 {
+		oldName = "a/" + oldFile.Path
+		headerOld = oldFile.Path
+		if newFile.Mode == 0 {
+			headerNew = oldFile.Path
+		}
+	}
+	if newFile.Mode != 0 {
+		newName = "b/" + newFile.Path
+	}
+
+	var sb strings.Builder
+	sb.WriteString("diff --git " + path.Join("a", headerOld) + " " + path.Join("b", headerNew) + "\n")
+	switch {
+	case oldFile.Mode == 0:
+		sb.WriteString("new file mode " + newFile.Mode.String() + "\n")
+	case newFile.Mode == 0:
+		sb.WriteString("deleted file mode " + oldFile.Mode.String() + "\n")
+	case oldFile.Mode != newFile.Mode:
+		sb.WriteString("old mode " + oldFile.Mode.String() + "\n")
+		sb.WriteString("new mode " + newFile.Mode.String() + "\n")
+	}
+	if pair.Renamed || pair.Copied {
+		relation := "rename"
+		if pair.Copied {
+			relation = "copy"
+		}
+		sb.WriteString(fmt.Sprintf("similarity index %d%%\n", pair.Similarity))
+		sb.WriteString(relation + " from " + oldFile.Path + "\n")
+		sb.WriteString(relation + " to " + newFile.Path + "\n")
+	}
+
+	if string(oldFile.Content) == string(newFile.Content) {
+		return sb.String(), stat, nil
+	}
+	if diff.IsBinary(oldFile.Content) || diff.IsBinary(newFile.Content) {
+		stat.Binary, stat.OldSize, stat.NewSize = true, len(oldFile.Content), len(newFile.Content)
+		if !opts.Binary {
+			sb.WriteString(diff.BinarySummary(oldName, newName))
+			return sb.String(), stat, nil
+		}
+		patch, patchErr := diff.BinaryPatch(oldFile.Content, newFile.Content)
+		if patchErr != nil {
+			return "", stat, fmt.Errorf("failed to encode the binary patch of %s -> %w", stat.Path, patchErr)
+		}
+		sb.WriteString(patch)
+		return sb.String(), stat, nil
+	}
+	edits := diff.CompareLines(opts.Algorithm, diff.SplitLines(string(oldFile.Content)), diff.SplitLines(string(newFile.Content)), opts.Whitespace)
+	body := diff.Unified(oldName, newName, edits, opts.Context)
+	if opts.WordDiff != "" {
+		body = diff.WordDiff(oldName, newName, edits, opts.WordDiff, opts.Context)
+	}
+	if body == "" && oldFile.Mode == newFile.Mode && !pair.Renamed && !pair.Copied {
+		// Every change is whitespace the options ignore.
+		return "", stat, nil
+	}
+	stat.Insertions, stat.Deletions = diff.CountChanges(edits)
+	sb.WriteString(body)
+	return sb.String(), stat, nil
+}
+
+// statRenamePath shows a pair whose paths differ as the --stat output does, with the directories the
+// two paths share outside the braces, e.g. "{old => new}/main.go" or "src/{a.go => b.go}".
+func statRenamePath(oldPath string, newPath string) string {
+	prefix := 0
+	for i := 0; i < len(oldPath) && i < len(newPath) && oldPath[i] == newPath[i]; i++ {
+		if oldPath[i] == '/' {
+			prefix = i + 1
+		}
+	}
+	suffix := 0
+	for i := 1; i <= len(oldPath)-prefix && i <= len(newPath)-prefix && oldPath[len(oldPath)-i] == newPath[len(newPath)-i]; i++ {
+		if oldPath[len(oldPath)-i] == '/' {
+			suffix = i
+		}
+	}
+	if prefix == 0 && suffix == 0 {
+		return oldPath + " => " + newPath
+	}
+	return oldPath[:prefix] + "{" + oldPath[prefix:len(oldPath)-suffix] + " => " + newPath[prefix:len(newPath)-suffix] + "}" + oldPath[len(oldPath)-suffix:]
+}
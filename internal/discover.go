@@ -0,0 +1,199 @@
+// File: discover.go
+// Package: internal
+
+// Program Description:
+// This file locates the repository a command operates on.
+// Starting at a directory, it walks up towards the filesystem root until it finds a .jit directory,
+// or a .jit file created by --separate-jit-dir that holds the path of the repository ("jitdir:
+// <path>"). A file is used rather than a symbolic link because creating links needs extra rights
+// on Windows. --jit-dir and --work-tree, or the JIT_DIR and JIT_WORK_TREE environment variables,
+// override the search.
+
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"jit/pkg/util"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotARepository is returned when no repository is found.
+var ErrNotARepository = errors.New("not a jit repository (or any of the parent directories)")
+
+// ErrInvalidJitFile is returned when a .jit file does not point to a repository.
+var ErrInvalidJitFile = errors.New("invalid .jit file")
+
+// JitFilePrefix starts the content of a .jit file pointing to a separate repository directory.
+const JitFilePrefix = "jitdir: "
+
+// WriteJitFile writes a .jit file pointing to a repository directory. The path is stored with
+// forward slashes so the file reads the same on every platform.
+func WriteJitFile(path string, jitDir string) error {
+	return WriteLocked(path, []byte(JitFilePrefix+filepath.ToSlash(jitDir)+"\n"))
+}
+
+// ReadJitFile returns the repository directory a .jit file points to. A relative path is
+// relative to the directory holding the file.
+//
+// Returns:
+//
+//	jitDir (string): The absolute path of the repository directory.
+//	err (error): ErrInvalidJitFile if the file is not a .jit file or the directory it names does
+//	             not exist, or the error met while reading it.
+func ReadJitFile(path string) (jitDir string, err error) {
+	content, readErr := Files.ReadFile(path)
+	if readErr != nil {
+		return "", readErr
+	}
+	target, ok := strings.CutPrefix(strings.TrimSpace(string(content)), JitFilePrefix)
+	if !ok || target == "" {
+		return "", fmt.Errorf("%w: %s", ErrInvalidJitFile, path)
+	}
+	jitDir = filepath.FromSlash(target)
+	if !filepath.IsAbs(jitDir) {
+		jitDir = filepath.Join(filepath.Dir(path), jitDir)
+	}
+	if info, statErr := Files.Stat(jitDir); statErr != nil || !info.IsDir() {
+		return "", fmt.Errorf("%w: %s points to %s, which is not a directory", ErrInvalidJitFile, path, jitDir)
+	}
+	return jitDir, nil
+}
+
+// FindJitDir finds the .jit directory of the repository containing a directory.
+//
+// Args:
+//
+//	start (string): The directory to start searching from, usually the current working directory.
+//
+// Returns:
+//
+//	jitDir (string): The absolute path of the .jit directory, or of the directory a .jit file
+//	                 points to.
+//	err (error): ErrNotARepository if no directory up to the filesystem root contains a .jit
+//	             directory or file, ErrInvalidJitFile for a .jit file that cannot be followed, or
+//	             the error returned while resolving the start directory.
+//
+// Usage:
+//
+//	cwd, _ := os.Getwd()
+//	jitDir, err := FindJitDir(cwd)
+//	if err != nil {
+//	    log.Fatalln(err)
+//	}
+func FindJitDir(start string) (jitDir string, err error) {
+	jitDir, _, err = FindRepository(start)
+	return jitDir, err
+}
+
+// FindRepository is FindJitDir that also returns the work tree: the directory holding the .jit
+// directory or file.
+func FindRepository(start string) (jitDir string, workTree string, err error) {
+	dir, absErr := filepath.Abs(start)
+	if absErr != nil {
+		return "", "", absErr
+	}
+
+	for {
+		candidate := filepath.Join(dir, util.JitDirName)
+		if info, statErr := Files.Stat(candidate); statErr == nil {
+			if info.IsDir() {
+				return candidate, dir, nil
+			}
+			target, readErr := ReadJitFile(candidate)
+			if readErr != nil {
+				return "", "", readErr
+			}
+			return target, dir, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", fmt.Errorf("%w: %s", ErrNotARepository, start)
+		}
+		dir = parent
+	}
+}
+
+// DiscoverRepository determines the repository and work tree a command operates on, as
+// DiscoverRepositoryWith does with the JIT_DIR and JIT_WORK_TREE environment variables.
+//
+// Usage:
+//
+//	cwd, _ := os.Getwd()
+//	jitDir, workTree, err := DiscoverRepository(cwd)
+//	if err != nil {
+//	    log.Fatalln(err)
+//	}
+func DiscoverRepository(cwd string) (jitDir string, workTree string, err error) {
+	return DiscoverRepositoryWith(cwd, os.Getenv(util.EnvJitDir), os.Getenv(util.EnvWorkTree))
+}
+
+// DiscoverRepositoryWith determines the repository and work tree a command operates on.
+//
+// Args:
+//
+//	cwd (string): The directory the command runs in. Relative paths are resolved against it.
+//	jitDirOverride (string): The repository given with --jit-dir or JIT_DIR, or empty to search.
+//	workTreeOverride (string): The work tree given with --work-tree or JIT_WORK_TREE, or empty.
+//
+// Returns:
+//
+//	jitDir (string): The absolute path of the .jit directory.
+//	workTree (string): The absolute path of the work tree.
+//	err (error): ErrNotARepository if no repository is found, or ErrUnsupportedFormat if the
+//	             repository uses a format this jit does not support (see CheckRepositoryFormat).
+//	             With ErrUnsupportedFormat, jitDir and workTree are still returned, so that
+//	             "jit config" can be used to inspect the repository.
+//
+// The function performs the following steps:
+//  1. If jitDirOverride is set, it is the repository and no search takes place.
+//  2. Otherwise the repository is found with FindRepository, starting at cwd.
+//  3. The work tree is workTreeOverride if set; otherwise the directory containing the .jit
+//     directory or file, or cwd when the repository was given explicitly.
+//
+// Usage:
+//
+//	jitDir, workTree, err := DiscoverRepositoryWith(dir, jitDirFlag, workTreeFlag)
+//	if err != nil {
+//	    log.Fatalln(err)
+//	}
+func DiscoverRepositoryWith(cwd string, jitDirOverride string, workTreeOverride string) (jitDir string, workTree string, err error) {
+	if jitDirOverride != "" {
+		jitDir, err = filepath.Abs(ResolvePath(cwd, jitDirOverride))
+		if err != nil {
+			return "", "", err
+		}
+		if info, statErr := Files.Stat(jitDir); statErr != nil || !info.IsDir() {
+			return "", "", fmt.Errorf("%w: %s", ErrNotARepository, jitDirOverride)
+		}
+		workTree = cwd
+	} else {
+		jitDir, workTree, err = FindRepository(cwd)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	if workTreeOverride != "" {
+		workTree = ResolvePath(cwd, workTreeOverride)
+	}
+	workTree, err = filepath.Abs(workTree)
+	if err != nil {
+		return "", "", err
+	}
+	Verbosef("Using repository %s with work tree %s", jitDir, workTree)
+	return jitDir, workTree, CheckRepositoryFormat(jitDir)
+}
+
+// ResolvePath returns a path given to a command running in dir: absolute paths are returned
+// unchanged and relative ones are joined to dir. An empty dir means the current directory of the
+// process.
+func ResolvePath(dir string, path string) string {
+	if dir == "" || path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
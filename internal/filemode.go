@@ -0,0 +1,136 @@
+// File: filemode.go
+// Package: internal
+
+// Program Description:
+// This file handles the file modes recorded for tracked paths.
+// Regular files are recorded as 100644 or 100755 depending on the owner's executable bit, as
+// "jit diff --no-index" shows them. When core.fileMode is false (filesystems that cannot represent
+// the executable bit), the mode on disk is ignored and the previously recorded mode is kept. There
+// is no checkout yet, so modes are never written back to the working tree.
+// Symbolic links are recorded as 120000 entries whose content is the link target; the link itself
+// is read, never the file it points to.
+
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// FileMode is the mode recorded for a path in stage and tree entries.
+type FileMode uint32
+
+const (
+	ModeRegular    FileMode = 0100644
+	ModeExecutable FileMode = 0100755
+	ModeSymlink    FileMode = 0120000
+)
+
+// String returns the mode in the octal form used in stage and tree entries, e.g. "100644".
+func (m FileMode) String() string {
+	return fmt.Sprintf("%06o", uint32(m))
+}
+
+// ParseFileMode parses a mode in the octal form used in stage and tree entries.
+//
+// Args:
+//
+//	mode (string): The octal mode, e.g. "100755".
+//
+// Returns:
+//
+//	fileMode (FileMode): The parsed mode.
+//	err (error): An error if the string is not one of the supported modes.
+func ParseFileMode(mode string) (fileMode FileMode, err error) {
+	value, parseErr := strconv.ParseUint(mode, 8, 32)
+	if parseErr != nil {
+		return 0, fmt.Errorf("invalid file mode -> %s", mode)
+	}
+	fileMode = FileMode(value)
+	switch fileMode {
+	case ModeRegular, ModeExecutable, ModeSymlink:
+		return fileMode, nil
+	default:
+		return 0, fmt.Errorf("unsupported file mode -> %s", mode)
+	}
+}
+
+// FileModeFromInfo determines the mode to record for a working tree file.
+//
+// Args:
+//
+//	info (os.FileInfo): The stat information of the file in the working tree.
+//	trustFileMode (bool): The value of core.fileMode. When false the executable bit on disk is ignored.
+//	recorded (FileMode): The mode currently recorded for the path, or 0 if the path is new.
+//
+// Returns:
+//
+//	FileMode: ModeSymlink for symbolic links, ModeExecutable if the owner executable bit is set,
+//	          otherwise ModeRegular; as in git, a 0654 file is regular. When the file mode is not
+//	          trusted, the recorded mode (or ModeRegular for new paths) is returned for regular files.
+//
+// Usage:
+//
+//	info, _ := os.Lstat(path)
+//	mode := FileModeFromInfo(info, true, 0)
+func FileModeFromInfo(info os.FileInfo, trustFileMode bool, recorded FileMode) FileMode {
+	if info.Mode()&os.ModeSymlink != 0 {
+		return ModeSymlink
+	}
+	if !trustFileMode {
+		if recorded != 0 && recorded != ModeSymlink {
+			return recorded
+		}
+		return ModeRegular
+	}
+	if info.Mode().Perm()&0100 != 0 {
+		return ModeExecutable
+	}
+	return ModeRegular
+}
+
+// IsModeChange reports whether a path changed only in a way status and diff should report as a
+// mode change, i.e. between regular and executable.
+func IsModeChange(recorded FileMode, current FileMode) bool {
+	return recorded != current && recorded != 0 && current != 0 && !IsTypeChange(recorded, current)
+}
+
+// IsTypeChange reports whether a path changed between a symbolic link and a regular file.
+func IsTypeChange(recorded FileMode, current FileMode) bool {
+	return recorded != 0 && current != 0 && (recorded == ModeSymlink) != (current == ModeSymlink)
+}
+
+// ReadWorkTreeContent reads the content to record for a working tree path.
+//
+// Args:
+//
+//	filePath (string): The path of the file or symbolic link in the working tree.
+//
+// Returns:
+//
+//	content ([]byte): The file content, or the slash-separated link target for symbolic links.
+//	mode (FileMode): ModeSymlink for symbolic links, otherwise the mode derived from the executable bit.
+//	err (error): Any error returned while reading the path, nil otherwise.
+//
+// Note:
+//   - The link itself is recorded, never the file it points to, so dangling links are fine.
+func ReadWorkTreeContent(filePath string) (content []byte, mode FileMode, err error) {
+	info, statErr := Files.Lstat(filePath)
+	if statErr != nil {
+		return nil, 0, statErr
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, linkErr := Files.Readlink(filePath)
+		if linkErr != nil {
+			return nil, 0, linkErr
+		}
+		return []byte(filepath.ToSlash(target)), ModeSymlink, nil
+	}
+	content, err = Files.ReadFile(filePath)
+	if err != nil {
+		return nil, 0, err
+	}
+	return content, FileModeFromInfo(info, true, 0), nil
+}
@@ -0,0 +1,423 @@
+// File: filesystem.go
+// Package: internal
+
+// Program Description:
+// This file defines the filesystem that repository and work tree I/O goes through.
+// Reading and writing the jit directory, config files, ignore files, templates and work tree files
+// all use the Files variable instead of the os package, so tests can substitute the in-memory
+// MemFileSystem and other storage can be plugged in. Hooks are looked up through Files but run
+// from disk, as any external program has to be.
+
+package internal
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FileSystem is the set of file operations jit performs on repositories and work trees.
+// The methods behave like the functions of the same name in the os package, including the
+// *fs.PathError values they return.
+type FileSystem interface {
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+	// WriteFileSync is WriteFile followed by flushing the file to stable storage, like fsync.
+	WriteFileSync(name string, data []byte, perm fs.FileMode) error
+	// CreateExclusive creates a file with the given content, failing with fs.ErrExist if it already
+	// exists, like os.OpenFile with O_CREATE|O_EXCL.
+	CreateExclusive(name string, data []byte, perm fs.FileMode) error
+	Rename(oldname string, newname string) error
+	Stat(name string) (fs.FileInfo, error)
+	Lstat(name string) (fs.FileInfo, error)
+	ReadDir(name string) ([]fs.DirEntry, error)
+	Mkdir(name string, perm fs.FileMode) error
+	MkdirAll(name string, perm fs.FileMode) error
+	Remove(name string) error
+	Readlink(name string) (string, error)
+	Symlink(oldname string, newname string) error
+	Chmod(name string, mode fs.FileMode) error
+}
+
+// Files is the filesystem used for repository and work tree I/O. It is the real filesystem unless
+// replaced, typically by a test:
+//
+//	previous := internal.Files
+//	internal.Files = internal.NewMemFileSystem()
+//	defer func() { internal.Files = previous }()
+//
+// Files is a mutable global shared by the whole process, not a per-repository setting: replacing
+// it, as EnableTrace also does, changes the filesystem of every operation in flight on any
+// goroutine. Replace it only while no jit operation runs, and never from tests that run in
+// parallel with each other.
+var Files FileSystem = OSFileSystem{}
+
+// OSFileSystem is the real filesystem, backed by the os package.
+type OSFileSystem struct{}
+
+func (OSFileSystem) ReadFile(name string) ([]byte, error) { return os.ReadFile(name) }
+func (OSFileSystem) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return os.WriteFile(name, data, perm)
+}
+func (OSFileSystem) WriteFileSync(name string, data []byte, perm fs.FileMode) error {
+	file, openErr := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if openErr != nil {
+		return openErr
+	}
+	_, writeErr := file.Write(data)
+	if writeErr == nil {
+		writeErr = file.Sync()
+	}
+	closeErr := file.Close()
+	if writeErr != nil {
+		return writeErr
+	}
+	return closeErr
+}
+func (OSFileSystem) CreateExclusive(name string, data []byte, perm fs.FileMode) error {
+	file, openErr := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if openErr != nil {
+		return openErr
+	}
+	_, writeErr := file.Write(data)
+	closeErr := file.Close()
+	if writeErr != nil {
+		return writeErr
+	}
+	return closeErr
+}
+func (OSFileSystem) Rename(oldname string, newname string) error  { return renameFile(oldname, newname) }
+func (OSFileSystem) Stat(name string) (fs.FileInfo, error)        { return os.Stat(name) }
+func (OSFileSystem) Lstat(name string) (fs.FileInfo, error)       { return os.Lstat(name) }
+func (OSFileSystem) ReadDir(name string) ([]fs.DirEntry, error)   { return os.ReadDir(name) }
+func (OSFileSystem) Mkdir(name string, perm fs.FileMode) error    { return os.Mkdir(name, perm) }
+func (OSFileSystem) MkdirAll(name string, perm fs.FileMode) error { return os.MkdirAll(name, perm) }
+func (OSFileSystem) Remove(name string) error                     { return os.Remove(name) }
+func (OSFileSystem) Readlink(name string) (string, error)         { return os.Readlink(name) }
+func (OSFileSystem) Symlink(oldname string, newname string) error {
+	return os.Symlink(oldname, newname)
+}
+func (OSFileSystem) Chmod(name string, mode fs.FileMode) error { return os.Chmod(name, mode) }
+
+// maxSymlinkDepth bounds symbolic link resolution in MemFileSystem, like the kernel's ELOOP limit.
+const maxSymlinkDepth = 40
+
+// memNode is a file, directory or symbolic link in a MemFileSystem.
+type memNode struct {
+	mode    fs.FileMode
+	data    []byte
+	target  string
+	modTime time.Time
+}
+
+// MemFileSystem is an in-memory FileSystem. Paths are cleaned with filepath.Clean; relative and
+// absolute paths are distinct. It is safe for concurrent use.
+type MemFileSystem struct {
+	mu    sync.Mutex
+	nodes map[string]*memNode
+}
+
+// NewMemFileSystem creates an in-memory filesystem containing only the root directory.
+func NewMemFileSystem() *MemFileSystem {
+	root := &memNode{mode: fs.ModeDir | 0755, modTime: time.Now()}
+	return &MemFileSystem{nodes: map[string]*memNode{string(filepath.Separator): root, ".": root}}
+}
+
+// memFileInfo describes a memNode for Stat, Lstat and ReadDir.
+type memFileInfo struct {
+	name string
+	node *memNode
+}
+
+func (i memFileInfo) Name() string       { return i.name }
+func (i memFileInfo) Size() int64        { return int64(len(i.node.data)) }
+func (i memFileInfo) Mode() fs.FileMode  { return i.node.mode }
+func (i memFileInfo) ModTime() time.Time { return i.node.modTime }
+func (i memFileInfo) IsDir() bool        { return i.node.mode.IsDir() }
+func (i memFileInfo) Sys() any           { return nil }
+
+// resolve returns the clean path of name with every symbolic link in its directories resolved,
+// and in its last element too when followLast is set.
+func (m *MemFileSystem) resolve(name string, followLast bool) (string, error) {
+	name = filepath.Clean(name)
+	for depth := 0; depth < maxSymlinkDepth; depth++ {
+		resolved, restarted := m.resolveOnce(name, followLast)
+		if !restarted {
+			return resolved, nil
+		}
+		name = resolved
+	}
+	return "", errors.New("too many levels of symbolic links")
+}
+
+// resolveOnce replaces the first symbolic link in name by its target. restarted is false when
+// name contains no link to replace.
+func (m *MemFileSystem) resolveOnce(name string, followLast bool) (resolved string, restarted bool) {
+	// The directories of name from the longest to the shortest, ending at the root or ".".
+	prefixes := []string{name}
+	for dir := name; filepath.Dir(dir) != dir; {
+		dir = filepath.Dir(dir)
+		prefixes = append(prefixes, dir)
+	}
+	for i := len(prefixes) - 1; i >= 0; i-- {
+		current := prefixes[i]
+		node, ok := m.nodes[current]
+		if !ok || node.mode&fs.ModeSymlink == 0 || (i == 0 && !followLast) {
+			continue
+		}
+		target := node.target
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(current), target)
+		}
+		rest, _ := filepath.Rel(current, name)
+		return filepath.Join(target, rest), true
+	}
+	return name, false
+}
+
+// lookup returns the node at name. The caller holds m.mu.
+func (m *MemFileSystem) lookup(op string, name string, followLast bool) (string, *memNode, error) {
+	resolved, resolveErr := m.resolve(name, followLast)
+	if resolveErr != nil {
+		return "", nil, &fs.PathError{Op: op, Path: name, Err: resolveErr}
+	}
+	node, ok := m.nodes[resolved]
+	if !ok {
+		return resolved, nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return resolved, node, nil
+}
+
+// create adds a node at name, whose parent must be an existing directory. The caller holds m.mu.
+func (m *MemFileSystem) create(op string, name string, node *memNode) error {
+	resolved, resolveErr := m.resolve(name, false)
+	if resolveErr != nil {
+		return &fs.PathError{Op: op, Path: name, Err: resolveErr}
+	}
+	if _, exists := m.nodes[resolved]; exists {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrExist}
+	}
+	if parent, ok := m.nodes[filepath.Dir(resolved)]; !ok || !parent.mode.IsDir() {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	node.modTime = time.Now()
+	m.nodes[resolved] = node
+	return nil
+}
+
+func (m *MemFileSystem) ReadFile(name string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, node, lookupErr := m.lookup("open", name, true)
+	if lookupErr != nil {
+		return nil, lookupErr
+	}
+	if node.mode.IsDir() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: errors.New("is a directory")}
+	}
+	return append([]byte(nil), node.data...), nil
+}
+
+func (m *MemFileSystem) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, node, lookupErr := m.lookup("open", name, true)
+	if lookupErr == nil {
+		if node.mode.IsDir() {
+			return &fs.PathError{Op: "open", Path: name, Err: errors.New("is a directory")}
+		}
+		node.data, node.modTime = append([]byte(nil), data...), time.Now()
+		return nil
+	}
+	resolved, resolveErr := m.resolve(name, true)
+	if resolveErr != nil {
+		return &fs.PathError{Op: "open", Path: name, Err: resolveErr}
+	}
+	return m.create("open", resolved, &memNode{mode: perm.Perm(), data: append([]byte(nil), data...)})
+}
+
+// WriteFileSync is WriteFile; memory needs no flushing.
+func (m *MemFileSystem) WriteFileSync(name string, data []byte, perm fs.FileMode) error {
+	return m.WriteFile(name, data, perm)
+}
+
+func (m *MemFileSystem) CreateExclusive(name string, data []byte, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.create("open", name, &memNode{mode: perm.Perm(), data: append([]byte(nil), data...)})
+}
+
+// Rename moves a file or symbolic link, replacing newname if it is not a directory. Directories
+// cannot be renamed.
+func (m *MemFileSystem) Rename(oldname string, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	oldResolved, node, lookupErr := m.lookup("rename", oldname, false)
+	if lookupErr != nil {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: fs.ErrNotExist}
+	}
+	if node.mode.IsDir() {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: errors.New("is a directory")}
+	}
+	newResolved, resolveErr := m.resolve(newname, false)
+	if resolveErr != nil {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: resolveErr}
+	}
+	if existing, ok := m.nodes[newResolved]; ok && existing.mode.IsDir() {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: errors.New("is a directory")}
+	}
+	if parent, ok := m.nodes[filepath.Dir(newResolved)]; !ok || !parent.mode.IsDir() {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: fs.ErrNotExist}
+	}
+	delete(m.nodes, oldResolved)
+	m.nodes[newResolved] = node
+	return nil
+}
+
+func (m *MemFileSystem) Stat(name string) (fs.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	resolved, node, lookupErr := m.lookup("stat", name, true)
+	if lookupErr != nil {
+		return nil, lookupErr
+	}
+	return memFileInfo{name: filepath.Base(resolved), node: node}, nil
+}
+
+func (m *MemFileSystem) Lstat(name string) (fs.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, node, lookupErr := m.lookup("lstat", name, false)
+	if lookupErr != nil {
+		return nil, lookupErr
+	}
+	return memFileInfo{name: filepath.Base(name), node: node}, nil
+}
+
+func (m *MemFileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	resolved, node, lookupErr := m.lookup("open", name, true)
+	if lookupErr != nil {
+		return nil, lookupErr
+	}
+	if !node.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdirent", Path: name, Err: errors.New("not a directory")}
+	}
+	var entries []fs.DirEntry
+	for path, child := range m.nodes {
+		if path != resolved && filepath.Dir(path) == resolved {
+			entries = append(entries, fs.FileInfoToDirEntry(memFileInfo{name: filepath.Base(path), node: child}))
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+func (m *MemFileSystem) Mkdir(name string, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.create("mkdir", name, &memNode{mode: fs.ModeDir | perm.Perm()})
+}
+
+func (m *MemFileSystem) MkdirAll(name string, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	resolved, resolveErr := m.resolve(name, true)
+	if resolveErr != nil {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: resolveErr}
+	}
+	var missing []string
+	for dir := resolved; ; dir = filepath.Dir(dir) {
+		if node, ok := m.nodes[dir]; ok {
+			if !node.mode.IsDir() {
+				return &fs.PathError{Op: "mkdir", Path: dir, Err: errors.New("not a directory")}
+			}
+			break
+		}
+		missing = append(missing, dir)
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	for i := len(missing) - 1; i >= 0; i-- {
+		m.nodes[missing[i]] = &memNode{mode: fs.ModeDir | perm.Perm(), modTime: time.Now()}
+	}
+	return nil
+}
+
+func (m *MemFileSystem) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	resolved, node, lookupErr := m.lookup("remove", name, false)
+	if lookupErr != nil {
+		return lookupErr
+	}
+	if node.mode.IsDir() {
+		for path := range m.nodes {
+			if path != resolved && filepath.Dir(path) == resolved {
+				return &fs.PathError{Op: "remove", Path: name, Err: errors.New("directory not empty")}
+			}
+		}
+	}
+	delete(m.nodes, resolved)
+	return nil
+}
+
+func (m *MemFileSystem) Readlink(name string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, node, lookupErr := m.lookup("readlink", name, false)
+	if lookupErr != nil {
+		return "", lookupErr
+	}
+	if node.mode&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: errors.New("invalid argument")}
+	}
+	return node.target, nil
+}
+
+func (m *MemFileSystem) Symlink(oldname string, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.create("symlink", newname, &memNode{mode: fs.ModeSymlink | 0777, target: oldname})
+}
+
+func (m *MemFileSystem) Chmod(name string, mode fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, node, lookupErr := m.lookup("chmod", name, true)
+	if lookupErr != nil {
+		return lookupErr
+	}
+	node.mode = node.mode.Type() | mode.Perm()
+	return nil
+}
+
+// removeAll removes a path and, if it is a directory, everything it contains, like os.RemoveAll.
+// A missing path is not an error.
+func removeAll(name string) error {
+	info, statErr := Files.Lstat(name)
+	if errors.Is(statErr, fs.ErrNotExist) {
+		return nil
+	}
+	if statErr != nil {
+		return statErr
+	}
+	if info.IsDir() {
+		entries, readErr := Files.ReadDir(name)
+		if readErr != nil {
+			return readErr
+		}
+		for _, entry := range entries {
+			if removeErr := removeAll(filepath.Join(name, entry.Name())); removeErr != nil {
+				return removeErr
+			}
+		}
+	}
+	return Files.Remove(name)
+}
@@ -0,0 +1,97 @@
+// File: format.go
+// Package: internal
+
+// Program Description:
+// This file guards repositories against versions of jit that do not understand them.
+// init records core.repositoryFormatVersion in the repository config: 0 for a plain repository,
+// 1 when the repository relies on an extension, listed in the extensions section (such as
+// extensions.objectFormat for sha256 objects). As in git, a version 1 repository may only be used
+// when every extension it lists is known; otherwise a newer repository would be read wrongly or
+// corrupted by an older jit. Repositories created before the version existed count as version 0.
+
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"jit/pkg/util"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RepositoryFormatVersion is the newest repository format this version of jit supports.
+const RepositoryFormatVersion = 1
+
+// ErrUnsupportedFormat is returned when a repository uses a format or extension this version of
+// jit does not support.
+var ErrUnsupportedFormat = errors.New("unsupported repository format")
+
+// knownExtensions are the extensions this version of jit supports, by lowercased name.
+var knownExtensions = map[string]bool{
+	"objectformat": true,
+}
+
+// repositoryFormatConfig returns the format settings init writes for a new repository.
+func repositoryFormatConfig(objectFormat string) map[string]string {
+	if objectFormat == "" || objectFormat == "sha1" {
+		return map[string]string{"core.repositoryFormatVersion": "0"}
+	}
+	return map[string]string{
+		"core.repositoryFormatVersion": "1",
+		"extensions.objectFormat":      objectFormat,
+	}
+}
+
+// CheckRepositoryFormat verifies that jit can safely operate on a repository.
+//
+// Args:
+//
+//	jitDir (string): The jit directory of the repository.
+//
+// Returns:
+//
+//	err (error): ErrUnsupportedFormat, with what to do about it, if the repository format version
+//	             is newer than RepositoryFormatVersion or a version 1 repository uses an unknown
+//	             extension; the error met reading the config otherwise.
+//
+// Usage:
+//
+//	if err := CheckRepositoryFormat(jitDir); err != nil {
+//	    log.Fatalln(err)
+//	}
+func CheckRepositoryFormat(jitDir string) error {
+	config, loadErr := LoadConfigFile(filepath.Join(jitDir, util.CONFIG), ScopeLocal)
+	if loadErr != nil {
+		return loadErr
+	}
+
+	version := 0
+	if value, ok := config.Get("core.repositoryFormatVersion"); ok {
+		parsed, parseErr := strconv.Atoi(value)
+		if parseErr != nil || parsed < 0 {
+			return fmt.Errorf("%w: invalid core.repositoryFormatVersion %q in %s", ErrUnsupportedFormat, value, jitDir)
+		}
+		version = parsed
+	}
+	if version > RepositoryFormatVersion {
+		return fmt.Errorf("%w: %s has format version %d but this jit supports up to %d; upgrade jit to use it", ErrUnsupportedFormat, jitDir, version, RepositoryFormatVersion)
+	}
+	if version == 0 {
+		return nil
+	}
+
+	var unknown []string
+	for _, entry := range config.Entries() {
+		name, isExtension := strings.CutPrefix(entry.Key, "extensions.")
+		if isExtension && !knownExtensions[name] {
+			unknown = append(unknown, entry.Key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("%w: %s uses %s, which this jit does not support; upgrade jit to use it", ErrUnsupportedFormat, jitDir, strings.Join(unknown, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+// File: fsck.go
+// Package: internal
+
+// Program Description:
+// This file checks that a repository is in a usable state and, when asked, repairs it.
+// It verifies the layout init creates, that the config file parses, that HEAD names an existing
+// branch and that no stale lock file blocks updates. Repairs are limited to what can be done
+// without guessing at content: missing entries are recreated empty, a HEAD written by an earlier
+// version is migrated, a HEAD naming a missing branch is pointed at an existing one and stale
+// locks are removed.
+
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"jit/pkg/util"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FsckProblem is an issue found by CheckRepository.
+type FsckProblem struct {
+	Path     string // The slash-separated path of the entry, relative to the jit directory.
+	Message  string // What is wrong.
+	Repaired bool   // Whether the repair mode fixed it.
+}
+
+func (p FsckProblem) String() string {
+	if p.Repaired {
+		return fmt.Sprintf("%s: %s (repaired)", p.Path, p.Message)
+	}
+	return fmt.Sprintf("%s: %s", p.Path, p.Message)
+}
+
+// CheckRepository checks the integrity of a repository.
+//
+// Args:
+//
+//	jitDir (string): The jit directory of the repository.
+//	repair (bool): Whether to fix the problems that can be fixed safely.
+//
+// Returns:
+//
+//	problems ([]FsckProblem): The problems found, in the order they were checked.
+//	err (error): An error met while checking or repairing, as opposed to a problem found.
+//
+// The function performs the following steps:
+//  1. Checks that every file and directory init creates exists with the right type, recreating
+//     missing ones in repair mode.
+//  2. Checks that the config file parses.
+//  3. Checks HEAD: a legacy absolute path is migrated, and a HEAD naming a missing branch is
+//     switched to init.defaultBranch, to the first existing branch or, when there is none, to a
+//     new init.defaultBranch (main by default).
+//  4. Reports lock files, removing those older than StaleLockAge in repair mode.
+//
+// Usage:
+//
+//	problems, err := CheckRepository(jitDir, true)
+//	if err != nil {
+//	    log.Fatalln(err)
+//	}
+//	for _, problem := range problems {
+//	    fmt.Println(problem)
+//	}
+func CheckRepository(jitDir string, repair bool) (problems []FsckProblem, err error) {
+	checks := []func(string, bool) ([]FsckProblem, error){checkLayout, checkConfig, checkHead, checkLocks}
+	for _, check := range checks {
+		found, checkErr := check(jitDir, repair)
+		problems = append(problems, found...)
+		if checkErr != nil {
+			return problems, checkErr
+		}
+	}
+	return problems, nil
+}
+
+// checkLayout checks the entries of jitFileSystem.
+func checkLayout(jitDir string, repair bool) (problems []FsckProblem, err error) {
+	names := make([]string, 0, len(jitFileSystem))
+	for name := range jitFileSystem {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		wantDir := jitFileSystem[name] == util.Directory
+		info, statErr := Files.Stat(filepath.Join(jitDir, name))
+		switch {
+		case errors.Is(statErr, fs.ErrNotExist):
+			problem := FsckProblem{Path: name, Message: "missing"}
+			if repair {
+				if wantDir {
+					err = mkdirAllShared(filepath.Join(jitDir, name))
+				} else if err = Files.WriteFile(filepath.Join(jitDir, name), nil, util.DefaultFilePerm); err == nil {
+					err = adjustSharedFile(filepath.Join(jitDir, name))
+				}
+				if err != nil {
+					return append(problems, problem), err
+				}
+				problem.Repaired = true
+			}
+			problems = append(problems, problem)
+		case statErr != nil:
+			return problems, statErr
+		case info.IsDir() != wantDir:
+			kind := "a file"
+			if wantDir {
+				kind = "a directory"
+			}
+			problems = append(problems, FsckProblem{Path: name, Message: "should be " + kind})
+		}
+	}
+	return problems, nil
+}
+
+// checkConfig checks that the config file parses.
+func checkConfig(jitDir string, _ bool) ([]FsckProblem, error) {
+	if _, readErr := ReadConfigFile(filepath.Join(jitDir, util.CONFIG), ScopeLocal); readErr != nil {
+		return []FsckProblem{{Path: util.CONFIG, Message: readErr.Error()}}, nil
+	}
+	return nil, nil
+}
+
+// checkHead checks that HEAD names an existing branch.
+func checkHead(jitDir string, repair bool) ([]FsckProblem, error) {
+	branch, legacy, readErr := ReadHead(jitDir)
+	if readErr != nil && !errors.Is(readErr, ErrNoBranch) && !errors.Is(readErr, fs.ErrNotExist) {
+		return nil, readErr
+	}
+
+	var problems []FsckProblem
+	if legacy {
+		problem := FsckProblem{Path: util.HEAD, Message: "holds the absolute path of its branch"}
+		if repair {
+			if _, migrateErr := MigrateHead(jitDir); migrateErr != nil {
+				return append(problems, problem), migrateErr
+			}
+			problem.Repaired = true
+		}
+		problems = append(problems, problem)
+	}
+
+	message := ""
+	if readErr != nil {
+		message = "does not point to a branch"
+	} else if info, statErr := Files.Stat(branchPath(jitDir, branch)); statErr != nil || info.IsDir() {
+		message = fmt.Sprintf("points to branch %s, which does not exist", branch)
+	}
+	if message == "" {
+		return problems, nil
+	}
+
+	problem := FsckProblem{Path: util.HEAD, Message: message}
+	if repair {
+		target, chooseErr := chooseHeadBranch(jitDir)
+		if chooseErr != nil {
+			return append(problems, problem), chooseErr
+		}
+		if _, setupErr := SetUpInitialBranch(jitDir, target); setupErr != nil {
+			return append(problems, problem), setupErr
+		}
+		problem.Message += "; it now points to " + target
+		problem.Repaired = true
+	}
+	return append(problems, problem), nil
+}
+
+// chooseHeadBranch picks the branch a broken HEAD is pointed at: init.defaultBranch if it exists,
+// else the first existing branch, else init.defaultBranch or main, which is then created.
+func chooseHeadBranch(jitDir string) (string, error) {
+	fallback := util.MAIN
+	if config, loadErr := LoadConfigFile(filepath.Join(jitDir, util.CONFIG), ScopeLocal); loadErr == nil {
+		if name, ok := config.Get("init.defaultBranch"); ok && CheckRefName(name) == nil {
+			fallback = name
+		}
+	}
+	if info, statErr := Files.Stat(branchPath(jitDir, fallback)); statErr == nil && !info.IsDir() {
+		return fallback, nil
+	}
+
+	branches, listErr := listBranches(jitDir, "")
+	if listErr != nil {
+		return "", listErr
+	}
+	if len(branches) > 0 {
+		return branches[0], nil
+	}
+	return fallback, nil
+}
+
+// listBranches returns the sorted names of the branches below a directory of the branches
+// directory, skipping lock files.
+func listBranches(jitDir string, dir string) ([]string, error) {
+	entries, readErr := Files.ReadDir(filepath.Join(jitDir, util.BRANCHES, filepath.FromSlash(dir)))
+	if errors.Is(readErr, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name := path.Join(dir, entry.Name())
+		if entry.IsDir() {
+			nested, nestedErr := listBranches(jitDir, name)
+			if nestedErr != nil {
+				return nil, nestedErr
+			}
+			names = append(names, nested...)
+		} else if CheckRefName(name) == nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// branchPath returns the file of a branch.
+func branchPath(jitDir string, branch string) string {
+	return filepath.Join(jitDir, util.BRANCHES, filepath.FromSlash(branch))
+}
+
+// checkLocks reports the lock files of HEAD, config and the branches.
+func checkLocks(jitDir string, repair bool) ([]FsckProblem, error) {
+	var problems []FsckProblem
+	var visit func(dir string) error
+	visit = func(dir string) error {
+		entries, readErr := Files.ReadDir(filepath.Join(jitDir, filepath.FromSlash(dir)))
+		if errors.Is(readErr, fs.ErrNotExist) {
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+		for _, entry := range entries {
+			name := path.Join(dir, entry.Name())
+			if entry.IsDir() {
+				if dir != "" || entry.Name() == util.BRANCHES {
+					if visitErr := visit(name); visitErr != nil {
+						return visitErr
+					}
+				}
+				continue
+			}
+			if !strings.HasSuffix(name, LockSuffix) {
+				continue
+			}
+			info, infoErr := entry.Info()
+			if infoErr != nil {
+				return infoErr
+			}
+			problem := FsckProblem{Path: name, Message: "lock file held since " + info.ModTime().Format(time.RFC3339)}
+			if repair && time.Since(info.ModTime()) >= StaleLockAge {
+				if removeErr := Files.Remove(filepath.Join(jitDir, filepath.FromSlash(name))); removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
+					return removeErr
+				}
+				problem.Message = "stale lock file removed"
+				problem.Repaired = true
+			}
+			problems = append(problems, problem)
+		}
+		return nil
+	}
+	return problems, visit("")
+}
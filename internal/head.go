@@ -0,0 +1,114 @@
+// File: head.go
+// Package: internal
+
+// Program Description:
+// This file reads and writes HEAD, the file naming the current branch.
+// HEAD holds a symbolic reference relative to the jit directory, "ref: branches/<name>", so a
+// repository keeps working after it is moved or copied. Repositories created by earlier versions
+// stored the absolute path of the branch file instead; ReadHead still understands that form and
+// MigrateHead rewrites it, which "jit init" does when it re-initializes a repository.
+
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"jit/pkg/util"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// HeadRefPrefix starts the content of a HEAD that points to a branch.
+const HeadRefPrefix = "ref: "
+
+// ErrNoBranch is returned when HEAD does not name a branch.
+var ErrNoBranch = errors.New("HEAD does not point to a branch")
+
+// WriteHead points HEAD at a branch, under its lock file.
+//
+// Args:
+//
+//	jitDir (string): The jit directory of the repository.
+//	branch (string): The name of the branch, e.g. "main" or "feature/login".
+//
+// Returns:
+//
+//	err (error): ErrInvalidRefName for an invalid branch name, ErrLocked if another process is
+//	             updating HEAD, or any error met while writing.
+//
+// Usage:
+//
+//	if err := WriteHead(jitDir, "main"); err != nil {
+//	    log.Fatalln(err)
+//	}
+func WriteHead(jitDir string, branch string) error {
+	if nameErr := CheckRefName(branch); nameErr != nil {
+		return nameErr
+	}
+	return WriteLocked(filepath.Join(jitDir, util.HEAD), []byte(headRef(branch)))
+}
+
+// headRef returns the content of a HEAD pointing to a branch.
+func headRef(branch string) string {
+	return HeadRefPrefix + path.Join(util.BRANCHES, branch) + "\n"
+}
+
+// ReadHead returns the name of the branch HEAD points to.
+//
+// Args:
+//
+//	jitDir (string): The jit directory of the repository.
+//
+// Returns:
+//
+//	branch (string): The slash-separated name of the branch, e.g. "main" or "feature/login".
+//	legacy (bool): Whether HEAD holds the absolute path written by earlier versions of jit.
+//	err (error): ErrNoBranch if HEAD is empty or does not name a branch, or the error met while
+//	             reading it.
+//
+// Note:
+//   - A legacy HEAD is read relative to the branches directory it names, so it resolves even
+//     when the repository has moved since it was written.
+func ReadHead(jitDir string) (branch string, legacy bool, err error) {
+	content, readErr := Files.ReadFile(filepath.Join(jitDir, util.HEAD))
+	if readErr != nil {
+		return "", false, readErr
+	}
+	head := strings.TrimSpace(string(content))
+
+	if ref, ok := strings.CutPrefix(head, HeadRefPrefix); ok {
+		name, inBranches := strings.CutPrefix(strings.TrimSpace(ref), util.BRANCHES+"/")
+		if !inBranches || CheckRefName(name) != nil {
+			return "", false, fmt.Errorf("%w: %q in %s", ErrNoBranch, head, jitDir)
+		}
+		return name, false, nil
+	}
+
+	// Earlier versions could only create top-level branches, so the name is what follows the last
+	// branches directory in the path.
+	if filepath.IsAbs(head) {
+		marker := string(filepath.Separator) + util.BRANCHES + string(filepath.Separator)
+		if i := strings.LastIndex(head, marker); i >= 0 {
+			name := filepath.ToSlash(head[i+len(marker):])
+			if CheckRefName(name) == nil {
+				return name, true, nil
+			}
+		}
+	}
+	return "", false, fmt.Errorf("%w: %q in %s", ErrNoBranch, head, jitDir)
+}
+
+// MigrateHead rewrites a HEAD holding an absolute branch path into a symbolic reference and
+// reports whether it did. A HEAD that is already symbolic is left alone.
+func MigrateHead(jitDir string) (migrated bool, err error) {
+	branch, legacy, readErr := ReadHead(jitDir)
+	if readErr != nil || !legacy {
+		return false, readErr
+	}
+	if writeErr := WriteHead(jitDir, branch); writeErr != nil {
+		return false, writeErr
+	}
+	Verbosef("Rewrote HEAD of %s as a reference to branch %s", jitDir, branch)
+	return true, nil
+}
@@ -0,0 +1,185 @@
+// File: hooks.go
+// Package: internal
+
+// Program Description:
+// This file runs client-side hooks.
+// A hook is an executable file in the hooks directory of the repository (or core.hooksPath) named
+// after the point in an operation where it runs. It is given the documented arguments, runs at the
+// top of the work tree with JIT_DIR set, and a non-zero exit status from a pre-* or *-msg hook
+// aborts the operation.
+
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"jit/pkg/util"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Hook names and the arguments they are given.
+const (
+	HookPreCommit        = "pre-commit"         // No arguments. Exiting non-zero aborts the commit.
+	HookPrepareCommitMsg = "prepare-commit-msg" // The message file, the message source (message, template, merge, squash or commit) and, for commit, the commit id.
+	HookCommitMsg        = "commit-msg"         // The message file. Exiting non-zero aborts the commit; the hook may edit the file.
+	HookPostCommit       = "post-commit"        // No arguments. The exit status is ignored.
+	HookPrePush          = "pre-push"           // The remote name and url; the refs to push are given on stdin. Exiting non-zero aborts the push.
+	HookPostCheckout     = "post-checkout"      // The previous HEAD, the new HEAD and 1 for a branch checkout or 0 for a file checkout. The exit status is ignored.
+	HookPostMerge        = "post-merge"         // 1 if the merge was a squash merge, 0 otherwise. The exit status is ignored.
+)
+
+// HookError is returned when a hook exits with a non-zero status.
+type HookError struct {
+	Name     string
+	ExitCode int
+}
+
+func (e *HookError) Error() string {
+	return fmt.Sprintf("hook %s failed with exit code %d", e.Name, e.ExitCode)
+}
+
+// Hooks locates and runs the hooks of a repository. Commands and external tools use it so hooks
+// run the same way everywhere.
+type Hooks struct {
+	Dir      string    // The hooks directory: core.hooksPath, or the hooks directory in .jit.
+	JitDir   string    // The .jit directory, exported to hooks as JIT_DIR.
+	WorkTree string    // The directory hooks run in.
+	Output   io.Writer // Where the output of hooks goes; os.Stderr when nil.
+}
+
+// NewHooks returns the hooks of a repository.
+//
+// Args:
+//
+//	jitDir (string): The .jit directory of the repository.
+//	workTree (string): The directory hooks run in; the top of the work tree, or jitDir for a bare
+//	                   repository.
+//	config (*Config): The configuration to read core.hooksPath from; may be nil.
+//
+// Returns:
+//
+//	hooks (*Hooks): The hooks of the repository.
+//
+// Note:
+//   - A relative core.hooksPath is relative to the work tree, and a leading ~/ is expanded to the
+//     home directory, so one hooks directory can be shared by several repositories.
+func NewHooks(jitDir string, workTree string, config *Config) (hooks *Hooks) {
+	dir := filepath.Join(jitDir, util.HOOKS)
+	if config != nil {
+		if hooksPath, ok := config.Get("core.hooksPath"); ok && hooksPath != "" {
+			dir = expandHooksPath(hooksPath, workTree)
+		}
+	}
+	if absJitDir, absErr := filepath.Abs(jitDir); absErr == nil {
+		jitDir = absJitDir
+	}
+	return &Hooks{Dir: dir, JitDir: jitDir, WorkTree: workTree}
+}
+
+func expandHooksPath(path string, workTree string) string {
+	path = expandHome(path)
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(workTree, path)
+}
+
+// expandHome replaces a leading ~/ with the home directory.
+func expandHome(path string) string {
+	if rest, found := strings.CutPrefix(path, "~/"); found {
+		if home, homeErr := os.UserHomeDir(); homeErr == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
+
+// Find returns the path of a hook if it exists and is executable.
+func (h *Hooks) Find(name string) (path string, ok bool) {
+	path = filepath.Join(h.Dir, name)
+	info, statErr := Files.Stat(path)
+	if statErr != nil || info.IsDir() || info.Mode().Perm()&0111 == 0 {
+		return "", false
+	}
+	return path, true
+}
+
+// List returns the names of the executable hooks in the hooks directory, sorted. Sample hooks
+// ending in .sample are skipped.
+func (h *Hooks) List() (names []string, err error) {
+	entries, readErr := Files.ReadDir(h.Dir)
+	if errors.Is(readErr, os.ErrNotExist) {
+		return nil, nil
+	}
+	if readErr != nil {
+		return nil, readErr
+	}
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".sample") {
+			continue
+		}
+		if _, ok := h.Find(entry.Name()); ok {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// Run runs a hook if it exists.
+//
+// Args:
+//
+//	name (string): The hook name, one of the Hook* constants or any other name for custom hooks.
+//	args ([]string): The arguments documented for the hook.
+//	stdin (io.Reader): The hook's standard input, or nil for none.
+//
+// Returns:
+//
+//	ran (bool): True if the hook exists and was run.
+//	err (error): A *HookError if the hook exits with a non-zero status, or an error if it cannot
+//	             be started.
+//
+// Usage:
+//
+//	hooks := NewHooks(jitDir, workTree, config)
+//	if _, err := hooks.Run(HookPreCommit, nil, nil); err != nil {
+//	    log.Fatalln(err)
+//	}
+//
+// Note:
+//   - A hook file without the executable bit is ignored, so hooks can be disabled with chmod -x.
+//   - The hook's standard output goes to standard error, keeping the output of commands clean.
+//   - Callers decide whether a failure aborts the operation; post-* hooks cannot.
+func (h *Hooks) Run(name string, args []string, stdin io.Reader) (ran bool, err error) {
+	path, ok := h.Find(name)
+	if !ok {
+		return false, nil
+	}
+	defer TraceRegion("hook", name)()
+	Verbosef("Running hook %s", path)
+
+	hookCmd := exec.Command(path, args...)
+	hookCmd.Dir = h.WorkTree
+	hookCmd.Env = append(os.Environ(), util.EnvJitDir+"="+h.JitDir)
+	hookCmd.Stdin = stdin
+	output := h.Output
+	if output == nil {
+		output = os.Stderr
+	}
+	hookCmd.Stdout = output
+	hookCmd.Stderr = output
+
+	runErr := hookCmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(runErr, &exitErr) {
+		return true, &HookError{Name: name, ExitCode: exitErr.ExitCode()}
+	}
+	if runErr != nil {
+		return false, fmt.Errorf("cannot run hook %s -> %w", name, runErr)
+	}
+	return true, nil
+}
@@ -0,0 +1,371 @@
+// File: ignore.go
+// Package: internal
+
+// Program Description:
+// This file handles ignore rules for the working tree.
+// Rules are read from .jitignore files in every directory, from info/exclude inside the jit directory
+// and from a global ignore file, using gitignore semantics: globs, "**", negation with "!",
+// directory-only patterns with a trailing "/" and patterns anchored by a "/".
+// Repositories imported from git keep working out of the box: a directory without a .jitignore
+// falls back to its .gitignore unless core.readGitFiles is false.
+
+package internal
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"jit/pkg/util"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// IgnorePattern is a single parsed line of an ignore file.
+type IgnorePattern struct {
+	Pattern  string // The pattern as written, without the negation prefix or trailing slash.
+	Base     string // The slash-separated directory, relative to the work tree, that the pattern is relative to.
+	Negate   bool   // The pattern started with "!" and re-includes matching paths.
+	DirOnly  bool   // The pattern ended with "/" and only matches directories.
+	Anchored bool   // The pattern contains a "/" and is matched against the full relative path.
+	regex    *regexp.Regexp
+}
+
+// IgnoreMatcher decides whether paths in a work tree are ignored. It is safe for concurrent use.
+type IgnoreMatcher struct {
+	workTree     string
+	readGitFiles bool
+	global       []IgnorePattern
+	exclude      []IgnorePattern
+	mu           sync.Mutex // Guards perDir.
+	perDir       map[string][]IgnorePattern
+}
+
+// ParseIgnorePatterns parses the content of an ignore file.
+//
+// Args:
+//
+//	content (string): The text of the ignore file.
+//	base (string): The slash-separated directory of the ignore file relative to the work tree ("" for the root).
+//
+// Returns:
+//
+//	patterns ([]IgnorePattern): The parsed patterns in file order. Blank lines and comments are skipped.
+//
+// Note:
+//   - A leading "\" escapes "#" and "!" so they can start a literal pattern.
+//   - Trailing spaces are removed unless escaped with "\".
+func ParseIgnorePatterns(content string, base string) (patterns []IgnorePattern) {
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if pattern, ok := parseIgnoreLine(line, base); ok {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+func parseIgnoreLine(line string, base string) (IgnorePattern, bool) {
+	line = trimUnescapedTrailingSpaces(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return IgnorePattern{}, false
+	}
+
+	pattern := IgnorePattern{Base: base}
+	if strings.HasPrefix(line, "!") {
+		pattern.Negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		pattern.DirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return IgnorePattern{}, false
+	}
+
+	compilePathPattern(&pattern, line)
+	return pattern, true
+}
+
+// compilePathPattern fills in the anchoring and compiled glob of a pattern whose prefix and
+// suffix markers have already been stripped. It is shared by ignore and attribute files.
+func compilePathPattern(pattern *IgnorePattern, glob string) {
+	if strings.Contains(glob, "/") {
+		pattern.Anchored = true
+		glob = strings.TrimPrefix(glob, "/")
+	}
+	pattern.Pattern = glob
+	pattern.regex = compileIgnoreGlob(glob)
+}
+
+func trimUnescapedTrailingSpaces(line string) string {
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	return strings.ReplaceAll(line, `\ `, " ")
+}
+
+// compileIgnoreGlob translates a gitignore glob into an anchored regular expression.
+func compileIgnoreGlob(glob string) *regexp.Regexp {
+	var sb strings.Builder
+	sb.WriteString("^")
+
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case c == '*' && strings.HasPrefix(glob[i:], "**"):
+			atStart := i == 0 || glob[i-1] == '/'
+			atEnd := i+2 == len(glob) || glob[i+2] == '/'
+			if atStart && atEnd {
+				if i+2 == len(glob) {
+					// Trailing "/**" matches everything inside.
+					sb.WriteString(".*")
+				} else {
+					// Leading "**/" or inner "/**/" matches zero or more directories.
+					sb.WriteString("(?:.*/)?")
+					i++
+				}
+				i++
+				continue
+			}
+			// "**" not delimited by slashes behaves like a regular "*".
+			sb.WriteString("[^/]*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(regexp.QuoteMeta(string(c)))
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	sb.WriteString("$")
+	regex, compileErr := regexp.Compile(sb.String())
+	if compileErr != nil {
+		// Fall back to a literal match for malformed character classes.
+		return regexp.MustCompile("^" + regexp.QuoteMeta(glob) + "$")
+	}
+	return regex
+}
+
+// Matches reports whether the pattern matches the given path.
+//
+// Args:
+//
+//	relPath (string): The slash-separated path relative to the work tree.
+//	isDir (bool): Whether the path is a directory.
+//
+// Returns:
+//
+//	bool: true if the pattern matches, regardless of whether it is a negation.
+func (p IgnorePattern) Matches(relPath string, isDir bool) bool {
+	if p.DirOnly && !isDir {
+		return false
+	}
+
+	target := relPath
+	if p.Base != "" {
+		if !strings.HasPrefix(relPath, p.Base+"/") {
+			return false
+		}
+		target = relPath[len(p.Base)+1:]
+	}
+
+	if !p.Anchored {
+		target = path.Base(target)
+	}
+	return p.regex.MatchString(target)
+}
+
+// NewIgnoreMatcher creates an ignore matcher for a work tree.
+//
+// Args:
+//
+//	workTree (string): The root of the working directory.
+//	jitDir (string): The jit directory; rules are read from its info/exclude file. May be empty.
+//	globalFile (string): The global ignore file. May be empty or point to a missing file.
+//	readGitFiles (bool): The value of core.readGitFiles. When true a directory without a .jitignore
+//	                     uses its .gitignore instead.
+//
+// Returns:
+//
+//	matcher (*IgnoreMatcher): The matcher; per-directory .jitignore files are loaded on demand.
+//	err (error): An error if info/exclude or the global file exist but cannot be read.
+//
+// Usage:
+//
+//	matcher, err := NewIgnoreMatcher(workTree, jitDir, DefaultGlobalIgnoreFile(), ReadGitFiles(config))
+//	if err != nil {
+//	    log.Fatalf("Failed to load ignore rules: %s", err)
+//	}
+//	ignored := matcher.IsIgnored("build/output.o", false)
+func NewIgnoreMatcher(workTree string, jitDir string, globalFile string, readGitFiles bool) (matcher *IgnoreMatcher, err error) {
+	matcher = &IgnoreMatcher{workTree: workTree, readGitFiles: readGitFiles, perDir: map[string][]IgnorePattern{}}
+
+	if globalFile != "" {
+		matcher.global, err = readIgnoreFile(globalFile, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if jitDir != "" {
+		matcher.exclude, err = readIgnoreFile(filepath.Join(jitDir, util.INFO, util.EXCLUDE), "")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return matcher, nil
+}
+
+// DefaultGlobalIgnoreFile returns the location of the global ignore file:
+// $XDG_CONFIG_HOME/jit/ignore, falling back to ~/.config/jit/ignore.
+func DefaultGlobalIgnoreFile() string {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "jit", "ignore")
+	}
+	home, homeErr := os.UserHomeDir()
+	if homeErr != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "jit", "ignore")
+}
+
+// ReadGitFiles returns the value of core.readGitFiles, which defaults to true.
+func ReadGitFiles(config *Config) bool {
+	if config == nil {
+		return true
+	}
+	if value, ok := config.Get("core.readGitFiles"); ok {
+		enabled, parseErr := ParseConfigBool(value)
+		return enabled || parseErr != nil
+	}
+	return true
+}
+
+// inTreeFile returns the path of a per-directory file: the jit file if it exists, otherwise the git
+// file when readGitFiles is set. A missing jit file is returned when there is nothing to fall back to.
+func inTreeFile(workTree string, dir string, jitName string, gitName string, readGitFiles bool) string {
+	jitFile := filepath.Join(workTree, filepath.FromSlash(dir), jitName)
+	if !readGitFiles {
+		return jitFile
+	}
+	if _, statErr := Files.Lstat(jitFile); statErr == nil {
+		return jitFile
+	}
+	return filepath.Join(workTree, filepath.FromSlash(dir), gitName)
+}
+
+func readIgnoreFile(file string, base string) ([]IgnorePattern, error) {
+	data, readErr := Files.ReadFile(file)
+	if readErr != nil {
+		if errors.Is(readErr, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, readErr
+	}
+	return ParseIgnorePatterns(string(data), base), nil
+}
+
+// IsIgnored reports whether a path in the work tree is ignored.
+//
+// Args:
+//
+//	relPath (string): The path relative to the work tree, using either separator.
+//	isDir (bool): Whether the path is a directory.
+//
+// Returns:
+//
+//	bool: true if the path, or any of its parent directories, is ignored.
+//
+// The function performs the following steps:
+//  1. Checks every parent directory first; a file inside an ignored directory cannot be re-included.
+//  2. Consults the .jitignore files from the deepest directory up to the root, then info/exclude,
+//     then the global file. The first source with a matching pattern decides, and within a source
+//     the last matching pattern wins.
+func (m *IgnoreMatcher) IsIgnored(relPath string, isDir bool) bool {
+	relPath = strings.Trim(filepath.ToSlash(relPath), "/")
+	if relPath == "" || relPath == "." {
+		return false
+	}
+
+	parts := strings.Split(relPath, "/")
+	for i := 1; i < len(parts); i++ {
+		if m.matchPath(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return m.matchPath(relPath, isDir)
+}
+
+func (m *IgnoreMatcher) matchPath(relPath string, isDir bool) bool {
+	dir := path.Dir(relPath)
+	for {
+		if dir == "." {
+			dir = ""
+		}
+		if ignored, decided := lastMatch(m.patternsFor(dir), relPath, isDir); decided {
+			return ignored
+		}
+		if dir == "" {
+			break
+		}
+		dir = path.Dir(dir)
+	}
+
+	if ignored, decided := lastMatch(m.exclude, relPath, isDir); decided {
+		return ignored
+	}
+	ignored, _ := lastMatch(m.global, relPath, isDir)
+	return ignored
+}
+
+func lastMatch(patterns []IgnorePattern, relPath string, isDir bool) (ignored bool, decided bool) {
+	for i := len(patterns) - 1; i >= 0; i-- {
+		if patterns[i].Matches(relPath, isDir) {
+			return !patterns[i].Negate, true
+		}
+	}
+	return false, false
+}
+
+// patternsFor returns the patterns of the .jitignore (or fallback .gitignore) file in dir, loading and
+// caching it on first use.
+func (m *IgnoreMatcher) patternsFor(dir string) []IgnorePattern {
+	m.mu.Lock()
+	patterns, ok := m.perDir[dir]
+	m.mu.Unlock()
+	if ok {
+		return patterns
+	}
+	patterns, _ = readIgnoreFile(inTreeFile(m.workTree, dir, util.JitIgnoreFile, util.GitIgnoreFile, m.readGitFiles), dir)
+	m.mu.Lock()
+	m.perDir[dir] = patterns
+	m.mu.Unlock()
+	return patterns
+}
@@ -15,13 +15,22 @@ package internal
 import (
 	"errors"
 	"fmt"
+	"io/fs"
 	"jit/pkg/util"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 )
 
+// ErrRepositoryExists is returned when the path of the .jit directory is taken by something that is
+// not a directory, so no repository can be created or re-initialized there.
+var ErrRepositoryExists = errors.New("cannot create the repository: the path already exists")
+
+// ErrInvalidOption is returned when an option passed to InitializeJitRepository has the wrong type or value.
+var ErrInvalidOption = errors.New("invalid init option")
+
 var jitFileSystem = map[string]util.File{
 	util.MAIN:      util.DataFile,
 	util.HEAD:      util.DataFile,
@@ -32,6 +41,7 @@ var jitFileSystem = map[string]util.File{
 	util.BRANCHES:  util.Directory,
 	util.SNAPSHOTS: util.Directory,
 	util.OBJECTS:   util.Directory,
+	util.HOOKS:     util.Directory,
 }
 
 // InitializeJitRepository initializes a new JIT repository based on the provided options.
@@ -54,11 +64,25 @@ var jitFileSystem = map[string]util.File{
 //
 // The function performs the following steps:
 // 1. Parses and validates each option from the provided map (quiet mode, bare repository, etc.).
+//    Missing options take their defaults; an option of the wrong type or a permission that is not
+//    octal fails with ErrInvalidOption.
 // 2. Determines the root directory for the repository, handling separate directory scenarios.
-// 3. In the case of a separate directory, creates a symbolic link to it.
+// 3. In the case of a separate directory, writes a .jit file pointing to it (see WriteJitFile).
 // 4. Creates the necessary directory structure and files for the repository.
-// 5. Writes configuration settings to the repository's config file.
-// 6. Sets up the initial branch for the repository.
+// 5. Copies the template directory (--template, JIT_TEMPLATE_DIR or init.templateDir) into it.
+// 6. Writes configuration settings to the repository's config file, including the repository
+//    format version (see CheckRepositoryFormat).
+// 7. Sets up the initial branch for the repository.
+// 8. For a shared repository ("shared" set to group, all or an octal mode), sets the mode of
+//    every directory and file (see ParseSharedRepository).
+//
+// Running it on an existing repository re-initializes it, as git does: missing directories and files
+// are recreated, templates are copied without overwriting anything, config keys the repository
+// lacks are added and a HEAD written by an earlier version is migrated (see MigrateHead). HEAD and
+// the branches are left alone unless "force" is set, in which case HEAD is switched to the
+// initial branch given. Asking for a different object format fails with
+// ErrInvalidOption, and a repository in a format this jit does not support with
+// ErrUnsupportedFormat.
 //
 // Usage:
 //     options := map[string]any{"quiet": true, "bare": false, "separate-jit-dir": "/path/to/dir", "initial-branch": "main"}
@@ -71,48 +95,34 @@ var jitFileSystem = map[string]util.File{
 //     - The function provides extensive logging and error handling to ensure that any issues during
 //       repository setup are clearly communicated and addressed.
 //     - The repository initialization process is flexible, accommodating various configurations and setups.
-//     - In case of any failure during the process, appropriate cleanup is performed to avoid partial setups.
+//     - In case of any failure during the process, appropriate cleanup is performed to avoid partial setups:
+//       the .jit directory or file is removed if this call created it, and in a bare or separate
+//       repository directory every entry this call added is removed, leaving earlier content alone.
+//       The same cleanup runs if jit is interrupted during init.
 
 func InitializeJitRepository(options map[string]any, dir string) (ok bool, err error) {
 
-	quiet, ok := options["quiet"].(bool)
-	if !ok {
-		log.Println("Quiet mode failed")
-	}
-
-	bare, ok := options["bare"].(bool)
-	if !ok {
-		log.Println("Bare option failed")
+	quiet, quietErr := initOption(options, "quiet", false)
+	bare, bareErr := initOption(options, "bare", false)
+	separateJitDir, sepDirErr := initOption(options, "separate-jit-dir", "")
+	template, templateErr := initOption(options, "template", "")
+	objectFormat, formatErr := initOption(options, "object-format", "sha1")
+	initialBranch, branchErr := initOption(options, "initial-branch", "main")
+	directoryPerm, permErr := initOption(options, "perm", "0755")
+	force, forceErr := initOption(options, "force", false)
+	shared, sharedErr := initOption(options, "shared", "")
+	if optionErr := errors.Join(quietErr, bareErr, sepDirErr, templateErr, formatErr, branchErr, permErr, forceErr, sharedErr); optionErr != nil {
+		return false, optionErr
 	}
 
-	separateJitDir, ok := options["separate-jit-dir"].(string)
-	if !ok {
-		log.Println("Separate Jit Dir failed")
-	}
-
-	template, ok := options["template"].(string)
-	if !ok {
-		log.Println("Template option failed")
-	}
-
-	objectFormat, ok := options["object-format"].(string)
-	if !ok {
-		log.Println("Object format option failed")
-	}
-
-	initialBranch, ok := options["initial-branch"].(string)
-	if !ok {
-		log.Println("Initial Branch option failed")
-	}
-
-	directoryPerm, ok := options["perm"].(string)
-	if !ok {
-		log.Println("Permission option failed")
+	sharedMode, sharedModeErr := ParseSharedRepository(shared)
+	if sharedModeErr != nil {
+		return false, fmt.Errorf("%w: %w", ErrInvalidOption, sharedModeErr)
 	}
 
 	filePermission, convertErr := strconv.ParseUint(directoryPerm, 8, 32)
 	if convertErr != nil {
-		filePermission = 0755
+		return false, fmt.Errorf("%w: perm must be an octal permission -> %s", ErrInvalidOption, directoryPerm)
 	}
 
 	var sepDir string
@@ -130,11 +140,51 @@ func InitializeJitRepository(options map[string]any, dir string) (ok bool, err e
 		return false, wkDirErr
 	}
 
+	jitFile := filepath.Join(workingDir, util.JitDirName)
+	if separateJitDir == "" && !bare {
+		// A work tree created with --separate-jit-dir re-initializes the repository its .jit file names.
+		if info, statErr := Files.Lstat(jitFile); statErr == nil && info.Mode().IsRegular() {
+			target, readErr := ReadJitFile(jitFile)
+			if readErr != nil {
+				return false, fmt.Errorf("%w: %w", ErrRepositoryExists, readErr)
+			}
+			separateJitDir, sepDir = target, target
+		}
+	}
+	if sepDir != "" {
+		absDir, absErr := filepath.Abs(sepDir)
+		if absErr != nil {
+			return false, absErr
+		}
+		sepDir = absDir
+	}
+
+	finalJitDir := ConstructFinalJitDir(workingDir, sepDir, bare)
+	reinit := isJitRepository(finalJitDir)
+	if reinit {
+		if unsupportedErr := CheckRepositoryFormat(finalJitDir); unsupportedErr != nil {
+			return false, unsupportedErr
+		}
+	}
+
+	// Remove whatever this call created if a later step fails.
+	rollback := newInitRollback(workingDir, finalJitDir, separateJitDir != "")
+	defer OnInterrupt(rollback.run)()
+	defer func() {
+		if err != nil {
+			rollback.run()
+		}
+	}()
+
 	if separateJitDir != "" {
-		//Create a symbolic link
-		createErr := os.Symlink(sepDir, filepath.Join(workingDir, util.JitDirName))
-		if createErr != nil {
-			return false, errors.New("start the terminal in administrative mode to use separate directory option")
+		//Point the .jit file to the repository, unless it already does
+		if !pointsTo(jitFile, sepDir) {
+			if _, statErr := Files.Lstat(jitFile); statErr == nil {
+				return false, fmt.Errorf("%w: %s", ErrRepositoryExists, jitFile)
+			}
+			if writeErr := WriteJitFile(jitFile, sepDir); writeErr != nil {
+				return false, fmt.Errorf("cannot create %s -> %w", jitFile, writeErr)
+			}
 		}
 
 		if _, createJitDirErr := CreateJitDir(sepDir, true, bare, filePermission); createJitDirErr != nil {
@@ -146,35 +196,199 @@ func InitializeJitRepository(options map[string]any, dir string) (ok bool, err e
 		}
 	}
 
+	Verbosef("Created repository directory %s", finalJitDir)
+
+	//Copy the template directory
+	template = ResolveTemplateDir(template)
+	if template != "" {
+		Verbosef("Copying templates from %s", template)
+		if copyErr := CopyTemplate(template, finalJitDir); copyErr != nil {
+			Warnf("templates not copied from %s -> %s", template, copyErr)
+		}
+	}
+
 	//Write configuration
 	config := map[string]string{
-		"TEMPLATE":       template,
-		"OBJECT-FORMAT":  objectFormat,
-		"INITIAL-BRANCH": initialBranch,
+		"core.objectFormat":  objectFormat,
+		"init.defaultBranch": initialBranch,
+	}
+	if template != "" {
+		config["init.templateDir"] = template
+	}
+	for key, value := range repositoryFormatConfig(objectFormat) {
+		config[key] = value
+	}
+	for key, value := range platformConfig {
+		config[key] = value
+	}
+	if sharedMode != 0 {
+		config["core.sharedRepository"] = shared
 	}
 
-	finalJitDir := ConstructFinalJitDir(workingDir, sepDir, bare)
-
-	if _, writeErr := WriteToConfigFile(config, finalJitDir); writeErr != nil {
-		log.Println(writeErr)
+	_, formatGiven := options["object-format"]
+	_, branchGiven := options["initial-branch"]
+	switchHead := !reinit || (force && branchGiven)
+	if reinit {
+		if configErr := reinitializeConfig(finalJitDir, config, formatGiven); configErr != nil {
+			return false, configErr
+		}
+		if sharedMode, err = reinitializeShared(finalJitDir, options); err != nil {
+			return false, err
+		}
+		if _, migrateErr := MigrateHead(finalJitDir); migrateErr != nil {
+			Warnf("re-init: HEAD left as it is -> %s", migrateErr)
+		}
+		if current, _, _ := ReadHead(finalJitDir); branchGiven && !force && current != initialBranch {
+			Warnf("re-init: ignored --initial-branch=%s; use --force to switch HEAD to it", initialBranch)
+		}
+	} else if _, writeErr := WriteToConfigFile(config, finalJitDir); writeErr != nil {
+		return false, fmt.Errorf("cannot write the repository configuration -> %w", writeErr)
 	}
 
 	//setup initial branch
-	ok, setupErr := SetUpInitialBranch(finalJitDir, initialBranch)
-	if setupErr != nil {
-		log.Printf("encountered an error while creating a jit repository.")
-		return false, setupErr
+	if switchHead {
+		if _, setupErr := SetUpInitialBranch(finalJitDir, initialBranch); setupErr != nil {
+			return false, fmt.Errorf("encountered an error while creating a jit repository -> %w", setupErr)
+		}
+	}
+
+	if sharedMode != 0 {
+		if sharedErr := applySharedPermissions(finalJitDir, sharedMode); sharedErr != nil {
+			return false, fmt.Errorf("cannot set the permissions of a shared repository -> %w", sharedErr)
+		}
 	}
 
 	if !quiet {
-		dirAbs, _ := filepath.Abs(workingDir)
-		log.Printf("Successfully initialized a new jit repository -> %s", filepath.Join(dirAbs, util.JitDirName))
+		if reinit {
+			Infof("Reinitialized existing jit repository -> %s", finalJitDir)
+		} else {
+			dirAbs, _ := filepath.Abs(workingDir)
+			Infof("Successfully initialized a new jit repository -> %s", filepath.Join(dirAbs, util.JitDirName))
+		}
 	}
 
 	return true, nil
 
 }
 
+// initRollback removes what a failed InitializeJitRepository created.
+type initRollback struct {
+	repoDir  string
+	existing map[string]bool // The entries of repoDir before init, or nil if repoDir did not exist.
+	jitFile  string          // The .jit file of a separate repository, if init is about to create it.
+}
+
+// newInitRollback records the state of the repository directory before init touches it.
+func newInitRollback(workingDir string, repoDir string, separate bool) *initRollback {
+	rollback := &initRollback{repoDir: repoDir}
+	if entries, readErr := Files.ReadDir(repoDir); readErr == nil {
+		rollback.existing = map[string]bool{}
+		for _, entry := range entries {
+			rollback.existing[entry.Name()] = true
+		}
+	}
+	if separate {
+		jitFile := filepath.Join(workingDir, util.JitDirName)
+		if _, statErr := Files.Lstat(jitFile); errors.Is(statErr, fs.ErrNotExist) {
+			rollback.jitFile = jitFile
+		}
+	}
+	return rollback
+}
+
+// run removes the .jit file and the repository content created since newInitRollback.
+func (r *initRollback) run() {
+	Verbosef("Removing the partially created repository %s", r.repoDir)
+	if r.jitFile != "" {
+		_ = Files.Remove(r.jitFile)
+	}
+	if r.existing == nil {
+		_ = removeAll(r.repoDir)
+		return
+	}
+	entries, _ := Files.ReadDir(r.repoDir)
+	for _, entry := range entries {
+		if !r.existing[entry.Name()] {
+			_ = removeAll(filepath.Join(r.repoDir, entry.Name()))
+		}
+	}
+}
+
+// pointsTo reports whether a .jit file, or the .jit link created by earlier versions, already
+// names a repository directory.
+func pointsTo(jitFile string, repoDir string) bool {
+	if target, readErr := ReadJitFile(jitFile); readErr == nil {
+		return target == repoDir
+	}
+	target, linkErr := Files.Readlink(jitFile)
+	return linkErr == nil && target == repoDir
+}
+
+// initOption returns an option of InitializeJitRepository, or fallback when it is not given.
+func initOption[T any](options map[string]any, name string, fallback T) (T, error) {
+	raw, present := options[name]
+	if !present {
+		return fallback, nil
+	}
+	value, ok := raw.(T)
+	if !ok {
+		return fallback, fmt.Errorf("%w: %s has type %T", ErrInvalidOption, name, raw)
+	}
+	return value, nil
+}
+
+// reinitializeShared records the --shared option of a re-initialization, which replaces the
+// previous setting, and returns the mode the repository must be given.
+func reinitializeShared(jitDir string, options map[string]any) (fs.FileMode, error) {
+	configPath := filepath.Join(jitDir, util.CONFIG)
+	if shared, given := options["shared"].(string); given {
+		if setErr := SetConfigValue(configPath, "core.sharedRepository", shared, false); setErr != nil {
+			return 0, fmt.Errorf("cannot write the repository configuration -> %w", setErr)
+		}
+		return ParseSharedRepository(shared)
+	}
+	config, loadErr := LoadConfigFile(configPath, ScopeLocal)
+	if loadErr != nil {
+		return 0, loadErr
+	}
+	value, _ := config.Get("core.sharedRepository")
+	return ParseSharedRepository(value)
+}
+
+// isJitRepository reports whether a directory already holds a repository, i.e. has a head file.
+func isJitRepository(dir string) bool {
+	info, statErr := Files.Stat(filepath.Join(dir, util.HEAD))
+	return statErr == nil && info.Mode().IsRegular()
+}
+
+// reinitializeConfig adds the init settings an existing repository lacks, leaving the others as
+// they are. The object format cannot change once objects may have been written with it, so asking
+// for a different one is an error.
+func reinitializeConfig(jitDir string, config map[string]string, formatGiven bool) error {
+	current, loadErr := LoadConfigFile(filepath.Join(jitDir, util.CONFIG), ScopeLocal)
+	if loadErr != nil {
+		return fmt.Errorf("cannot read the repository configuration -> %w", loadErr)
+	}
+	format, hasFormat := current.Get("core.objectFormat")
+	if formatGiven && hasFormat && format != "" && format != config["core.objectFormat"] {
+		return fmt.Errorf("%w: cannot change the object format of an existing repository from %s to %s", ErrInvalidOption, format, config["core.objectFormat"])
+	}
+
+	missing := map[string]string{}
+	for key, value := range config {
+		if _, ok := current.Get(key); !ok && value != "" {
+			missing[key] = value
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if _, writeErr := WriteToConfigFile(missing, jitDir); writeErr != nil {
+		return fmt.Errorf("cannot write the repository configuration -> %w", writeErr)
+	}
+	return nil
+}
+
 // ConstructFinalJitDir constructs the final directory path for the JIT repository.
 //
 // This function determines the final path where the JIT repository should be created or initialized.
@@ -370,7 +584,7 @@ func CheckWritePermission(currentDir string) (err error) {
 //   - It ensures that operations intended for directories are not mistakenly performed on files.
 func ValidateDirPath(currentDir string) (err error) {
 	//check to make sure the given path is a directory
-	info, pathErr := os.Stat(currentDir)
+	info, pathErr := Files.Stat(currentDir)
 	if pathErr != nil {
 		return pathErr
 	}
@@ -404,11 +618,15 @@ func ValidateDirPath(currentDir string) (err error) {
 //
 // The function performs the following steps:
 // 1. If the repository is not bare and not in a separate directory, it creates the root ".jit" directory.
+//   - An existing .jit directory is reused; anything else named .jit fails with ErrRepositoryExists.
+//
 // 2. It then iterates over the jitFileSystem map, creating each file and directory specified therein.
-//   - For each file, it uses os.Create to create the file, immediately closing it afterward.
-//   - For each directory, it uses os.MkdirAll to ensure the directory and all necessary parent directories are created.
+//   - For each missing file, it uses Files.WriteFile to create the empty file, with the permission
+//     of the directories without execute bits.
+//   - For each directory, it uses Files.MkdirAll with filePermission to ensure the directory and all
+//     necessary parent directories are created.
 //
-// 3. If any error occurs during the creation process, it triggers a cleanup and returns an error.
+// 3. The first error stops the creation and is returned, and InitializeJitRepository rolls back.
 //
 // Usage:
 //
@@ -418,40 +636,45 @@ func ValidateDirPath(currentDir string) (err error) {
 //	}
 //
 // Note:
-//   - The function is careful to close all file resources it opens, using deferred Close calls.
-//   - File and directory creation is logged, and any errors encountered halt the process and trigger cleanup.
+//   - Entries are created in name order, so the error returned for a broken setup is always the same.
 //   - The behavior of the function changes based on the `bare` and `sepDir` flags,
 //     accommodating different repository setups.
 func CreateJitDir(wkDir string, sepDir bool, bare bool, filePermission uint64) (ok bool, err error) {
 
 	if sepDir == false && bare == false {
 		//Creat the root ".jit" directory if it's not a bare repo
-		if mkErr := os.Mkdir(filepath.Join(wkDir, util.JitDirName), os.FileMode(filePermission)); mkErr != nil {
-			errMsg := fmt.Sprintf(" %s already contains a jit repository. change the current directory or remove the .jit from current directory.", wkDir)
-			return false, errors.New(errMsg)
+		jitDir := filepath.Join(wkDir, util.JitDirName)
+		if mkErr := Files.Mkdir(jitDir, os.FileMode(filePermission)); mkErr != nil {
+			if !errors.Is(mkErr, fs.ErrExist) {
+				return false, fmt.Errorf("cannot create %s -> %w", jitDir, mkErr)
+			}
+			if info, statErr := Files.Stat(jitDir); statErr != nil || !info.IsDir() {
+				return false, fmt.Errorf("%w in %s: change the current directory or remove the .jit file from it", ErrRepositoryExists, wkDir)
+			}
 		}
 		wkDir = filepath.Join(wkDir, util.JitDirName) // Create repository in .jit directory
 
 	}
 
-	for k, v := range jitFileSystem {
-		if v == util.DataFile {
-			file, createErr := os.Create(filepath.Join(wkDir, k))
-			if createErr != nil {
-				log.Println(createErr)
-				break
-			}
-			// Close the file as soon as you're done
-			if closeErr := file.Close(); closeErr != nil {
-				log.Println(closeErr)
-				break
+	names := make([]string, 0, len(jitFileSystem))
+	for k := range jitFileSystem {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	for _, k := range names {
+		var createErr error
+		switch jitFileSystem[k] {
+		case util.DataFile:
+			// Existing files are kept, so creating the structure again never loses content.
+			if _, statErr := Files.Lstat(filepath.Join(wkDir, k)); errors.Is(statErr, fs.ErrNotExist) {
+				createErr = Files.WriteFile(filepath.Join(wkDir, k), nil, filePermFor(os.FileMode(filePermission)))
 			}
+		case util.Directory:
+			createErr = Files.MkdirAll(filepath.Join(wkDir, k), os.FileMode(filePermission))
 		}
-		if v == util.Directory {
-			if createErr := os.MkdirAll(filepath.Join(wkDir, k), util.DefaultFilePerm); createErr != nil {
-				log.Println(createErr)
-				break
-			}
+		if createErr != nil {
+			return false, fmt.Errorf("cannot create %s -> %w", k, createErr)
 		}
 	}
 
@@ -460,13 +683,13 @@ func CreateJitDir(wkDir string, sepDir bool, bare bool, filePermission uint64) (
 
 // WriteToConfigFile writes configuration key-value pairs to a configuration file in the JIT repository.
 //
-// This function is responsible for appending configuration settings to a config file
+// This function is responsible for storing configuration settings in the config file
 // within the specified JIT repository. It takes a map of configuration key-value pairs (config)
 // and the directory of the JIT repository (jitDir) as arguments.
 //
 // Args:
 //
-//	config (map[string]string): A map containing configuration keys and their corresponding values.
+//	config (map[string]string): A map containing configuration keys ("section.key") and their corresponding values.
 //	jitDir (string): The directory where the JIT repository's config file is located.
 //
 // Returns:
@@ -476,43 +699,41 @@ func CreateJitDir(wkDir string, sepDir bool, bare bool, filePermission uint64) (
 //	             If the write operation is successful, err will be nil.
 //
 // The function performs the following steps:
-//  1. It constructs the full path of the config file within the JIT repository.
-//  2. It opens (or creates if not existent) the config file with append mode and default file permissions.
-//  3. It iterates over each key-value pair in the provided configuration map and writes them to the
-//     config file in the format "key=value\n".
+//  1. It parses the config file, if any.
+//  2. It replaces the value of every key that is already present and adds the remaining keys, in
+//     sorted order, to their section.
+//  3. It writes the config file back, keeping the formatting of untouched lines.
 //
 // Usage:
 //
-//	config := map[string]string{"TEMPLATE": "templatePath", "INITIAL-BRANCH": "main"}
+//	config := map[string]string{"init.templateDir": "templatePath", "init.defaultBranch": "main"}
 //	ok, err := WriteToConfigFile(config, "/path/to/jit/repo")
 //	if err != nil {
 //	    log.Fatalf("Failed to write to config file: %s", err)
 //	}
 //
 // Note:
-//   - The function uses `os.OpenFile` with the `os.O_APPEND|os.O_CREATE` flags, ensuring that
-//     the config file is created if it does not exist, and existing content is not overwritten.
-//   - Proper error handling is implemented to catch and return errors encountered during file
-//     operations.
-//   - The function ensures file resources are properly closed using deferred Close calls.
-//   - Any write errors encountered during the iteration over the configuration map are log but
-//     do not interrupt the process. This behavior could be modified based on requirements.
+//   - Writing a key twice updates it instead of adding a duplicate line, so the file can be
+//     rewritten safely, e.g. when a repository is re-initialized.
 func WriteToConfigFile(config map[string]string, jitDir string) (ok bool, err error) {
 
-	configFile := filepath.Join(jitDir, util.CONFIG)
-	f, openErr := os.OpenFile(configFile, os.O_APPEND|os.O_CREATE, util.DefaultFilePerm)
-	defer func() {
-		_ = f.Close()
-	}()
-	if openErr != nil {
-		return false, openErr
+	keys := make([]string, 0, len(config))
+	for k := range config {
+		keys = append(keys, k)
 	}
+	sort.Strings(keys)
 
-	for k, v := range config {
-		line := fmt.Sprintf("%s=%s\n", k, v)
-		if _, writeErr := f.Write([]byte(line)); writeErr != nil {
-			log.Println(writeErr)
+	configPath := filepath.Join(jitDir, util.CONFIG)
+	editErr := editConfigFile(configPath, func(file *configFile) error {
+		for _, k := range keys {
+			if setErr := file.set(k, config[k], false); setErr != nil {
+				return setErr
+			}
 		}
+		return nil
+	})
+	if editErr != nil {
+		return false, editErr
 	}
 
 	return true, nil
@@ -536,12 +757,11 @@ func WriteToConfigFile(config map[string]string, jitDir string) (ok bool, err er
 //	             If the setup is successful, err will be nil.
 //
 // The function performs the following steps:
-//  1. It constructs the path for the new branch file within the JIT repository.
-//  2. It opens (or creates if not existent) the branch file with append mode and default file permissions.
-//  3. It also constructs the path for the HEAD file within the JIT repository.
-//  4. It opens (or creates if not existent) the HEAD file with read-write mode, creates the file if it does
-//     not exist, and truncates it if it does.
-//  5. It then writes the path of the branch file into the HEAD file, effectively pointing HEAD to the new branch.
+//  1. It checks the branch name with CheckRefName and constructs the path for the new branch file
+//     within the JIT repository.
+//  2. It creates the branch file with default file permissions unless it already exists.
+//  3. It then points HEAD to the new branch with WriteHead, storing a reference relative to the
+//     repository so that it can be moved.
 //
 // Usage:
 //
@@ -551,32 +771,36 @@ func WriteToConfigFile(config map[string]string, jitDir string) (ok bool, err er
 //	}
 //
 // Note:
-//   - The function uses `os.OpenFile` to handle file operations which ensures that the files
-//     are created if they do not exist.
+//   - An invalid name fails with ErrInvalidRefName before anything is written.
+//   - Files are accessed through Files, so an existing branch file is never truncated and HEAD
+//     is created if it does not exist.
+//   - The branch file and HEAD are written under their lock files, failing with ErrLocked while
+//     another process updates them.
 //   - Proper error handling is implemented to catch and return errors encountered during file
 //     operations.
-//   - The function ensures file resources are properly closed using deferred Close calls.
 func SetUpInitialBranch(jitDir string, initialBranch string) (ok bool, err error) {
 
-	branchPath := filepath.Join(jitDir, util.BRANCHES, initialBranch)
-	bf, openBranchErr := os.OpenFile(branchPath, os.O_APPEND|os.O_CREATE, util.DefaultFilePerm)
-	defer func() {
-		_ = bf.Close()
-	}()
-	if openBranchErr != nil {
-		return false, openBranchErr
+	if nameErr := CheckRefName(initialBranch); nameErr != nil {
+		return false, nameErr
 	}
 
-	headPath := filepath.Join(jitDir, util.HEAD)
-	hf, openHeadErr := os.OpenFile(headPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, util.DefaultFilePerm)
-	defer func() {
-		_ = hf.Close()
-	}()
-	if openHeadErr != nil {
-		return false, openHeadErr
+	branchPath := filepath.Join(jitDir, util.BRANCHES, initialBranch)
+	info, statErr := Files.Stat(branchPath)
+	switch {
+	case errors.Is(statErr, fs.ErrNotExist):
+		if mkErr := mkdirAllShared(filepath.Dir(branchPath)); mkErr != nil {
+			return false, mkErr
+		}
+		if createErr := WriteLocked(branchPath, nil); createErr != nil {
+			return false, createErr
+		}
+	case statErr != nil:
+		return false, statErr
+	case info.IsDir():
+		return false, fmt.Errorf("invalid branch name -> %q", initialBranch)
 	}
 
-	if _, writeErr := hf.WriteString(bf.Name()); writeErr != nil {
+	if writeErr := WriteHead(jitDir, initialBranch); writeErr != nil {
 		return false, writeErr
 	}
 
@@ -0,0 +1,112 @@
+// File: interrupt.go
+// Package: internal
+
+// Program Description:
+// This file lets jit clean up when it is interrupted with Ctrl-C or killed with SIGTERM.
+// Operations that leave the repository half-modified while they run register a cleanup with
+// OnInterrupt and remove it once they are done: every lock file is released this way, and a
+// failed init removes the partially created repository. HandleSignals, installed by the jit
+// command, runs the registered cleanups when a signal arrives and then exits with 128 plus the
+// signal number, as a shell reports a process killed by a signal.
+
+package internal
+
+import (
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+)
+
+// interruptCleanups holds the cleanups registered with OnInterrupt.
+var interruptCleanups = struct {
+	mu       sync.Mutex
+	next     int
+	cleanups map[int]func()
+}{cleanups: map[int]func(){}}
+
+// OnInterrupt registers a cleanup to run if jit is interrupted before the returned remove function
+// is called. Cleanups run in the reverse order of their registration, so an operation is undone
+// before the ones it is part of.
+//
+// Usage:
+//
+//	remove := OnInterrupt(func() { _ = Files.Remove(tempPath) })
+//	defer remove()
+func OnInterrupt(cleanup func()) (remove func()) {
+	interruptCleanups.mu.Lock()
+	defer interruptCleanups.mu.Unlock()
+	id := interruptCleanups.next
+	interruptCleanups.next++
+	interruptCleanups.cleanups[id] = cleanup
+	return func() {
+		interruptCleanups.mu.Lock()
+		delete(interruptCleanups.cleanups, id)
+		interruptCleanups.mu.Unlock()
+	}
+}
+
+// RunInterruptCleanups runs and removes every registered cleanup, newest first.
+func RunInterruptCleanups() {
+	interruptCleanups.mu.Lock()
+	ids := make([]int, 0, len(interruptCleanups.cleanups))
+	for id := range interruptCleanups.cleanups {
+		ids = append(ids, id)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(ids)))
+	cleanups := make([]func(), 0, len(ids))
+	for _, id := range ids {
+		cleanups = append(cleanups, interruptCleanups.cleanups[id])
+		delete(interruptCleanups.cleanups, id)
+	}
+	interruptCleanups.mu.Unlock()
+
+	for _, cleanup := range cleanups {
+		cleanup()
+	}
+}
+
+// HandleSignals runs the registered cleanups when jit receives SIGINT or SIGTERM.
+//
+// Args:
+//
+//	exit (func(code int)): Called after the cleanups with 128 plus the signal number, e.g. 130
+//	                       for SIGINT; os.Exit in the jit command.
+//
+// Returns:
+//
+//	stop (func()): Restores the default handling of the signals.
+//
+// Usage:
+//
+//	stop := HandleSignals(os.Exit)
+//	defer stop()
+//
+// Note:
+//   - A cleanup runs while the interrupted operation may still be executing on another goroutine,
+//     so cleanups must tolerate what they remove having already gone.
+func HandleSignals(exit func(code int)) (stop func()) {
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case received := <-signals:
+			Warnf("interrupted by %s, cleaning up", received)
+			RunInterruptCleanups()
+			code := 128
+			if number, ok := received.(syscall.Signal); ok {
+				code += int(number)
+			}
+			exit(code)
+		case <-done:
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
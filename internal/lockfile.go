@@ -0,0 +1,169 @@
+// File: lockfile.go
+// Package: internal
+
+// Program Description:
+// This file serializes updates to repository files between jit processes, as git does.
+// Before rewriting a file such as config, HEAD or a branch, a process creates "<file>.lock"
+// exclusively; a second process trying to update the same file fails instead of interleaving its
+// writes. The new content is written to the lock file, flushed to disk and renamed over the
+// original, so neither a reader nor a crash can leave a half-written file. A lock left behind by a
+// crashed process is removed once it is older than StaleLockAge; locks held when jit is
+// interrupted are released before it exits (see OnInterrupt). Telling a crashed holder from a
+// slow one by the age of its lock is a heuristic: a process that keeps a lock for longer than
+// StaleLockAge, e.g. because it was suspended, can lose it to another process.
+
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"jit/pkg/util"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LockSuffix is appended to the name of a file to form the name of its lock file.
+const LockSuffix = ".lock"
+
+// StaleLockAge is how old a lock file must be before it is assumed to belong to a process that
+// died while holding it. Updates hold their locks for milliseconds.
+var StaleLockAge = 10 * time.Minute
+
+// ErrLocked is returned when a file is locked by another process.
+var ErrLocked = errors.New("unable to create lock file")
+
+// Lockfile is a lock held on a file while it is rewritten.
+type Lockfile struct {
+	path     string
+	lockPath string
+	done     bool
+	release  func() // Removes the interrupt cleanup that deletes the lock file.
+}
+
+// LockFile takes the lock on a file.
+//
+// Args:
+//
+//	path (string): The file to lock. It does not have to exist; its directory must.
+//
+// Returns:
+//
+//	lock (*Lockfile): The lock, to be released with Commit or Rollback.
+//	err (error): ErrLocked if another process holds the lock, or an error if the lock file cannot
+//	             be created.
+//
+// The function performs the following steps:
+//  1. Creates <path>.lock exclusively, recording the process id in it.
+//  2. If the lock file exists but is older than StaleLockAge, removes it and tries once more.
+//  3. Registers the removal of the lock file with OnInterrupt until the lock is released.
+//
+// Usage:
+//
+//	lock, err := LockFile(headPath)
+//	if err != nil {
+//	    log.Fatalln(err)
+//	}
+//	if err := lock.Commit([]byte(branchPath)); err != nil {
+//	    log.Fatalln(err)
+//	}
+func LockFile(path string) (lock *Lockfile, err error) {
+	lockPath := path + LockSuffix
+	owner := []byte(strconv.Itoa(os.Getpid()))
+	createErr := Files.CreateExclusive(lockPath, owner, util.DefaultFilePerm)
+	if errors.Is(createErr, fs.ErrExist) && removeStaleLock(lockPath) {
+		createErr = Files.CreateExclusive(lockPath, owner, util.DefaultFilePerm)
+	}
+	if errors.Is(createErr, fs.ErrExist) {
+		holder := ""
+		if content, readErr := Files.ReadFile(lockPath); readErr == nil && len(content) > 0 {
+			holder = fmt.Sprintf(" held by process %s", strings.TrimSpace(string(content)))
+		}
+		return nil, fmt.Errorf("%w %s%s: another jit process seems to be running; if it crashed, remove the file and try again", ErrLocked, lockPath, holder)
+	}
+	if createErr != nil {
+		return nil, createErr
+	}
+	release := OnInterrupt(func() { _ = Files.Remove(lockPath) })
+	return &Lockfile{path: path, lockPath: lockPath, release: release}, nil
+}
+
+// removeStaleLock removes a lock file older than StaleLockAge and reports whether the lock may be
+// taken again.
+//
+// The lock file is first renamed to a name of its own, which succeeds for only one of several
+// processes finding the same stale lock, and its age is checked again under that name. A lock
+// that turns out to be fresh was created by another process after the stale one was removed; it
+// is put back instead of being deleted from under its holder.
+func removeStaleLock(lockPath string) bool {
+	info, statErr := Files.Stat(lockPath)
+	if statErr != nil || time.Since(info.ModTime()) < StaleLockAge {
+		return false
+	}
+
+	stalePath := fmt.Sprintf("%s.stale-%d-%d", lockPath, os.Getpid(), time.Now().UnixNano())
+	if renameErr := Files.Rename(lockPath, stalePath); renameErr != nil {
+		// Another process removed the lock first.
+		return errors.Is(renameErr, fs.ErrNotExist)
+	}
+	moved, movedErr := Files.Stat(stalePath)
+	if movedErr != nil {
+		return false
+	}
+	if time.Since(moved.ModTime()) < StaleLockAge {
+		if content, readErr := Files.ReadFile(stalePath); readErr == nil {
+			_ = Files.CreateExclusive(lockPath, content, util.DefaultFilePerm)
+		}
+		_ = Files.Remove(stalePath)
+		return false
+	}
+	Warnf("removing stale lock file %s", lockPath)
+	return Files.Remove(stalePath) == nil
+}
+
+// Commit writes the new content of the file and releases the lock by renaming the lock file over
+// the file. The content is flushed to disk before the rename, so after a crash the file has either
+// its old or its new content. In a shared repository the file gets the mode core.sharedRepository
+// asks for.
+func (l *Lockfile) Commit(content []byte) error {
+	if l.done {
+		return fmt.Errorf("lock on %s already released", l.path)
+	}
+	l.done = true
+	defer l.release()
+	if writeErr := Files.WriteFileSync(l.lockPath, content, util.DefaultFilePerm); writeErr != nil {
+		_ = Files.Remove(l.lockPath)
+		return writeErr
+	}
+	if modeErr := adjustSharedFile(l.lockPath); modeErr != nil {
+		_ = Files.Remove(l.lockPath)
+		return modeErr
+	}
+	if renameErr := Files.Rename(l.lockPath, l.path); renameErr != nil {
+		_ = Files.Remove(l.lockPath)
+		return renameErr
+	}
+	return nil
+}
+
+// Rollback releases the lock and leaves the file unchanged. It does nothing after Commit, so it
+// can be deferred.
+func (l *Lockfile) Rollback() error {
+	if l.done {
+		return nil
+	}
+	l.done = true
+	defer l.release()
+	return Files.Remove(l.lockPath)
+}
+
+// WriteLocked replaces the content of a file while holding its lock.
+func WriteLocked(path string, content []byte) error {
+	lock, lockErr := LockFile(path)
+	if lockErr != nil {
+		return lockErr
+	}
+	return lock.Commit(content)
+}
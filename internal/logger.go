@@ -0,0 +1,44 @@
+// File: logger.go
+// Package: internal
+
+// Program Description:
+// This file implements the verbosity contract shared by every command.
+// Messages are written to the standard logger at one of three levels: errors and warnings are
+// always shown, informational messages (such as "Initialized a new jit repository") are hidden by
+// the global -q/--quiet option, and detailed messages about what a command is doing are only shown
+// with --verbose.
+
+package internal
+
+import "log"
+
+// Verbosity is how much a command reports.
+type Verbosity int
+
+const (
+	VerbosityQuiet   Verbosity = iota // Only errors and warnings.
+	VerbosityNormal                   // Errors, warnings and informational messages.
+	VerbosityVerbose                  // Everything, including the details of each step.
+)
+
+// CurrentVerbosity is the verbosity of the running command, set from the global options.
+var CurrentVerbosity = VerbosityNormal
+
+// Warnf logs a warning. Warnings are shown at every verbosity.
+func Warnf(format string, args ...any) {
+	log.Printf("warning: "+format, args...)
+}
+
+// Infof logs an informational message, unless the command is quiet.
+func Infof(format string, args ...any) {
+	if CurrentVerbosity >= VerbosityNormal {
+		log.Printf(format, args...)
+	}
+}
+
+// Verbosef logs a detail of what the command is doing, only when it is verbose.
+func Verbosef(format string, args ...any) {
+	if CurrentVerbosity >= VerbosityVerbose {
+		log.Printf(format, args...)
+	}
+}
@@ -0,0 +1,24 @@
+// File: platform_other.go
+// Package: internal
+
+// Program Description:
+// This file holds the Unix side of what differs on Windows (see platform_windows.go).
+
+//go:build !windows
+
+package internal
+
+import "os"
+
+// unixPermissions reports whether file modes beyond read-only, such as core.sharedRepository
+// needs, can be set.
+const unixPermissions = true
+
+// platformConfig is the configuration init writes on this platform. The defaults of core.fileMode
+// and core.symlinks already hold here.
+var platformConfig = map[string]string{}
+
+// renameFile renames a file.
+func renameFile(oldname string, newname string) error {
+	return os.Rename(oldname, newname)
+}
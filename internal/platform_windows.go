@@ -0,0 +1,53 @@
+// File: platform_windows.go
+// Package: internal
+
+// Program Description:
+// This file holds what differs on Windows.
+// Windows has no executable bit and needs extra rights to create symbolic links, so new
+// repositories record core.fileMode and core.symlinks as false. A file another process has open,
+// typically an antivirus scanner or an indexer, cannot be replaced for a short while, so renames
+// that fail with a sharing violation are retried before giving up. Long paths need no handling
+// here: the os package adds the \\?\ prefix itself.
+
+//go:build windows
+
+package internal
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"time"
+)
+
+// unixPermissions reports whether file modes beyond read-only, such as core.sharedRepository
+// needs, can be set.
+const unixPermissions = false
+
+// platformConfig is the configuration init writes on this platform.
+var platformConfig = map[string]string{
+	"core.fileMode": "false",
+	"core.symlinks": "false",
+}
+
+// errorSharingViolation is ERROR_SHARING_VIOLATION, which the syscall package does not define.
+const errorSharingViolation syscall.Errno = 32
+
+// renameRetries is how many times a rename blocked by another process is retried.
+const renameRetries = 5
+
+// renameFile renames a file, retrying while another process holds the target open.
+func renameFile(oldname string, newname string) error {
+	delay := 10 * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		err := os.Rename(oldname, newname)
+		if err == nil || attempt == renameRetries {
+			return err
+		}
+		if !errors.Is(err, syscall.ERROR_ACCESS_DENIED) && !errors.Is(err, errorSharingViolation) {
+			return err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
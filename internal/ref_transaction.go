@@ -0,0 +1,206 @@
+// File: ref_transaction.go
+// Package: internal
+
+// Program Description:
+// This file updates several refs as one operation, for commands such as fetch, push and branch
+// that move more than one branch at a time.
+// A RefTransaction collects updates, each with the value the ref is expected to have, then locks
+// every ref, checks the expected values and only writes when all of them match. If anything goes
+// wrong the locks are released and no ref changes; should a write fail midway, the refs already
+// written are restored to their previous values.
+
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"jit/pkg/util"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrRefConflict is returned when a ref does not have the value a transaction expects.
+var ErrRefConflict = errors.New("cannot lock ref")
+
+// refUpdate is one change queued in a RefTransaction.
+type refUpdate struct {
+	name      string
+	newTarget string
+	oldTarget string
+	mustExist bool // The ref must exist with oldTarget; otherwise it must not exist.
+	delete    bool
+}
+
+// RefTransaction is a set of ref updates applied all together or not at all.
+type RefTransaction struct {
+	jitDir  string
+	updates []refUpdate
+	done    bool
+}
+
+// NewRefTransaction starts a transaction on the branches of a repository.
+//
+// Usage:
+//
+//	transaction := NewRefTransaction(jitDir)
+//	transaction.Update("main", newCommit, oldCommit)
+//	transaction.Create("release", newCommit)
+//	if err := transaction.Commit(); err != nil {
+//	    log.Fatalln(err)
+//	}
+func NewRefTransaction(jitDir string) *RefTransaction {
+	return &RefTransaction{jitDir: jitDir}
+}
+
+// Create queues the creation of a ref, which must not exist yet.
+func (t *RefTransaction) Create(name string, target string) {
+	t.updates = append(t.updates, refUpdate{name: name, newTarget: target})
+}
+
+// Update queues moving a ref from oldTarget to newTarget. The ref must exist and point to oldTarget.
+func (t *RefTransaction) Update(name string, newTarget string, oldTarget string) {
+	t.updates = append(t.updates, refUpdate{name: name, newTarget: newTarget, oldTarget: oldTarget, mustExist: true})
+}
+
+// Delete queues the removal of a ref, which must exist and point to oldTarget.
+func (t *RefTransaction) Delete(name string, oldTarget string) {
+	t.updates = append(t.updates, refUpdate{name: name, oldTarget: oldTarget, mustExist: true, delete: true})
+}
+
+// lockedRef is a ref locked during Commit with the value it had.
+type lockedRef struct {
+	update  refUpdate
+	path    string
+	lock    *Lockfile
+	existed bool
+	old     []byte
+}
+
+// Commit applies the queued updates.
+//
+// Returns:
+//
+//	err (error): ErrRefConflict if a ref does not have its expected value, ErrLocked if another
+//	             process holds one of the refs, or any error met while writing. No ref is changed
+//	             when an error is returned.
+//
+// The function performs the following steps:
+//  1. Rejects a transaction with an invalid ref name or that changes the same ref twice.
+//  2. Locks every ref in name order, so concurrent transactions cannot deadlock.
+//  3. Checks each ref against its expected value.
+//  4. Writes or deletes every ref, restoring the ones already written if one fails.
+func (t *RefTransaction) Commit() (err error) {
+	if t.done {
+		return errors.New("ref transaction already finished")
+	}
+	t.done = true
+
+	updates := append([]refUpdate(nil), t.updates...)
+	sort.SliceStable(updates, func(i, j int) bool { return updates[i].name < updates[j].name })
+	for i, update := range updates {
+		if nameErr := CheckRefName(update.name); nameErr != nil {
+			return nameErr
+		}
+		if i > 0 && updates[i-1].name == update.name {
+			return fmt.Errorf("multiple updates for ref %s not allowed", update.name)
+		}
+	}
+
+	locked := make([]*lockedRef, 0, len(updates))
+	defer func() {
+		for _, ref := range locked {
+			_ = ref.lock.Rollback()
+		}
+	}()
+
+	for _, update := range updates {
+		ref, lockErr := t.lockRef(update)
+		if lockErr != nil {
+			return lockErr
+		}
+		locked = append(locked, ref)
+	}
+
+	for _, ref := range locked {
+		if checkErr := ref.check(); checkErr != nil {
+			return checkErr
+		}
+	}
+
+	for i, ref := range locked {
+		if applyErr := ref.apply(); applyErr != nil {
+			for _, written := range locked[:i] {
+				written.restore()
+			}
+			return applyErr
+		}
+	}
+	return nil
+}
+
+// lockRef locks a ref and reads its current value.
+func (t *RefTransaction) lockRef(update refUpdate) (*lockedRef, error) {
+	path := filepath.Join(t.jitDir, util.BRANCHES, filepath.FromSlash(update.name))
+	if !update.delete {
+		if mkErr := mkdirAllShared(filepath.Dir(path)); mkErr != nil {
+			return nil, mkErr
+		}
+	}
+	lock, lockErr := LockFile(path)
+	if errors.Is(lockErr, fs.ErrNotExist) && update.delete {
+		return nil, fmt.Errorf("%w %s: it does not exist", ErrRefConflict, update.name)
+	}
+	if lockErr != nil {
+		return nil, lockErr
+	}
+
+	ref := &lockedRef{update: update, path: path, lock: lock}
+	content, readErr := Files.ReadFile(path)
+	switch {
+	case readErr == nil:
+		ref.existed, ref.old = true, content
+	case !errors.Is(readErr, fs.ErrNotExist):
+		_ = lock.Rollback()
+		return nil, readErr
+	}
+	return ref, nil
+}
+
+// check verifies that a locked ref has the value its update expects.
+func (r *lockedRef) check() error {
+	if !r.update.mustExist {
+		if r.existed {
+			return fmt.Errorf("%w %s: it already exists", ErrRefConflict, r.update.name)
+		}
+		return nil
+	}
+	if !r.existed {
+		return fmt.Errorf("%w %s: it does not exist", ErrRefConflict, r.update.name)
+	}
+	if current := strings.TrimSpace(string(r.old)); current != r.update.oldTarget {
+		return fmt.Errorf("%w %s: expected %q but it is %q", ErrRefConflict, r.update.name, r.update.oldTarget, current)
+	}
+	return nil
+}
+
+// apply writes or deletes a locked ref, releasing its lock.
+func (r *lockedRef) apply() error {
+	if !r.update.delete {
+		return r.lock.Commit([]byte(r.update.newTarget))
+	}
+	if removeErr := Files.Remove(r.path); removeErr != nil {
+		return removeErr
+	}
+	return r.lock.Rollback()
+}
+
+// restore puts back the value a ref had before apply, as well as it can.
+func (r *lockedRef) restore() {
+	if r.existed {
+		_ = WriteLocked(r.path, r.old)
+		return
+	}
+	_ = Files.Remove(r.path)
+}
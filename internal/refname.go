@@ -0,0 +1,105 @@
+// File: refname.go
+// Package: internal
+
+// Program Description:
+// This file validates the names of branches and other refs before they are used as file paths.
+// A ref is stored as a file under the branches directory, so a name such as "../config" or
+// "/etc/passwd" would write outside it, and names with spaces, control characters or the
+// characters revision syntax uses could never be typed back. The rules follow git's
+// check-ref-format, plus the device names Windows reserves (CON, NUL, COM1...), which are refused
+// on every platform so a repository can always be cloned on Windows.
+
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidRefName is returned when a branch or ref name is not valid.
+var ErrInvalidRefName = errors.New("invalid ref name")
+
+// CheckRefName reports whether a name can be used for a branch or ref.
+//
+// Args:
+//
+//	name (string): The name of the ref, relative to the branches directory, e.g. "main" or
+//	               "feature/login".
+//
+// Returns:
+//
+//	err (error): ErrInvalidRefName, with the reason, if the name is not valid; nil otherwise.
+//
+// A valid name:
+//   - is not empty, is not "@" and does not start with "-";
+//   - has no space, control character, "~", "^", ":", "?", "*", "[" or "\";
+//   - has no "..", "//" or "@{" and does not start or end with "/" or end with ".";
+//   - has no component that starts with "." or ends with ".lock";
+//   - has no component that is a device name reserved by Windows, with or without an extension.
+//
+// Usage:
+//
+//	if err := CheckRefName(branch); err != nil {
+//	    log.Fatalln(err)
+//	}
+func CheckRefName(name string) error {
+	invalid := func(reason string) error {
+		return fmt.Errorf("%w %q: %s", ErrInvalidRefName, name, reason)
+	}
+
+	switch {
+	case name == "":
+		return invalid("it is empty")
+	case name == "@":
+		return invalid("it is a revision shorthand")
+	case strings.HasPrefix(name, "-"):
+		return invalid("it starts with '-'")
+	case strings.HasPrefix(name, "/") || strings.HasSuffix(name, "/"):
+		return invalid("it starts or ends with '/'")
+	case strings.HasSuffix(name, "."):
+		return invalid("it ends with '.'")
+	case strings.Contains(name, ".."):
+		return invalid("it contains '..'")
+	case strings.Contains(name, "//"):
+		return invalid("it contains '//'")
+	case strings.Contains(name, "@{"):
+		return invalid("it contains '@{'")
+	}
+
+	for _, c := range name {
+		if c < 0x20 || c == 0x7f {
+			return invalid("it contains a control character")
+		}
+		if strings.ContainsRune(" ~^:?*[\\", c) {
+			return invalid(fmt.Sprintf("it contains %q", c))
+		}
+	}
+
+	for _, component := range strings.Split(name, "/") {
+		if strings.HasPrefix(component, ".") {
+			return invalid("a component starts with '.'")
+		}
+		if strings.HasSuffix(component, LockSuffix) {
+			return invalid("a component ends with " + LockSuffix)
+		}
+		if isReservedFileName(component) {
+			return invalid(fmt.Sprintf("%s is a reserved file name on Windows", component))
+		}
+	}
+	return nil
+}
+
+// isReservedFileName reports whether Windows reserves a file name for a device. The name is
+// reserved whatever its case and extension: "nul" and "Aux.txt" both are.
+func isReservedFileName(name string) bool {
+	base, _, _ := strings.Cut(strings.ToUpper(name), ".")
+	switch base {
+	case "CON", "PRN", "AUX", "NUL":
+		return true
+	}
+	if len(base) == 4 && (strings.HasPrefix(base, "COM") || strings.HasPrefix(base, "LPT")) {
+		return base[3] >= '1' && base[3] <= '9'
+	}
+	return false
+}
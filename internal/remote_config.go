@@ -0,0 +1,39 @@
+// File: remote_config.go
+// Package: internal
+
+// Program Description:
+// This file moves and copies the per-branch config sections.
+// A [branch "main"] section holds the settings of a branch, such as the remote and merge ref it
+// tracks; "branch -m" and "branch -c" carry it along with the branch.
+
+package internal
+
+// RenameBranchConfig moves the branch.<oldName> section, with the upstream of the branch, to
+// branch.<newName>, replacing any configuration newName had. It is a no-op if oldName has none.
+func RenameBranchConfig(path string, oldName string, newName string) (err error) {
+	return editConfigFile(path, func(file *configFile) error {
+		file.removeSection("branch", newName)
+		_, renameErr := file.renameSection("branch", oldName, "branch", newName)
+		return renameErr
+	})
+}
+
+// CopyBranchConfig copies the branch.<oldName> section to branch.<newName>, replacing any
+// configuration newName had.
+func CopyBranchConfig(path string, oldName string, newName string) (err error) {
+	return editConfigFile(path, func(file *configFile) error {
+		file.removeSection("branch", newName)
+		var copied []configLine
+		for _, line := range file.lines {
+			if line.section == "branch" && line.subsection == oldName && line.name != "" {
+				copied = append(copied, line)
+			}
+		}
+		for _, line := range copied {
+			if setErr := file.set(joinConfigKey("branch", newName, line.name), line.value, true); setErr != nil {
+				return setErr
+			}
+		}
+		return nil
+	})
+}
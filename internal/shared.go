@@ -0,0 +1,158 @@
+// File: shared.go
+// Package: internal
+
+// Program Description:
+// This file implements repositories shared by several Unix users, set up with "jit init --shared".
+// core.sharedRepository says who may write the repository: "group" (or "true") makes every file
+// group-writable, "all" also makes it readable by everyone, and an octal mode such as 0640 is used
+// as is. Directories get the matching mode with execute bits and the setgid bit, so files created
+// in them belong to the repository's group. The setgid bit also marks the repository as shared:
+// files and directories jit creates later take their mode from the directory they are created
+// in, so no command has to read the configuration first.
+
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"jit/pkg/util"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidSharedMode is returned for a core.sharedRepository value that is not understood.
+var ErrInvalidSharedMode = errors.New("invalid core.sharedRepository value")
+
+// ParseSharedRepository parses a core.sharedRepository value.
+//
+// Args:
+//
+//	value (string): "umask", "false" or "" for a private repository, "group" or "true", "all",
+//	                "world" or "everybody", or an octal file mode such as "0660".
+//
+// Returns:
+//
+//	mode (fs.FileMode): The permission of the files of the repository, without execute bits, or 0
+//	                    for a private repository, which uses the umask.
+//	err (error): ErrInvalidSharedMode for an unknown value or a mode the owner cannot read and
+//	             write.
+func ParseSharedRepository(value string) (mode fs.FileMode, err error) {
+	switch strings.ToLower(value) {
+	case "", "umask", "false", "no", "off":
+		return 0, nil
+	case "group", "true", "yes", "on", "1":
+		return 0660, nil
+	case "all", "world", "everybody", "2":
+		return 0664, nil
+	}
+	octal, parseErr := strconv.ParseUint(value, 8, 32)
+	if parseErr != nil || octal&^0777 != 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidSharedMode, value)
+	}
+	if octal&0600 != 0600 {
+		return 0, fmt.Errorf("%w: %q does not let the owner read and write", ErrInvalidSharedMode, value)
+	}
+	return fs.FileMode(octal) &^ 0111, nil
+}
+
+// sharedDirMode returns the mode of the directories of a shared repository whose files have
+// fileMode: every class that can read can also list, and the setgid bit is set.
+func sharedDirMode(fileMode fs.FileMode) fs.FileMode {
+	return fileMode | (fileMode&0444)>>2 | fs.ModeSetgid
+}
+
+// applySharedPermissions sets the mode of every entry of a repository for core.sharedRepository.
+// Executable files, such as hooks, stay executable for everyone who can read them.
+func applySharedPermissions(jitDir string, fileMode fs.FileMode) error {
+	if !unixPermissions {
+		Warnf("core.sharedRepository has no effect on this platform")
+		return nil
+	}
+	var visit func(dir string) error
+	visit = func(dir string) error {
+		if chmodErr := Files.Chmod(dir, sharedDirMode(fileMode)); chmodErr != nil {
+			return chmodErr
+		}
+		entries, readErr := Files.ReadDir(dir)
+		if readErr != nil {
+			return readErr
+		}
+		for _, entry := range entries {
+			entryPath := filepath.Join(dir, entry.Name())
+			switch {
+			case entry.IsDir():
+				if visitErr := visit(entryPath); visitErr != nil {
+					return visitErr
+				}
+			case entry.Type().IsRegular():
+				info, infoErr := entry.Info()
+				if infoErr != nil {
+					return infoErr
+				}
+				mode := fileMode
+				if info.Mode()&0100 != 0 {
+					mode |= (fileMode & 0444) >> 2
+				}
+				if chmodErr := Files.Chmod(entryPath, mode); chmodErr != nil {
+					return chmodErr
+				}
+			}
+		}
+		return nil
+	}
+	return visit(jitDir)
+}
+
+// sharedFileMode returns the mode a file created in dir must have, and false when dir is not part
+// of a shared repository and the umask applies.
+func sharedFileMode(dir string) (fs.FileMode, bool) {
+	info, statErr := Files.Stat(dir)
+	if statErr != nil || info.Mode()&fs.ModeSetgid == 0 {
+		return 0, false
+	}
+	return info.Mode().Perm() &^ 0111, true
+}
+
+// adjustSharedFile gives a file created by jit the mode of the shared repository it is in.
+func adjustSharedFile(path string) error {
+	if mode, shared := sharedFileMode(filepath.Dir(path)); shared {
+		return Files.Chmod(path, mode)
+	}
+	return nil
+}
+
+// mkdirAllShared creates a directory and its missing parents. Inside a shared repository the new
+// directories get the mode and setgid bit of the directory they are created in.
+func mkdirAllShared(dir string) error {
+	var created []string
+	for current := dir; ; current = filepath.Dir(current) {
+		if _, statErr := Files.Stat(current); statErr == nil || filepath.Dir(current) == current {
+			break
+		}
+		created = append(created, current)
+	}
+	if mkErr := Files.MkdirAll(dir, 0755); mkErr != nil {
+		return mkErr
+	}
+	for i := len(created) - 1; i >= 0; i-- {
+		parent, statErr := Files.Stat(filepath.Dir(created[i]))
+		if statErr != nil || parent.Mode()&fs.ModeSetgid == 0 {
+			continue
+		}
+		if chmodErr := Files.Chmod(created[i], parent.Mode()&(fs.ModePerm|fs.ModeSetgid)); chmodErr != nil {
+			return chmodErr
+		}
+	}
+	return nil
+}
+
+// filePermFor returns the permission of the files created in a new repository whose directories
+// have dirPerm: the same bits without execute, e.g. 0644 for 0755 and 0600 for 0700.
+func filePermFor(dirPerm fs.FileMode) fs.FileMode {
+	if dirPerm == 0 {
+		return util.DefaultFilePerm
+	}
+	return dirPerm.Perm() &^ 0111
+}
@@ -0,0 +1,130 @@
+// File: template.go
+// Package: internal
+
+// Program Description:
+// This file copies a template directory into a new repository.
+// Everything in the template (hooks, info/exclude, description and any other default files) is
+// copied into the .jit directory with its permissions, without overwriting files init already
+// created. The template's config file is not copied, since init writes its own.
+
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"jit/pkg/util"
+	"os"
+	"path/filepath"
+)
+
+// ResolveTemplateDir returns the template directory to use for a new repository: the --template
+// option if given, then JIT_TEMPLATE_DIR, then init.templateDir from the system or global config.
+// An empty result means no template is used.
+func ResolveTemplateDir(option string) string {
+	if option != "" {
+		return option
+	}
+	if envDir := os.Getenv(util.EnvTemplateDir); envDir != "" {
+		return envDir
+	}
+	if config, loadErr := LoadConfig(""); loadErr == nil {
+		if configDir, ok := config.Get("init.templateDir"); ok {
+			return configDir
+		}
+	}
+	return ""
+}
+
+// CopyTemplate copies the content of a template directory into a .jit directory.
+//
+// Args:
+//
+//	templateDir (string): The template directory.
+//	jitDir (string): The .jit directory of the new repository.
+//
+// Returns:
+//
+//	err (error): An error if the template directory does not exist or a file cannot be copied.
+//
+// The function performs the following steps:
+//  1. Walks the template directory, skipping the top-level config file.
+//  2. Creates every directory and copies every file with its permission bits, so executable hooks
+//     stay executable.
+//  3. Recreates symbolic links as links.
+//  4. Leaves files that already exist in jitDir untouched.
+//
+// Usage:
+//
+//	if err := CopyTemplate("/usr/share/jit/templates", jitDir); err != nil {
+//	    log.Printf("warning: templates not found -> %s", err)
+//	}
+func CopyTemplate(templateDir string, jitDir string) (err error) {
+	info, statErr := Files.Stat(templateDir)
+	if statErr != nil {
+		return statErr
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", templateDir)
+	}
+
+	return copyTemplateDir(templateDir, jitDir, true)
+}
+
+// copyTemplateDir copies the entries of source into target, skipping config at the top level.
+func copyTemplateDir(source string, target string, top bool) error {
+	entries, readErr := Files.ReadDir(source)
+	if readErr != nil {
+		return readErr
+	}
+	for _, entry := range entries {
+		if top && entry.Name() == util.CONFIG {
+			continue
+		}
+		from := filepath.Join(source, entry.Name())
+		to := filepath.Join(target, entry.Name())
+
+		entryInfo, infoErr := entry.Info()
+		if infoErr != nil {
+			return infoErr
+		}
+		switch {
+		case entry.IsDir():
+			if mkErr := Files.MkdirAll(to, entryInfo.Mode().Perm()|0700); mkErr != nil {
+				return mkErr
+			}
+			if copyErr := copyTemplateDir(from, to, false); copyErr != nil {
+				return copyErr
+			}
+		case entry.Type()&fs.ModeSymlink != 0:
+			link, linkErr := Files.Readlink(from)
+			if linkErr != nil {
+				return linkErr
+			}
+			if symErr := Files.Symlink(link, to); symErr != nil && !errors.Is(symErr, fs.ErrExist) {
+				return symErr
+			}
+		default:
+			if copyErr := copyTemplateFile(from, to, entryInfo.Mode().Perm()); copyErr != nil {
+				return copyErr
+			}
+		}
+	}
+	return nil
+}
+
+// copyTemplateFile copies a file unless the target already exists.
+func copyTemplateFile(source string, target string, perm fs.FileMode) error {
+	if _, statErr := Files.Lstat(target); statErr == nil {
+		return nil
+	}
+	content, readErr := Files.ReadFile(source)
+	if readErr != nil {
+		return readErr
+	}
+	if writeErr := Files.WriteFile(target, content, perm); writeErr != nil {
+		return writeErr
+	}
+	// WriteFile applies the umask; set the template's mode exactly.
+	return Files.Chmod(target, perm)
+}
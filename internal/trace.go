@@ -0,0 +1,261 @@
+// File: trace.go
+// Package: internal
+
+// Program Description:
+// This file records a structured trace of what a command does, to diagnose slow operations.
+// Tracing is enabled by JIT_TRACE (or the --trace option) and writes one JSON object per line:
+// the command's start and exit, timed regions such as hooks, and every file operation made
+// through Files with its duration. JIT_TRACE=1 traces to stderr; an absolute path appends the
+// trace to that file.
+
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceEvent is one line of the trace.
+type TraceEvent struct {
+	Time     string         `json:"time"`
+	Event    string         `json:"event"` // start, exit, region_enter, region_leave or data.
+	Category string         `json:"category,omitempty"`
+	Name     string         `json:"name,omitempty"`
+	Elapsed  float64        `json:"elapsed_ms,omitempty"` // For region_leave and data: the duration; for exit: the process run time.
+	Data     map[string]any `json:"data,omitempty"`
+}
+
+// tracer writes trace events. It is nil while tracing is disabled.
+var tracer *traceWriter
+
+type traceWriter struct {
+	mu      sync.Mutex
+	out     io.Writer
+	started time.Time
+}
+
+// EnableTrace turns tracing on according to a JIT_TRACE value.
+//
+// Args:
+//
+//	target (string): "1", "2" or "true" to trace to stderr; an absolute path to append the trace to
+//	                 that file; "", "0" or "false" to leave tracing off.
+//
+// Returns:
+//
+//	err (error): An error if target is neither a switch nor an absolute path, or the file cannot be opened.
+//
+// Note:
+//   - Once enabled, Files is wrapped so every file operation is traced.
+func EnableTrace(target string) (err error) {
+	var out io.Writer
+	switch strings.ToLower(target) {
+	case "", "0", "false":
+		return nil
+	case "1", "2", "true":
+		out = os.Stderr
+	default:
+		if !filepath.IsAbs(target) {
+			return fmt.Errorf("JIT_TRACE must be 1 or an absolute path -> %s", target)
+		}
+		file, openErr := os.OpenFile(target, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if openErr != nil {
+			return openErr
+		}
+		out = file
+	}
+
+	DisableTrace()
+	Files = &tracingFileSystem{inner: Files}
+	tracer = &traceWriter{out: out, started: time.Now()}
+	return nil
+}
+
+// DisableTrace turns tracing off and restores the filesystem EnableTrace wrapped.
+func DisableTrace() {
+	if tracer == nil {
+		return
+	}
+	if wrapped, ok := Files.(*tracingFileSystem); ok {
+		Files = wrapped.inner
+	}
+	if closer, ok := tracer.out.(io.Closer); ok && tracer.out != os.Stderr {
+		_ = closer.Close()
+	}
+	tracer = nil
+}
+
+// TraceEnabled reports whether tracing is on.
+func TraceEnabled() bool {
+	return tracer != nil
+}
+
+// Trace records an event. It does nothing while tracing is disabled.
+func Trace(event TraceEvent) {
+	if tracer == nil {
+		return
+	}
+	event.Time = time.Now().Format("15:04:05.000000")
+	line, marshalErr := json.Marshal(event)
+	if marshalErr != nil {
+		return
+	}
+	tracer.mu.Lock()
+	defer tracer.mu.Unlock()
+	_, _ = tracer.out.Write(append(line, '\n'))
+}
+
+// TraceStart records the start of the process with its arguments.
+func TraceStart(args []string) {
+	Trace(TraceEvent{Event: "start", Data: map[string]any{"argv": args}})
+}
+
+// TraceExit records the end of the process with its exit code and total run time.
+func TraceExit(code int) {
+	if tracer == nil {
+		return
+	}
+	Trace(TraceEvent{Event: "exit", Elapsed: milliseconds(time.Since(tracer.started)), Data: map[string]any{"code": code}})
+}
+
+// TraceRegion records entering a region and returns the function that records leaving it, with
+// the time spent inside.
+//
+// Usage:
+//
+//	defer TraceRegion("hook", name)()
+func TraceRegion(category string, name string) (leave func()) {
+	if tracer == nil {
+		return func() {}
+	}
+	Trace(TraceEvent{Event: "region_enter", Category: category, Name: name})
+	entered := time.Now()
+	return func() {
+		Trace(TraceEvent{Event: "region_leave", Category: category, Name: name, Elapsed: milliseconds(time.Since(entered))})
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// tracingFileSystem traces every operation of the filesystem it wraps.
+type tracingFileSystem struct {
+	inner FileSystem
+}
+
+func (t *tracingFileSystem) record(op string, name string, started time.Time, err error, data map[string]any) {
+	if data == nil {
+		data = map[string]any{}
+	}
+	data["path"] = name
+	if err != nil {
+		data["error"] = err.Error()
+	}
+	Trace(TraceEvent{Event: "data", Category: "fs", Name: op, Elapsed: milliseconds(time.Since(started)), Data: data})
+}
+
+func (t *tracingFileSystem) ReadFile(name string) ([]byte, error) {
+	started := time.Now()
+	content, err := t.inner.ReadFile(name)
+	t.record("read", name, started, err, map[string]any{"bytes": len(content)})
+	return content, err
+}
+
+func (t *tracingFileSystem) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	started := time.Now()
+	err := t.inner.WriteFile(name, data, perm)
+	t.record("write", name, started, err, map[string]any{"bytes": len(data)})
+	return err
+}
+
+func (t *tracingFileSystem) WriteFileSync(name string, data []byte, perm fs.FileMode) error {
+	started := time.Now()
+	err := t.inner.WriteFileSync(name, data, perm)
+	t.record("write", name, started, err, map[string]any{"bytes": len(data), "sync": true})
+	return err
+}
+
+func (t *tracingFileSystem) CreateExclusive(name string, data []byte, perm fs.FileMode) error {
+	started := time.Now()
+	err := t.inner.CreateExclusive(name, data, perm)
+	t.record("create", name, started, err, map[string]any{"bytes": len(data)})
+	return err
+}
+
+func (t *tracingFileSystem) Rename(oldname string, newname string) error {
+	started := time.Now()
+	err := t.inner.Rename(oldname, newname)
+	t.record("rename", newname, started, err, map[string]any{"from": oldname})
+	return err
+}
+
+func (t *tracingFileSystem) Stat(name string) (fs.FileInfo, error) {
+	started := time.Now()
+	info, err := t.inner.Stat(name)
+	t.record("stat", name, started, err, nil)
+	return info, err
+}
+
+func (t *tracingFileSystem) Lstat(name string) (fs.FileInfo, error) {
+	started := time.Now()
+	info, err := t.inner.Lstat(name)
+	t.record("lstat", name, started, err, nil)
+	return info, err
+}
+
+func (t *tracingFileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	started := time.Now()
+	entries, err := t.inner.ReadDir(name)
+	t.record("readdir", name, started, err, map[string]any{"entries": len(entries)})
+	return entries, err
+}
+
+func (t *tracingFileSystem) Mkdir(name string, perm fs.FileMode) error {
+	started := time.Now()
+	err := t.inner.Mkdir(name, perm)
+	t.record("mkdir", name, started, err, nil)
+	return err
+}
+
+func (t *tracingFileSystem) MkdirAll(name string, perm fs.FileMode) error {
+	started := time.Now()
+	err := t.inner.MkdirAll(name, perm)
+	t.record("mkdir", name, started, err, nil)
+	return err
+}
+
+func (t *tracingFileSystem) Remove(name string) error {
+	started := time.Now()
+	err := t.inner.Remove(name)
+	t.record("remove", name, started, err, nil)
+	return err
+}
+
+func (t *tracingFileSystem) Readlink(name string) (string, error) {
+	started := time.Now()
+	target, err := t.inner.Readlink(name)
+	t.record("readlink", name, started, err, nil)
+	return target, err
+}
+
+func (t *tracingFileSystem) Symlink(oldname string, newname string) error {
+	started := time.Now()
+	err := t.inner.Symlink(oldname, newname)
+	t.record("symlink", newname, started, err, map[string]any{"target": oldname})
+	return err
+}
+
+func (t *tracingFileSystem) Chmod(name string, mode fs.FileMode) error {
+	started := time.Now()
+	err := t.inner.Chmod(name, mode)
+	t.record("chmod", name, started, err, map[string]any{"mode": fmt.Sprintf("%o", mode)})
+	return err
+}
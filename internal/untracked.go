@@ -0,0 +1,102 @@
+// File: untracked.go
+// Package: internal
+
+// Program Description:
+// This file finds the untracked files reported by status, in the three modes of
+// --untracked-files and status.showUntrackedFiles: "no" reports nothing, "all" reports every
+// untracked file, and "normal", the default, reports a directory holding no tracked files as a
+// single "dir/" entry without listing its contents, which keeps status fast and its memory use
+// bounded in directories such as node_modules.
+
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidUntrackedMode is returned for a value of --untracked-files that is not no, normal or all.
+var ErrInvalidUntrackedMode = errors.New("invalid untracked files mode")
+
+// UntrackedMode is how untracked files are reported.
+type UntrackedMode int
+
+const (
+	UntrackedNormal UntrackedMode = iota // Untracked directories are collapsed to one entry.
+	UntrackedNo                          // Untracked files are not reported.
+	UntrackedAll                         // Every untracked file is reported.
+)
+
+// ParseUntrackedMode parses a value of --untracked-files or status.showUntrackedFiles. An empty
+// value is the default, normal.
+func ParseUntrackedMode(value string) (UntrackedMode, error) {
+	switch strings.ToLower(value) {
+	case "", "normal":
+		return UntrackedNormal, nil
+	case "no":
+		return UntrackedNo, nil
+	case "all":
+		return UntrackedAll, nil
+	}
+	return UntrackedNormal, fmt.Errorf("%w: %s (use no, normal or all)", ErrInvalidUntrackedMode, value)
+}
+
+// UntrackedFiles returns the untracked paths of a working tree.
+//
+// Args:
+//
+//	ctx (context.Context): Stops the walk of the working tree when canceled.
+//	workTree (string): The root of the working tree.
+//	tracked ([]string): The slash-separated paths of the tracked files, in any order.
+//	mode (UntrackedMode): How to report untracked files.
+//	ignore (*IgnoreMatcher): The ignore rules; ignored files are never reported. May be nil.
+//
+// Returns:
+//
+//	untracked ([]string): The untracked paths, sorted. In normal mode, directories without tracked
+//	                      files appear once with a trailing "/".
+//	err (error): An error if the working tree cannot be walked.
+//
+// Usage:
+//
+//	mode, err := ParseUntrackedMode(untrackedFlag)
+//	if err != nil {
+//	    log.Fatalln(err)
+//	}
+//	untracked, err := UntrackedFiles(ctx, workTree, trackedPaths, mode, matcher)
+func UntrackedFiles(ctx context.Context, workTree string, tracked []string, mode UntrackedMode, ignore *IgnoreMatcher) (untracked []string, err error) {
+	if mode == UntrackedNo {
+		return nil, nil
+	}
+
+	sorted := append([]string(nil), tracked...)
+	sort.Strings(sorted)
+	options := WalkOptions{Ignore: ignore}
+	if mode == UntrackedNormal {
+		options.Collapse = func(dir string) bool {
+			return !hasPathUnder(sorted, dir)
+		}
+	}
+
+	entries, walkErr := WalkWorkTree(ctx, workTree, options)
+	if walkErr != nil {
+		return nil, walkErr
+	}
+	for _, entry := range entries {
+		if i := sort.SearchStrings(sorted, entry.Path); i < len(sorted) && sorted[i] == entry.Path {
+			continue
+		}
+		untracked = append(untracked, entry.Path)
+	}
+	return untracked, nil
+}
+
+// hasPathUnder reports whether a sorted list of paths contains one inside dir.
+func hasPathUnder(sorted []string, dir string) bool {
+	prefix := dir + "/"
+	i := sort.SearchStrings(sorted, prefix)
+	return i < len(sorted) && strings.HasPrefix(sorted[i], prefix)
+}
@@ -0,0 +1,228 @@
+// File: walk.go
+// Package: internal
+
+// Program Description:
+// This file walks the working tree to find the files a command such as status has to look at.
+// On large trees the walk is dominated by reading directories and calling lstat, so directories
+// are read by a fixed pool of goroutines taking them from a shared queue, each of which also
+// evaluates the ignore rules for the entries it finds. Ignored directories are not entered, .jit directories are always skipped,
+// and symbolic links are reported as entries rather than followed.
+
+package internal
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"jit/pkg/util"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// WalkEntry is a file or symbolic link found in the working tree, or a collapsed directory.
+type WalkEntry struct {
+	Path string      // The slash-separated path relative to the work tree; collapsed directories end in "/".
+	Info fs.FileInfo // The lstat information of the entry.
+}
+
+// WalkOptions control WalkWorkTree.
+type WalkOptions struct {
+	Ignore  *IgnoreMatcher // The ignore rules to apply, or nil to report every file.
+	Workers int            // The number of goroutines reading directories; 0 means GOMAXPROCS.
+	// Collapse, when set, is called for every directory. A directory for which it returns true is
+	// reported as a single entry, if it holds at least one file that is not ignored, instead of
+	// being walked.
+	Collapse func(dir string) bool
+}
+
+// treeWalker holds the state shared by the workers of one walk.
+type treeWalker struct {
+	ctx      context.Context
+	workTree string
+	ignore   *IgnoreMatcher
+	collapse func(dir string) bool
+	wg       sync.WaitGroup
+	mu       sync.Mutex // Guards queue, pending, entries and err.
+	ready    *sync.Cond // Signaled when a directory is queued or the walk is over.
+	queue    []string   // The directories found but not yet read.
+	pending  int        // The directories queued or being read; the walk is over at 0.
+	entries  []WalkEntry
+	err      error
+}
+
+// WalkWorkTree returns the files of a working tree that are not ignored.
+//
+// Args:
+//
+//	ctx (context.Context): Stops the walk when canceled; directories not read yet are skipped.
+//	workTree (string): The root of the working tree.
+//	options (WalkOptions): The ignore rules and the number of workers.
+//
+// Returns:
+//
+//	entries ([]WalkEntry): The files and symbolic links found, sorted by path.
+//	err (error): The first error met reading a directory or calling lstat, or the error of ctx if it
+//	             is canceled. Entries that disappear during the walk are skipped rather than reported.
+//
+// Usage:
+//
+//	matcher, _ := NewIgnoreMatcher(workTree, jitDir, DefaultGlobalIgnoreFile(), ReadGitFiles(config))
+//	entries, err := WalkWorkTree(ctx, workTree, WalkOptions{Ignore: matcher})
+//	if err != nil {
+//	    log.Fatalln(err)
+//	}
+//	for _, entry := range entries {
+//	    fmt.Println(entry.Path, entry.Info.Size())
+//	}
+//
+// Note:
+//   - The result is the same whatever the number of workers; only the time taken changes.
+func WalkWorkTree(ctx context.Context, workTree string, options WalkOptions) (entries []WalkEntry, err error) {
+	workers := options.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if _, statErr := Files.Stat(workTree); statErr != nil {
+		return nil, statErr
+	}
+	defer TraceRegion("walk", workTree)()
+
+	walker := &treeWalker{ctx: ctx, workTree: workTree, ignore: options.Ignore, collapse: options.Collapse, queue: []string{""}, pending: 1}
+	walker.ready = sync.NewCond(&walker.mu)
+	walker.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go walker.work()
+	}
+	walker.wg.Wait()
+
+	if walker.err != nil {
+		return nil, walker.err
+	}
+	sort.Slice(walker.entries, func(i, j int) bool { return walker.entries[i].Path < walker.entries[j].Path })
+	return walker.entries, nil
+}
+
+// work reads queued directories until none is queued or being read.
+func (w *treeWalker) work() {
+	defer w.wg.Done()
+	for {
+		dir, ok := w.next()
+		if !ok {
+			return
+		}
+		var subdirs []string
+		var found []WalkEntry
+		if ctxErr := w.ctx.Err(); ctxErr != nil {
+			w.fail(ctxErr)
+		} else {
+			subdirs, found = w.readDir(dir)
+		}
+
+		w.mu.Lock()
+		w.entries = append(w.entries, found...)
+		w.queue = append(w.queue, subdirs...)
+		w.pending += len(subdirs) - 1
+		if len(subdirs) > 0 || w.pending == 0 {
+			w.ready.Broadcast()
+		}
+		w.mu.Unlock()
+	}
+}
+
+// next takes a directory from the queue, waiting while other workers may still queue one. It
+// returns false once the walk is over.
+func (w *treeWalker) next() (dir string, ok bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for len(w.queue) == 0 && w.pending > 0 {
+		w.ready.Wait()
+	}
+	if len(w.queue) == 0 {
+		return "", false
+	}
+	// Taking the newest directory walks the tree depth first, which keeps the queue short.
+	dir = w.queue[len(w.queue)-1]
+	w.queue = w.queue[:len(w.queue)-1]
+	return dir, true
+}
+
+// readDir reads one directory and returns the subdirectories to walk and the files it holds that
+// are not ignored.
+func (w *treeWalker) readDir(dir string) (subdirs []string, found []WalkEntry) {
+	children, readErr := Files.ReadDir(filepath.Join(w.workTree, filepath.FromSlash(dir)))
+	if readErr != nil {
+		w.fail(readErr)
+		return nil, nil
+	}
+
+	for _, child := range children {
+		rel := path.Join(dir, child.Name())
+		if child.Name() == util.JitDirName {
+			continue
+		}
+		info, statErr := Files.Lstat(filepath.Join(w.workTree, filepath.FromSlash(rel)))
+		if statErr != nil {
+			w.fail(statErr)
+			continue
+		}
+		if w.ignore != nil && w.ignore.IsIgnored(rel, info.IsDir()) {
+			continue
+		}
+		if info.IsDir() && w.collapse != nil && w.collapse(rel) {
+			if w.holdsFile(rel) {
+				found = append(found, WalkEntry{Path: rel + "/", Info: info})
+			}
+			continue
+		}
+		if info.IsDir() {
+			subdirs = append(subdirs, rel)
+			continue
+		}
+		found = append(found, WalkEntry{Path: rel, Info: info})
+	}
+	return subdirs, found
+}
+
+// holdsFile reports whether a directory contains a file that is not ignored. It stops at the first
+// one, so only the directories on the way to that file are read, though each of them is listed in
+// full.
+func (w *treeWalker) holdsFile(dir string) bool {
+	if ctxErr := w.ctx.Err(); ctxErr != nil {
+		w.fail(ctxErr)
+		return false
+	}
+	children, readErr := Files.ReadDir(filepath.Join(w.workTree, filepath.FromSlash(dir)))
+	if readErr != nil {
+		w.fail(readErr)
+		return false
+	}
+	for _, child := range children {
+		rel := path.Join(dir, child.Name())
+		if child.Name() == util.JitDirName {
+			continue
+		}
+		isDir := child.IsDir()
+		if w.ignore != nil && w.ignore.IsIgnored(rel, isDir) {
+			continue
+		}
+		if !isDir || w.holdsFile(rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// fail records the first error of the walk. Paths removed while the walk runs are not errors.
+func (w *treeWalker) fail(err error) {
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	w.mu.Lock()
+	if w.err == nil {
+		w.err = err
+	}
+	w.mu.Unlock()
+}
@@ -0,0 +1,134 @@
+// File: refs.go
+// Package: repo
+
+// Program Description:
+// This file lets library users walk the refs of a repository one at a time.
+// A RefIter reads the branch directory lazily, so a caller looking for one ref does not load them
+// all, and supports predicate filtering and early termination through ForEach and ErrStop.
+
+package repo
+
+import (
+	"errors"
+	"io"
+	"jit/internal"
+	"jit/pkg/util"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrStop ends a ForEach iteration early without reporting an error.
+var ErrStop = errors.New("stop iteration")
+
+// Ref is a named pointer to a commit.
+type Ref struct {
+	Name   string // The branch name, e.g. "main" or "feature/login".
+	Target string // The commit the ref points to, or "" for a branch without commits.
+}
+
+// RefIter iterates over refs in name order.
+type RefIter struct {
+	root    string
+	pending []string // Slash-separated names still to visit; directories are expanded on demand.
+	filter  func(Ref) bool
+}
+
+// Branches returns an iterator over the branches of the repository.
+//
+// Usage:
+//
+//	iter, err := repository.Branches()
+//	if err != nil {
+//	    log.Fatalln(err)
+//	}
+//	err = iter.Filter(func(ref repo.Ref) bool {
+//	    return strings.HasPrefix(ref.Name, "feature/")
+//	}).ForEach(func(ref repo.Ref) error {
+//	    fmt.Println(ref.Name)
+//	    return nil
+//	})
+func (r *Repository) Branches() (iter *RefIter, err error) {
+	root := filepath.Join(r.jitDir, util.BRANCHES)
+	names, listErr := listDir(root, "")
+	if listErr != nil {
+		return nil, listErr
+	}
+	return &RefIter{root: root, pending: names}, nil
+}
+
+// Filter returns the iterator restricted to the refs for which keep returns true.
+func (it *RefIter) Filter(keep func(Ref) bool) *RefIter {
+	previous := it.filter
+	it.filter = func(ref Ref) bool {
+		return (previous == nil || previous(ref)) && keep(ref)
+	}
+	return it
+}
+
+// Next returns the next ref, or io.EOF when there are no more.
+func (it *RefIter) Next() (ref Ref, err error) {
+	for len(it.pending) > 0 {
+		name := it.pending[0]
+		it.pending = it.pending[1:]
+
+		file := filepath.Join(it.root, filepath.FromSlash(name))
+		info, statErr := internal.Files.Stat(file)
+		if statErr != nil {
+			return Ref{}, statErr
+		}
+		if info.IsDir() {
+			children, listErr := listDir(it.root, name)
+			if listErr != nil {
+				return Ref{}, listErr
+			}
+			it.pending = append(children, it.pending...)
+			continue
+		}
+
+		content, readErr := internal.Files.ReadFile(file)
+		if readErr != nil {
+			return Ref{}, readErr
+		}
+		ref = Ref{Name: name, Target: strings.TrimSpace(string(content))}
+		if it.filter == nil || it.filter(ref) {
+			return ref, nil
+		}
+	}
+	return Ref{}, io.EOF
+}
+
+// ForEach calls fn for every remaining ref. Returning ErrStop from fn ends the iteration with a nil
+// error; any other error ends it and is returned.
+func (it *RefIter) ForEach(fn func(Ref) error) error {
+	for {
+		ref, nextErr := it.Next()
+		if errors.Is(nextErr, io.EOF) {
+			return nil
+		}
+		if nextErr != nil {
+			return nextErr
+		}
+		if fnErr := fn(ref); fnErr != nil {
+			if errors.Is(fnErr, ErrStop) {
+				return nil
+			}
+			return fnErr
+		}
+	}
+}
+
+// listDir returns the sorted, slash-separated names of the entries of a directory below root.
+func listDir(root string, dir string) ([]string, error) {
+	entries, readErr := internal.Files.ReadDir(filepath.Join(root, filepath.FromSlash(dir)))
+	if readErr != nil {
+		return nil, readErr
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, path.Join(dir, entry.Name()))
+	}
+	sort.Strings(names)
+	return names, nil
+}
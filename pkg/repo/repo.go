@@ -0,0 +1,165 @@
+// File: repo.go
+// Package: repo
+
+// Program Description:
+// This file is the public library entry point to jit.
+// It lets other Go programs create and open repositories without going through the command line:
+// Init creates a repository the same way "jit init" does, Open finds an existing one, and the
+// returned Repository answers questions about it such as its configuration and current branch.
+
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"jit/internal"
+	"jit/pkg/util"
+	"os"
+	"path/filepath"
+)
+
+// ErrNotARepository is returned by Open when no repository is found.
+var ErrNotARepository = internal.ErrNotARepository
+
+// ErrUnsupportedFormat is returned by Open for a repository created by a newer version of jit.
+var ErrUnsupportedFormat = internal.ErrUnsupportedFormat
+
+// Repository is an opened jit repository.
+type Repository struct {
+	jitDir   string
+	workTree string
+}
+
+// InitOptions are the options of Init. The zero value creates a non-bare repository with the
+// defaults of "jit init".
+type InitOptions struct {
+	Bare           bool        // Create a bare repository without a work tree.
+	SeparateJitDir string      // Create the repository here and link it from the work tree.
+	Template       string      // The template directory; empty uses JIT_TEMPLATE_DIR or init.templateDir.
+	ObjectFormat   string      // The hash algorithm, sha1 (the default) or sha256.
+	InitialBranch  string      // The name of the initial branch, main by default.
+	Permission     os.FileMode // The permission of the created directories, 0755 by default.
+}
+
+// Init creates a new repository and opens it.
+//
+// Args:
+//
+//	dir (string): The directory to create the repository in. Empty means the current directory.
+//	options (InitOptions): The repository options.
+//
+// Returns:
+//
+//	repository (*Repository): The new repository.
+//	err (error): Any error returned while creating or opening the repository, nil otherwise.
+//
+// Usage:
+//
+//	repository, err := repo.Init("/path/to/project", repo.InitOptions{InitialBranch: "trunk"})
+//	if err != nil {
+//	    log.Fatalf("Failed to create repository: %s", err)
+//	}
+func Init(dir string, options InitOptions) (repository *Repository, err error) {
+	if options.ObjectFormat == "" {
+		options.ObjectFormat = "sha1"
+	}
+	if options.InitialBranch == "" {
+		options.InitialBranch = util.MAIN
+	}
+	if options.Permission == 0 {
+		options.Permission = 0755
+	}
+
+	initOptions := map[string]any{
+		"quiet":            true,
+		"bare":             options.Bare,
+		"separate-jit-dir": options.SeparateJitDir,
+		"template":         options.Template,
+		"object-format":    options.ObjectFormat,
+		"initial-branch":   options.InitialBranch,
+		"perm":             fmt.Sprintf("%o", options.Permission.Perm()),
+	}
+	if _, initErr := internal.InitializeJitRepository(initOptions, dir); initErr != nil {
+		return nil, initErr
+	}
+
+	if dir == "" {
+		dir = "."
+	}
+	return Open(dir)
+}
+
+// Open opens the repository containing a directory.
+//
+// Args:
+//
+//	path (string): A directory inside the work tree, or the directory of a bare repository.
+//
+// Returns:
+//
+//	repository (*Repository): The repository.
+//	err (error): ErrNotARepository if neither path nor any of its parents is a repository, or
+//	             ErrUnsupportedFormat if the repository uses a format this jit does not support.
+//
+// Note:
+//   - Unlike the command line, Open ignores JIT_DIR and JIT_WORK_TREE so a library caller always
+//     gets the repository it asked for.
+func Open(path string) (repository *Repository, err error) {
+	absPath, absErr := filepath.Abs(path)
+	if absErr != nil {
+		return nil, absErr
+	}
+
+	jitDir, workTree, findErr := internal.FindRepository(absPath)
+	if errors.Is(findErr, internal.ErrNotARepository) && isJitDir(absPath) {
+		jitDir, workTree, findErr = absPath, "", nil
+	}
+	if findErr != nil {
+		return nil, findErr
+	}
+	if formatErr := internal.CheckRepositoryFormat(jitDir); formatErr != nil {
+		return nil, formatErr
+	}
+	return &Repository{jitDir: jitDir, workTree: workTree}, nil
+}
+
+// isJitDir reports whether dir has the layout of a jit directory, as a bare repository does.
+func isJitDir(dir string) bool {
+	if info, statErr := internal.Files.Stat(filepath.Join(dir, util.HEAD)); statErr != nil || info.IsDir() {
+		return false
+	}
+	info, statErr := internal.Files.Stat(filepath.Join(dir, util.BRANCHES))
+	return statErr == nil && info.IsDir()
+}
+
+// JitDir returns the absolute path of the jit directory.
+func (r *Repository) JitDir() string {
+	return r.jitDir
+}
+
+// WorkTree returns the absolute path of the work tree, or "" for a bare repository.
+func (r *Repository) WorkTree() string {
+	return r.workTree
+}
+
+// IsBare reports whether the repository has no work tree.
+func (r *Repository) IsBare() bool {
+	return r.workTree == ""
+}
+
+// Config returns the value of a configuration key, looking at the repository, global and system
+// configuration files in that order of precedence.
+func (r *Repository) Config(key string) (value string, ok bool, err error) {
+	config, loadErr := internal.LoadConfig(r.jitDir)
+	if loadErr != nil {
+		return "", false, loadErr
+	}
+	value, ok = config.Get(key)
+	return value, ok, nil
+}
+
+// CurrentBranch returns the name of the branch HEAD points to.
+func (r *Repository) CurrentBranch() (name string, err error) {
+	name, _, err = internal.ReadHead(r.jitDir)
+	return name, err
+}
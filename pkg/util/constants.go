@@ -14,10 +14,42 @@ const INFO = "info"
 const BRANCHES = "branches"
 const SNAPSHOTS = "snapshots"
 const OBJECTS = "objects"
+const EXCLUDE = "exclude"
+const HOOKS = "hooks"
+
+const SystemConfigFile = "/etc/jitconfig"
+const GlobalConfigFile = ".jitconfig"
+
+const JitIgnoreFile = ".jitignore"
+const GitIgnoreFile = ".gitignore"
+
+const EnvJitDir = "JIT_DIR"
+const EnvWorkTree = "JIT_WORK_TREE"
+const EnvConfigGlobal = "JIT_CONFIG_GLOBAL"
+const EnvAuthorName = "JIT_AUTHOR_NAME"
+const EnvAuthorEmail = "JIT_AUTHOR_EMAIL"
+const EnvAuthorDate = "JIT_AUTHOR_DATE"
+const EnvCommitterName = "JIT_COMMITTER_NAME"
+const EnvCommitterEmail = "JIT_COMMITTER_EMAIL"
+const EnvCommitterDate = "JIT_COMMITTER_DATE"
+const EnvEditor = "JIT_EDITOR"
+const EnvTemplateDir = "JIT_TEMPLATE_DIR"
+const EnvTrace = "JIT_TRACE"
+
+const CommitEditMsg = "COMMIT_EDITMSG"
+const TagEditMsg = "TAG_EDITMSG"
+const DefaultEditor = "vi"
 
 const DefaultFilePerm = 0644
 
 const Init string = "init"
+const Config string = "config"
+const Hook string = "hook"
+const Help string = "help"
+const Fsck string = "fsck"
+const Branch string = "branch"
+const Diff string = "diff"
+const Status string = "status"
 
 type File string
 
@@ -2,23 +2,34 @@ package util
 
 import (
 	"embed"
+	"io"
 	"io/fs"
-	"log"
-	"os"
 )
 
 //go:embed help_docs/*
 var helpDocs embed.FS
 
-func DisplayHelpDocs(topic string) {
+// generatedHelp holds the help topics generated at runtime, such as the page of each command.
+var generatedHelp = map[string]func(out io.Writer) error{}
+
+// RegisterHelpTopic adds a generated help topic, served by DisplayHelpDocs before the embedded pages.
+func RegisterHelpTopic(topic string, write func(out io.Writer) error) {
+	generatedHelp[topic] = write
+}
+
+// DisplayHelpDocs writes the help page of a topic: a generated topic if one is registered,
+// otherwise the embedded page of that name. A missing topic returns an error wrapping fs.ErrNotExist.
+func DisplayHelpDocs(out io.Writer, topic string) error {
+	if write, ok := generatedHelp[topic]; ok {
+		return write(out)
+	}
 
 	file := topic + HelpDocExtension
 	data, readErr := fs.ReadFile(helpDocs, "help_docs/"+file)
 	if readErr != nil {
-		log.Fatalln(readErr)
+		return readErr
 	}
 
-	if _, writeErr := os.Stdout.Write(data); writeErr != nil {
-		log.Fatalln(writeErr)
-	}
+	_, writeErr := out.Write(data)
+	return writeErr
 }
@@ -0,0 +1,80 @@
+package test
+
+import (
+	"jit/internal"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResolveAlias(t *testing.T) {
+	dir, err := os.MkdirTemp("", "alias_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	configFile := filepath.Join(dir, "config")
+	writeTestFile(t, configFile, `[alias]
+	co = checkout
+	lg = log --graph '--format=%h %s'
+	last = lg -1
+	sh = !echo hi
+	init = commit
+	loop = again
+	again = loop
+`)
+	config, loadErr := internal.LoadConfigFile(configFile, internal.ScopeLocal)
+	if loadErr != nil {
+		t.Fatalf("LoadConfigFile failed: %s", loadErr)
+	}
+	isBuiltin := func(name string) bool { return name == "init" || name == "checkout" || name == "log" }
+
+	tests := []struct {
+		name     string
+		command  string
+		args     []string
+		expected []string
+		shell    string
+		wantErr  bool
+	}{
+		{name: "simple alias", command: "co", args: []string{"main"}, expected: []string{"checkout", "main"}},
+		{name: "quoted arguments", command: "lg", expected: []string{"log", "--graph", "--format=%h %s"}},
+		{name: "nested alias", command: "last", args: []string{"HEAD"}, expected: []string{"log", "--graph", "--format=%h %s", "-1", "HEAD"}},
+		{name: "shell alias", command: "sh", shell: "echo hi"},
+		{name: "builtins are not replaced", command: "init", expected: []string{"init"}},
+		{name: "unknown command is returned unchanged", command: "status", args: []string{"-s"}, expected: []string{"status", "-s"}},
+		{name: "alias loop", command: "loop", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved, shell, resolveErr := internal.ResolveAlias(config, tt.command, tt.args, isBuiltin)
+			if (resolveErr != nil) != tt.wantErr {
+				t.Fatalf("ResolveAlias() error = %v, wantErr %v", resolveErr, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(resolved, tt.expected) || shell != tt.shell {
+				t.Errorf("ResolveAlias() = %q, %q; want %q, %q", resolved, shell, tt.expected, tt.shell)
+			}
+		})
+	}
+}
+
+func TestSplitCommandLine(t *testing.T) {
+	words, err := internal.SplitCommandLine(`log  'a b' "c \"d\"" e\ f`)
+	if err != nil {
+		t.Fatalf("SplitCommandLine failed: %s", err)
+	}
+	expected := []string{"log", "a b", `c "d"`, "e f"}
+	if !reflect.DeepEqual(words, expected) {
+		t.Errorf("SplitCommandLine() = %q, want %q", words, expected)
+	}
+
+	if _, err := internal.SplitCommandLine(`log "unterminated`); err == nil {
+		t.Errorf("SplitCommandLine() expected an error for an unterminated quote")
+	}
+}
@@ -0,0 +1,84 @@
+package test
+
+import (
+	"bytes"
+	"jit/internal/diff"
+	"strings"
+	"testing"
+)
+
+func TestIsBinary(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  []byte
+		expected bool
+	}{
+		{"Text", []byte("hello\nworld\n"), false},
+		{"Empty", nil, false},
+		{"NUL byte", []byte("PNG\x00\x01\x02"), true},
+		{"NUL after sniff window", append(bytes.Repeat([]byte("a"), 9000), 0), false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := diff.IsBinary(tc.content); got != tc.expected {
+				t.Errorf("IsBinary() = %v, want %v", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestBinaryPatchRoundTrip(t *testing.T) {
+	large := make([]byte, 5000)
+	for i := range large {
+		large[i] = byte(i * 7 % 251)
+	}
+
+	tests := []struct {
+		name       string
+		oldContent []byte
+		newContent []byte
+	}{
+		{"Modified", []byte("\x00\x01\x02old"), []byte("\x00\x01\x02new content")},
+		{"Added", nil, []byte("\x89PNG\x00")},
+		{"Deleted", []byte("\x00gone"), nil},
+		{"Large", large[:4000], large},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			patch, err := diff.BinaryPatch(tc.oldContent, tc.newContent)
+			if err != nil {
+				t.Fatalf("BinaryPatch failed: %v", err)
+			}
+			if !strings.HasPrefix(patch, "GIT binary patch\nliteral ") {
+				t.Fatalf("Unexpected patch header: %q", patch)
+			}
+
+			newContent, oldContent, err := diff.ParseBinaryPatch(patch)
+			if err != nil {
+				t.Fatalf("ParseBinaryPatch failed: %v", err)
+			}
+			if !bytes.Equal(newContent, tc.newContent) {
+				t.Errorf("New content mismatch: got %d bytes, want %d", len(newContent), len(tc.newContent))
+			}
+			if !bytes.Equal(oldContent, tc.oldContent) {
+				t.Errorf("Old content mismatch: got %d bytes, want %d", len(oldContent), len(tc.oldContent))
+			}
+		})
+	}
+}
+
+func TestParseBinaryPatchRejectsCorruption(t *testing.T) {
+	patch, err := diff.BinaryPatch([]byte("\x00a"), []byte("\x00b"))
+	if err != nil {
+		t.Fatalf("BinaryPatch failed: %v", err)
+	}
+	corrupted := strings.Replace(patch, "literal 2\n", "literal 3\n", 1)
+	if _, _, err = diff.ParseBinaryPatch(corrupted); err == nil {
+		t.Error("Expected an error for a size mismatch")
+	}
+	if _, _, err = diff.ParseBinaryPatch("not a patch\n"); err == nil {
+		t.Error("Expected an error for a missing header")
+	}
+}
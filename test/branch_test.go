@@ -0,0 +1,117 @@
+package test
+
+import (
+	"errors"
+	"jit/internal"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newBranchTestRepo creates a repository on main with an upstream and a feature/login branch.
+func newBranchTestRepo(t *testing.T) (jitDir string) {
+	workTree, err := os.MkdirTemp("", "branch_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(workTree) })
+
+	if _, err := internal.InitializeJitRepository(map[string]any{"quiet": true}, workTree); err != nil {
+		t.Fatalf("InitializeJitRepository failed: %s", err)
+	}
+	jitDir = filepath.Join(workTree, ".jit")
+	for _, setting := range [][2]string{{"branch.main.remote", "origin"}, {"branch.main.merge", "refs/heads/main"}} {
+		if err := internal.SetConfigValue(filepath.Join(jitDir, "config"), setting[0], setting[1], false); err != nil {
+			t.Fatalf("SetConfigValue failed: %s", err)
+		}
+	}
+	transaction := internal.NewRefTransaction(jitDir)
+	transaction.Update("main", "c1", "")
+	transaction.Create("feature/login", "c2")
+	if err := transaction.Commit(); err != nil {
+		t.Fatalf("Commit failed: %s", err)
+	}
+	return jitDir
+}
+
+func loadBranchTestConfig(t *testing.T, jitDir string) *internal.Config {
+	config, err := internal.LoadConfigFile(filepath.Join(jitDir, "config"), internal.ScopeLocal)
+	if err != nil {
+		t.Fatalf("LoadConfigFile failed: %s", err)
+	}
+	return config
+}
+
+func TestRenameBranch(t *testing.T) {
+	jitDir := newBranchTestRepo(t)
+
+	if err := internal.RenameBranch(jitDir, "main", "trunk", false); err != nil {
+		t.Fatalf("RenameBranch failed: %s", err)
+	}
+	if names, _ := internal.ListBranches(jitDir); !reflect.DeepEqual(names, []string{"feature/login", "trunk"}) {
+		t.Errorf("branches = %v, want [feature/login trunk]", names)
+	}
+	if content, _ := os.ReadFile(filepath.Join(jitDir, "branches", "trunk")); string(content) != "c1" {
+		t.Errorf("trunk = %q, want c1", content)
+	}
+	if branch, _, _ := internal.ReadHead(jitDir); branch != "trunk" {
+		t.Errorf("HEAD = %q, want trunk after renaming the current branch", branch)
+	}
+	config := loadBranchTestConfig(t, jitDir)
+	remote, _ := config.Get("branch.trunk.remote")
+	merge, _ := config.Get("branch.trunk.merge")
+	if remote != "origin" || merge != "refs/heads/main" {
+		t.Errorf("upstream of trunk = %s %s, want origin refs/heads/main", remote, merge)
+	}
+	if _, ok := config.Get("branch.main.remote"); ok {
+		t.Errorf("main still has an upstream after the rename")
+	}
+
+	if err := internal.RenameBranch(jitDir, "feature/login", "trunk", false); !errors.Is(err, internal.ErrBranchExists) {
+		t.Errorf("RenameBranch() onto an existing branch = %v, want ErrBranchExists", err)
+	}
+	if err := internal.RenameBranch(jitDir, "feature/login", "trunk", true); err == nil || !strings.Contains(err.Error(), "current branch") {
+		t.Errorf("RenameBranch() forced onto the current branch = %v, want an error", err)
+	}
+	if err := internal.RenameBranch(jitDir, "missing", "other", false); !errors.Is(err, internal.ErrBranchNotFound) {
+		t.Errorf("RenameBranch() of a missing branch = %v, want ErrBranchNotFound", err)
+	}
+	if err := internal.RenameBranch(jitDir, "trunk", "bad..name", false); !errors.Is(err, internal.ErrInvalidRefName) {
+		t.Errorf("RenameBranch() to an invalid name = %v, want ErrInvalidRefName", err)
+	}
+}
+
+func TestCopyBranch(t *testing.T) {
+	jitDir := newBranchTestRepo(t)
+
+	if err := internal.CopyBranch(jitDir, "main", "backup", false); err != nil {
+		t.Fatalf("CopyBranch failed: %s", err)
+	}
+	if names, _ := internal.ListBranches(jitDir); !reflect.DeepEqual(names, []string{"backup", "feature/login", "main"}) {
+		t.Errorf("branches = %v, want [backup feature/login main]", names)
+	}
+	if branch, _, _ := internal.ReadHead(jitDir); branch != "main" {
+		t.Errorf("HEAD = %q, want main after a copy", branch)
+	}
+	config := loadBranchTestConfig(t, jitDir)
+	for _, branch := range []string{"main", "backup"} {
+		if merge, _ := config.Get("branch." + branch + ".merge"); merge != "refs/heads/main" {
+			t.Errorf("%s has no upstream after the copy", branch)
+		}
+	}
+
+	if err := internal.CopyBranch(jitDir, "feature/login", "backup", false); !errors.Is(err, internal.ErrBranchExists) {
+		t.Errorf("CopyBranch() onto an existing branch = %v, want ErrBranchExists", err)
+	}
+	if err := internal.CopyBranch(jitDir, "feature/login", "backup", true); err != nil {
+		t.Fatalf("CopyBranch() forced failed: %s", err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(jitDir, "branches", "backup")); string(content) != "c2" {
+		t.Errorf("backup = %q, want c2 after a forced copy", content)
+	}
+	if _, ok := loadBranchTestConfig(t, jitDir).Get("branch.backup.remote"); ok {
+		t.Errorf("backup kept the upstream of main after being replaced by feature/login")
+	}
+}
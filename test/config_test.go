@@ -0,0 +1,502 @@
+package test
+
+import (
+	"errors"
+	"jit/internal"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	tempDir, tempDirErr := os.MkdirTemp("", "config")
+	if tempDirErr != nil {
+		t.Fatalf("Failed to create temporary directory: %v", tempDirErr)
+	}
+	defer func(path string) {
+		_ = os.RemoveAll(path)
+	}(tempDir)
+
+	home := filepath.Join(tempDir, "home")
+	jitDir := filepath.Join(tempDir, "repo", ".jit")
+	t.Setenv("HOME", home)
+
+	writeTestFile(t, filepath.Join(home, ".jitconfig"), "# user settings\n[init]\n\tdefaultBranch = trunk\n[core]\n\teditor = vim\n\thook = global\n")
+	writeTestFile(t, filepath.Join(jitDir, "config"), "[Init]\ndefaultbranch = develop\n\n[core]\nhook=local\n")
+
+	config, err := internal.LoadConfig(jitDir)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	tests := []struct {
+		key      string
+		expected string
+		ok       bool
+	}{
+		{"init.defaultBranch", "develop", true},
+		{"CORE.EDITOR", "vim", true},
+		{"core.missing", "", false},
+	}
+	for _, tc := range tests {
+		value, ok := config.Get(tc.key)
+		if value != tc.expected || ok != tc.ok {
+			t.Errorf("Get(%q) = %q, %v; want %q, %v", tc.key, value, ok, tc.expected, tc.ok)
+		}
+	}
+	if values := config.GetAll("core.hook"); !reflect.DeepEqual(values, []string{"global", "local"}) {
+		t.Errorf("GetAll(core.hook) = %v, want [global local]", values)
+	}
+
+	outside, err := internal.LoadConfig("")
+	if err != nil {
+		t.Fatalf("LoadConfig outside a repository failed: %v", err)
+	}
+	if value, _ := outside.Get("init.defaultbranch"); value != "trunk" {
+		t.Errorf("Expected the global value outside a repository, got %q", value)
+	}
+}
+
+func TestReadConfigFileRejectsMalformedLines(t *testing.T) {
+	tempDir, tempDirErr := os.MkdirTemp("", "config")
+	if tempDirErr != nil {
+		t.Fatalf("Failed to create temporary directory: %v", tempDirErr)
+	}
+	defer func(path string) {
+		_ = os.RemoveAll(path)
+	}(tempDir)
+
+	file := filepath.Join(tempDir, "config")
+	malformed := []string{
+		"key = value\n",
+		"[core]\nnot a setting\n",
+		"[core\nkey = value\n",
+		"[core]\nkey = \"unterminated\n",
+		"[core]\nkey = bad \\q escape\n",
+	}
+	for _, content := range malformed {
+		writeTestFile(t, file, content)
+		if _, err := internal.ReadConfigFile(file, internal.ScopeLocal); err == nil {
+			t.Errorf("Expected an error for %q", content)
+		}
+	}
+
+	entries, err := internal.ReadConfigFile(filepath.Join(tempDir, "missing"), internal.ScopeLocal)
+	if err != nil || len(entries) != 0 {
+		t.Errorf("Expected no entries and no error for a missing file, got %v, %v", entries, err)
+	}
+}
+
+func TestWriteToConfigFileUpdatesExistingKeys(t *testing.T) {
+	tempDir, tempDirErr := os.MkdirTemp("", "config")
+	if tempDirErr != nil {
+		t.Fatalf("Failed to create temporary directory: %v", tempDirErr)
+	}
+	defer func(path string) {
+		_ = os.RemoveAll(path)
+	}(tempDir)
+
+	if _, err := internal.WriteToConfigFile(map[string]string{"init.templateDir": "/a", "core.objectFormat": "sha1"}, tempDir); err != nil {
+		t.Fatalf("WriteToConfigFile failed: %v", err)
+	}
+	if _, err := internal.WriteToConfigFile(map[string]string{"init.templateDir": "/b"}, tempDir); err != nil {
+		t.Fatalf("WriteToConfigFile failed: %v", err)
+	}
+
+	content, readErr := os.ReadFile(filepath.Join(tempDir, "config"))
+	if readErr != nil {
+		t.Fatalf("Failed to read config file: %v", readErr)
+	}
+	if string(content) != "[core]\n\tobjectFormat = sha1\n[init]\n\ttemplateDir = /b\n" {
+		t.Errorf("Unexpected config content %q", content)
+	}
+}
+
+func TestSetAndUnsetConfigValue(t *testing.T) {
+	tempDir, tempDirErr := os.MkdirTemp("", "config")
+	if tempDirErr != nil {
+		t.Fatalf("Failed to create temporary directory: %v", tempDirErr)
+	}
+	defer func(path string) {
+		_ = os.RemoveAll(path)
+	}(tempDir)
+
+	file := filepath.Join(tempDir, "nested", "config")
+	steps := []struct {
+		key   string
+		value string
+		add   bool
+	}{
+		{"user.name", "Ann", false},
+		{"USER.Name", "Bea", false},
+		{"remote.fetch", "a", true},
+		{"remote.fetch", "b", true},
+	}
+	for _, step := range steps {
+		if err := internal.SetConfigValue(file, step.key, step.value, step.add); err != nil {
+			t.Fatalf("SetConfigValue(%s) failed: %v", step.key, err)
+		}
+	}
+	if err := internal.SetConfigValue(file, "remote.fetch", "c", false); err == nil {
+		t.Error("Expected an error replacing a multi-valued key")
+	}
+
+	config, err := internal.LoadConfigFile(file, internal.ScopeLocal)
+	if err != nil {
+		t.Fatalf("LoadConfigFile failed: %v", err)
+	}
+	if value, _ := config.Get("user.name"); value != "Bea" {
+		t.Errorf("Expected user.name to be replaced, got %q", value)
+	}
+	if values := config.GetAll("remote.fetch"); !reflect.DeepEqual(values, []string{"a", "b"}) {
+		t.Errorf("GetAll(remote.fetch) = %v, want [a b]", values)
+	}
+
+	if _, err = internal.UnsetConfigValue(file, "remote.fetch", false); err == nil {
+		t.Error("Expected an error unsetting a multi-valued key without all")
+	}
+	removed, err := internal.UnsetConfigValue(file, "remote.fetch", true)
+	if err != nil || removed != 2 {
+		t.Errorf("UnsetConfigValue() = %d, %v; want 2, nil", removed, err)
+	}
+	if removed, _ = internal.UnsetConfigValue(file, "missing", false); removed != 0 {
+		t.Errorf("Expected nothing removed for a missing key, got %d", removed)
+	}
+}
+
+func TestParseConfigValues(t *testing.T) {
+	boolTests := []struct {
+		value    string
+		expected bool
+		wantErr  bool
+	}{
+		{"yes", true, false},
+		{"On", true, false},
+		{"0", false, false},
+		{"", false, false},
+		{"maybe", false, true},
+	}
+	for _, tc := range boolTests {
+		parsed, err := internal.ParseConfigBool(tc.value)
+		if parsed != tc.expected || (err != nil) != tc.wantErr {
+			t.Errorf("ParseConfigBool(%q) = %v, %v", tc.value, parsed, err)
+		}
+	}
+
+	intTests := []struct {
+		value    string
+		expected int64
+		wantErr  bool
+	}{
+		{"42", 42, false},
+		{"2k", 2048, false},
+		{"1M", 1 << 20, false},
+		{"-3", -3, false},
+		{"k", 0, true},
+		{"", 0, true},
+		{"8589934591g", 8589934591 << 30, false},
+		{"8589934592g", 0, true},
+		{"-8589934592g", -8589934592 << 30, false},
+		{"-8589934593g", 0, true},
+		{"9223372036854775808", 0, true},
+	}
+	for _, tc := range intTests {
+		parsed, err := internal.ParseConfigInt(tc.value)
+		if parsed != tc.expected || (err != nil) != tc.wantErr {
+			t.Errorf("ParseConfigInt(%q) = %d, %v", tc.value, parsed, err)
+		}
+		if err != nil && !strings.HasSuffix(err.Error(), "-> "+tc.value) {
+			t.Errorf("ParseConfigInt(%q) error = %q, want it to report the value as given", tc.value, err)
+		}
+	}
+}
+
+func TestFindJitDir(t *testing.T) {
+	tempDir, tempDirErr := os.MkdirTemp("", "repo")
+	if tempDirErr != nil {
+		t.Fatalf("Failed to create temporary directory: %v", tempDirErr)
+	}
+	defer func(path string) {
+		_ = os.RemoveAll(path)
+	}(tempDir)
+
+	nested := filepath.Join(tempDir, "src", "pkg")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatalf("Failed to create directories: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(tempDir, ".jit"), 0755); err != nil {
+		t.Fatalf("Failed to create .jit: %v", err)
+	}
+
+	jitDir, err := internal.FindJitDir(nested)
+	if err != nil {
+		t.Fatalf("FindJitDir failed: %v", err)
+	}
+	if jitDir != filepath.Join(tempDir, ".jit") {
+		t.Errorf("FindJitDir() = %s, want %s", jitDir, filepath.Join(tempDir, ".jit"))
+	}
+
+	outside, err := os.MkdirTemp("", "norepo")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer func(path string) {
+		_ = os.RemoveAll(path)
+	}(outside)
+	if _, err = internal.FindJitDir(outside); !errors.Is(err, internal.ErrNotARepository) {
+		t.Errorf("Expected ErrNotARepository, got %v", err)
+	}
+}
+
+func TestFindRepositoryThroughJitFile(t *testing.T) {
+	tempDir, tempDirErr := os.MkdirTemp("", "repo")
+	if tempDirErr != nil {
+		t.Fatalf("Failed to create temporary directory: %v", tempDirErr)
+	}
+	defer func(path string) {
+		_ = os.RemoveAll(path)
+	}(tempDir)
+
+	workTree := filepath.Join(tempDir, "work")
+	storage := filepath.Join(tempDir, "storage")
+	for _, dir := range []string{filepath.Join(workTree, "src"), storage} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directories: %v", err)
+		}
+	}
+	options := map[string]any{"quiet": true, "separate-jit-dir": storage}
+	if _, err := internal.InitializeJitRepository(options, workTree); err != nil {
+		t.Fatalf("InitializeJitRepository failed: %v", err)
+	}
+
+	info, statErr := os.Lstat(filepath.Join(workTree, ".jit"))
+	if statErr != nil || !info.Mode().IsRegular() {
+		t.Fatalf("Expected .jit to be a regular file (%v)", statErr)
+	}
+	jitDir, found, err := internal.FindRepository(filepath.Join(workTree, "src"))
+	if err != nil || jitDir != storage || found != workTree {
+		t.Errorf("FindRepository() = %s, %s, %v; want %s, %s", jitDir, found, err, storage, workTree)
+	}
+
+	// Running init again in the work tree re-initializes the separate repository.
+	if _, err := internal.InitializeJitRepository(map[string]any{"quiet": true}, workTree); err != nil {
+		t.Errorf("Re-init through the .jit file failed: %v", err)
+	}
+
+	// A relative path is relative to the directory holding the .jit file.
+	writeTestFile(t, filepath.Join(workTree, ".jit"), "jitdir: ../storage\n")
+	if jitDir, err := internal.FindJitDir(workTree); err != nil || jitDir != storage {
+		t.Errorf("FindJitDir() with a relative .jit file = %s, %v; want %s", jitDir, err, storage)
+	}
+
+	writeTestFile(t, filepath.Join(workTree, ".jit"), "jitdir: ../missing\n")
+	if _, err := internal.FindJitDir(workTree); !errors.Is(err, internal.ErrInvalidJitFile) {
+		t.Errorf("FindJitDir() with a dangling .jit file = %v, want ErrInvalidJitFile", err)
+	}
+}
+
+func TestConfigFileSyntax(t *testing.T) {
+	tempDir, tempDirErr := os.MkdirTemp("", "config")
+	if tempDirErr != nil {
+		t.Fatalf("Failed to create temporary directory: %v", tempDirErr)
+	}
+	defer func(path string) {
+		_ = os.RemoveAll(path)
+	}(tempDir)
+
+	file := filepath.Join(tempDir, "config")
+	writeTestFile(t, file, "; comment\n"+
+		"[core]\n"+
+		"\tbare\n"+
+		"\teditor = vim   -u  NONE # trailing comment\n"+
+		"\tpager = \" less \\\"-R\\\" ;\"\n"+
+		"\tlong = first \\\n\t\tsecond\n"+
+		"[remote \"Origin\"]\n"+
+		"\turl = https://example.com/repo.jit\n"+
+		"[branch.Main]\n"+
+		"\tremote = origin\n")
+
+	config, err := internal.LoadConfigFile(file, internal.ScopeLocal)
+	if err != nil {
+		t.Fatalf("LoadConfigFile failed: %v", err)
+	}
+
+	tests := []struct {
+		key      string
+		expected string
+		ok       bool
+	}{
+		{"core.bare", "true", true},
+		{"core.editor", "vim   -u  NONE", true},
+		{"core.pager", ` less "-R" ;`, true},
+		{"core.long", "first   second", true},
+		{"remote.Origin.url", "https://example.com/repo.jit", true},
+		{"remote.origin.url", "", false},
+		{"branch.main.remote", "origin", true},
+	}
+	for _, tc := range tests {
+		value, ok := config.Get(tc.key)
+		if value != tc.expected || ok != tc.ok {
+			t.Errorf("Get(%q) = %q, %v; want %q, %v", tc.key, value, ok, tc.expected, tc.ok)
+		}
+	}
+}
+
+func TestSetConfigValuePreservesFormatting(t *testing.T) {
+	tempDir, tempDirErr := os.MkdirTemp("", "config")
+	if tempDirErr != nil {
+		t.Fatalf("Failed to create temporary directory: %v", tempDirErr)
+	}
+	defer func(path string) {
+		_ = os.RemoveAll(path)
+	}(tempDir)
+
+	file := filepath.Join(tempDir, "config")
+	writeTestFile(t, file, "# My settings\n[core]\n    editor=vim # keep me\n    pager = less\n\n[user]\n\tname = Ann\n")
+
+	if err := internal.SetConfigValue(file, "core.editor", "nano", false); err != nil {
+		t.Fatalf("SetConfigValue failed: %v", err)
+	}
+	if err := internal.SetConfigValue(file, "core.autocrlf", "input", false); err != nil {
+		t.Fatalf("SetConfigValue failed: %v", err)
+	}
+	if err := internal.SetConfigValue(file, "remote.origin.url", "has # hash", false); err != nil {
+		t.Fatalf("SetConfigValue failed: %v", err)
+	}
+	if _, err := internal.UnsetConfigValue(file, "user.name", false); err != nil {
+		t.Fatalf("UnsetConfigValue failed: %v", err)
+	}
+
+	content, readErr := os.ReadFile(file)
+	if readErr != nil {
+		t.Fatalf("Failed to read config file: %v", readErr)
+	}
+	expected := "# My settings\n[core]\n\teditor = nano\n    pager = less\n\tautocrlf = input\n\n" +
+		"[remote \"origin\"]\n\turl = \"has # hash\"\n"
+	if string(content) != expected {
+		t.Errorf("Unexpected config content:\n%s\nwant\n%s", content, expected)
+	}
+
+	config, err := internal.LoadConfigFile(file, internal.ScopeLocal)
+	if err != nil {
+		t.Fatalf("LoadConfigFile failed: %v", err)
+	}
+	if value, _ := config.Get("remote.origin.url"); value != "has # hash" {
+		t.Errorf("Expected the quoted value to round-trip, got %q", value)
+	}
+}
+
+func TestSetConfigValueWithoutTrailingNewline(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config")
+	writeTestFile(t, file, "[core]\n\teditor = vim")
+
+	if err := internal.SetConfigValue(file, "core.pager", "less", false); err != nil {
+		t.Fatalf("SetConfigValue failed: %v", err)
+	}
+	content, readErr := os.ReadFile(file)
+	if readErr != nil {
+		t.Fatalf("Failed to read config file: %v", readErr)
+	}
+	if expected := "[core]\n\teditor = vim\n\tpager = less\n"; string(content) != expected {
+		t.Errorf("Unexpected config content %q, want %q", content, expected)
+	}
+}
+
+func TestSetConfigValueRejectsInvalidKeys(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config")
+	writeTestFile(t, file, "[core]\n\teditor = vim\n")
+
+	for _, key := range []string{"my section.key", "core_x.key", "core", "core.1key", "remote.a\nb.url"} {
+		if err := internal.SetConfigValue(file, key, "v", false); !errors.Is(err, internal.ErrInvalidConfigKey) {
+			t.Errorf("SetConfigValue(%q) error = %v, want ErrInvalidConfigKey", key, err)
+		}
+	}
+	if err := internal.SetConfigValue(file, "remote.my origin.url", "v", false); err != nil {
+		t.Errorf("SetConfigValue failed for a subsection with a space: %v", err)
+	}
+
+	config, err := internal.LoadConfigFile(file, internal.ScopeLocal)
+	if err != nil {
+		t.Fatalf("LoadConfigFile failed: %v", err)
+	}
+	if value, _ := config.Get("remote.my origin.url"); value != "v" {
+		t.Errorf("Get(remote.my origin.url) = %q, want %q", value, "v")
+	}
+}
+
+func TestUnsetConfigValueKeepsOtherSections(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config")
+	writeTestFile(t, file, "[alias]\n[user]\n\tname = Ann\n[core]\n# Nothing set yet\n[remote \"origin\"]\n")
+
+	if _, err := internal.UnsetConfigValue(file, "user.name", false); err != nil {
+		t.Fatalf("UnsetConfigValue failed: %v", err)
+	}
+	content, readErr := os.ReadFile(file)
+	if readErr != nil {
+		t.Fatalf("Failed to read config file: %v", readErr)
+	}
+	if expected := "[alias]\n[core]\n# Nothing set yet\n[remote \"origin\"]\n"; string(content) != expected {
+		t.Errorf("Unexpected config content %q, want %q", content, expected)
+	}
+}
+
+func TestDiscoverRepositoryEnvironment(t *testing.T) {
+	tempDir, tempDirErr := os.MkdirTemp("", "repo")
+	if tempDirErr != nil {
+		t.Fatalf("Failed to create temporary directory: %v", tempDirErr)
+	}
+	defer func(path string) {
+		_ = os.RemoveAll(path)
+	}(tempDir)
+
+	workTree := filepath.Join(tempDir, "project")
+	jitDir := filepath.Join(workTree, ".jit")
+	storage := filepath.Join(tempDir, "storage.jit")
+	for _, dir := range []string{filepath.Join(workTree, "src"), jitDir, storage} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+	}
+
+	tests := []struct {
+		name             string
+		envDir           string
+		envTree          string
+		cwd              string
+		expectedJitDir   string
+		expectedWorkTree string
+	}{
+		{"Search", "", "", filepath.Join(workTree, "src"), jitDir, workTree},
+		{"JIT_DIR", storage, "", workTree, storage, workTree},
+		{"JIT_DIR and JIT_WORK_TREE", storage, filepath.Join(workTree, "src"), tempDir, storage, filepath.Join(workTree, "src")},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("JIT_DIR", tc.envDir)
+			t.Setenv("JIT_WORK_TREE", tc.envTree)
+			gotDir, gotTree, err := internal.DiscoverRepository(tc.cwd)
+			if err != nil {
+				t.Fatalf("DiscoverRepository failed: %v", err)
+			}
+			if gotDir != tc.expectedJitDir || gotTree != tc.expectedWorkTree {
+				t.Errorf("DiscoverRepository() = %s, %s; want %s, %s", gotDir, gotTree, tc.expectedJitDir, tc.expectedWorkTree)
+			}
+		})
+	}
+
+	t.Setenv("JIT_DIR", filepath.Join(tempDir, "missing"))
+	if _, _, err := internal.DiscoverRepository(workTree); !errors.Is(err, internal.ErrNotARepository) {
+		t.Errorf("Expected ErrNotARepository for a missing JIT_DIR, got %v", err)
+	}
+}
+
+func TestGlobalConfigOverride(t *testing.T) {
+	t.Setenv("JIT_CONFIG_GLOBAL", "/tmp/custom.jitconfig")
+	path, err := internal.ConfigPath(internal.ScopeGlobal, "")
+	if err != nil || path != "/tmp/custom.jitconfig" {
+		t.Errorf("ConfigPath(global) = %s, %v; want /tmp/custom.jitconfig", path, err)
+	}
+}
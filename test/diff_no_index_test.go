@@ -0,0 +1,377 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"jit/cmd"
+	"jit/internal"
+	"jit/internal/diff"
+	"jit/pkg/util"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newNoIndexDirs creates two directories, old and new, to compare.
+func newNoIndexDirs(t *testing.T) (dir string) {
+	dir, err := os.MkdirTemp("", "diff_no_index")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	t.Setenv(util.EnvConfigGlobal, filepath.Join(dir, "gitconfig"))
+
+	writeTestFile(t, filepath.Join(dir, "old", "same.txt"), "same\n")
+	writeTestFile(t, filepath.Join(dir, "new", "same.txt"), "same\n")
+	writeTestFile(t, filepath.Join(dir, "old", "changed.txt"), "one\ntwo\nthree\n")
+	writeTestFile(t, filepath.Join(dir, "new", "changed.txt"), "one\n2\nthree\n")
+	writeTestFile(t, filepath.Join(dir, "old", "deleted.txt"), "gone\n")
+	writeTestFile(t, filepath.Join(dir, "new", "sub", "added.txt"), "new\n")
+	return dir
+}
+
+func runDiff(t *testing.T, dir string, args ...string) (code int, stdout string) {
+	t.Helper()
+	var out, stderr bytes.Buffer
+	code = cmd.Run(append([]string{"-C", dir, "diff"}, args...), strings.NewReader(""), &out, &stderr)
+	return code, out.String()
+}
+
+func TestNoIndexPairsDirectories(t *testing.T) {
+	dir := newNoIndexDirs(t)
+
+	pairs, err := internal.NoIndexPairs(context.Background(), "", filepath.Join(dir, "old"), filepath.Join(dir, "new"))
+	if err != nil {
+		t.Fatalf("NoIndexPairs failed: %s", err)
+	}
+	if len(pairs) != 4 {
+		t.Fatalf("got %d pairs, want 4: %+v", len(pairs), pairs)
+	}
+	if deleted := pairs[1]; deleted.Old.Mode != internal.ModeRegular || deleted.New.Mode != 0 {
+		t.Errorf("deleted.txt pair = %+v, want a file on the old side only", deleted)
+	}
+	if added := pairs[3]; added.Old.Mode != 0 || !strings.HasSuffix(added.New.Path, "new/sub/added.txt") {
+		t.Errorf("sub/added.txt pair = %+v, want a file on the new side only", added)
+	}
+}
+
+func TestWriteDiff(t *testing.T) {
+	pairs := []internal.DiffPair{
+		{Old: internal.DiffFile{Path: "a.txt", Content: []byte("x\n"), Mode: internal.ModeRegular}, New: internal.DiffFile{Path: "a.txt", Content: []byte("x\n"), Mode: internal.ModeExecutable}},
+		{Old: internal.DiffFile{Path: "b.bin", Content: []byte("\x00a"), Mode: internal.ModeRegular}, New: internal.DiffFile{Path: "b.bin", Content: []byte("\x00b"), Mode: internal.ModeRegular}},
+		{New: internal.DiffFile{Path: "c.txt", Content: []byte("c\n"), Mode: internal.ModeRegular}},
+		{Old: internal.DiffFile{Path: "same.txt", Content: []byte("s\n"), Mode: internal.ModeRegular}, New: internal.DiffFile{Path: "same.txt", Content: []byte("s\n"), Mode: internal.ModeRegular}},
+	}
+
+	var out bytes.Buffer
+	changed, err := internal.WriteDiff(&out, pairs, internal.DiffOptions{Context: diff.DefaultContext})
+	if err != nil {
+		t.Fatalf("WriteDiff failed: %s", err)
+	}
+	if !changed {
+		t.Error("WriteDiff reported no change")
+	}
+	for _, want := range []string{
+		"diff --git a/a.txt b/a.txt\nold mode 100644\nnew mode 100755\n",
+		"diff --git a/b.bin b/b.bin\nBinary files a/b.bin and b/b.bin differ\n",
+		"diff --git a/c.txt b/c.txt\nnew file mode 100644\n--- /dev/null\n+++ b/c.txt\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("diff does not contain %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "same.txt") {
+		t.Errorf("diff shows an unchanged file:\n%s", out.String())
+	}
+}
+
+func TestDiffNoIndexCommand(t *testing.T) {
+	dir := newNoIndexDirs(t)
+
+	code, stdout := runDiff(t, dir, "--no-index", "old", "new")
+	if code != 1 {
+		t.Fatalf("jit diff --no-index old new = %d, want 1", code)
+	}
+	for _, want := range []string{
+		"diff --git a/old/changed.txt b/new/changed.txt\n--- a/old/changed.txt\n+++ b/new/changed.txt\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n",
+		"diff --git a/old/deleted.txt b/old/deleted.txt\ndeleted file mode 100644\n",
+		"diff --git a/new/sub/added.txt b/new/sub/added.txt\nnew file mode 100644\n",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("diff does not contain %q:\n%s", want, stdout)
+		}
+	}
+
+	if code, stdout := runDiff(t, dir, "--no-index", "-U0", "old/changed.txt", "new/changed.txt"); code != 1 || !strings.Contains(stdout, "@@ -2 +2 @@\n-two\n+2\n") {
+		t.Errorf("jit diff -U0 = %d:\n%s", code, stdout)
+	}
+	if code, stdout := runDiff(t, dir, "--no-index", "old/same.txt", "new"); code != 0 || stdout != "" {
+		t.Errorf("jit diff of a file and a directory holding the same file = %d, %q; want 0 and no output", code, stdout)
+	}
+}
+
+func TestDiffNoIndexOptionsAfterPaths(t *testing.T) {
+	dir := newNoIndexDirs(t)
+	writeTestFile(t, filepath.Join(dir, "-U0"), "one\n2\nthree\n")
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"flag", []string{"--no-index", "old", "new", "--shortstat"}, " 3 files changed, 2 insertions(+), 2 deletions(-)\n"},
+		{"option and its value", []string{"old/changed.txt", "--no-index", "new/changed.txt", "-U", "0"}, "@@ -2 +2 @@\n-two\n+2\n"},
+		{"path after --", []string{"--no-index", "-U1", "--", "old/changed.txt", "-U0"}, "@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if code, stdout := runDiff(t, dir, tc.args...); code != 1 || !strings.HasSuffix(stdout, tc.want) {
+				t.Errorf("jit diff %v = %d:\n%s\nwant\n%s", tc.args, code, stdout, tc.want)
+			}
+		})
+	}
+}
+
+func TestDiffNoIndexCommandErrors(t *testing.T) {
+	dir := newNoIndexDirs(t)
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"without --no-index", []string{"old", "new"}, 2},
+		{"one path", []string{"--no-index", "old"}, 2},
+		{"negative context", []string{"--no-index", "-U", "-1", "old", "new"}, 2},
+		{"two algorithms", []string{"--no-index", "--patience", "--histogram", "old", "new"}, 2},
+		{"unknown algorithm", []string{"--no-index", "--diff-algorithm=fast", "old", "new"}, 2},
+		{"missing path", []string{"--no-index", "old", "missing"}, 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if code, _ := runDiff(t, dir, tc.args...); code != tc.code {
+				t.Errorf("jit diff %v = %d, want %d", tc.args, code, tc.code)
+			}
+		})
+	}
+}
+
+func TestDiffNoIndexReadsAlgorithmFromConfig(t *testing.T) {
+	dir := newNoIndexDirs(t)
+
+	writeTestFile(t, filepath.Join(dir, "gitconfig"), "[diff]\n\talgorithm = fast\n")
+	if code, _ := runDiff(t, dir, "--no-index", "old", "new"); code != 1 {
+		t.Errorf("jit diff with an invalid diff.algorithm = %d, want 1", code)
+	}
+	if code, _ := runDiff(t, dir, "--no-index", "--histogram", "old", "new"); code != 1 {
+		t.Errorf("jit diff --histogram with an invalid diff.algorithm = %d, want 1", code)
+	}
+
+	writeTestFile(t, filepath.Join(dir, "gitconfig"), "[diff]\n\talgorithm = patience\n")
+	if code, stdout := runDiff(t, dir, "--no-index", "old/changed.txt", "new/changed.txt"); code != 1 || !strings.Contains(stdout, "-two\n+2\n") {
+		t.Errorf("jit diff with diff.algorithm = patience = %d:\n%s", code, stdout)
+	}
+}
+
+func TestDiffNoIndexFindRenames(t *testing.T) {
+	dir := newNoIndexDirs(t)
+	writeTestFile(t, filepath.Join(dir, "old", "notes.txt"), "a\nb\nc\nd\n")
+	writeTestFile(t, filepath.Join(dir, "new", "moved", "notes.txt"), "a\nb\nc\nd\n")
+	writeTestFile(t, filepath.Join(dir, "old", "list.txt"), "one\ntwo\nthree\nfour\n")
+	writeTestFile(t, filepath.Join(dir, "new", "items.txt"), "one\ntwo\nthree\nfive\n")
+
+	code, stdout := runDiff(t, dir, "--no-index", "-M", "old", "new")
+	if code != 1 {
+		t.Fatalf("jit diff -M = %d, want 1", code)
+	}
+	for _, want := range []string{
+		"diff --git a/old/notes.txt b/new/moved/notes.txt\nsimilarity index 100%\nrename from old/notes.txt\nrename to new/moved/notes.txt\n",
+		"diff --git a/old/list.txt b/new/items.txt\nsimilarity index 73%\nrename from old/list.txt\nrename to new/items.txt\n--- a/old/list.txt\n+++ b/new/items.txt\n",
+		"deleted file mode 100644\n--- a/old/deleted.txt\n",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("diff does not contain %q:\n%s", want, stdout)
+		}
+	}
+	if strings.Contains(stdout, "deleted file mode 100644\n--- a/old/notes.txt") {
+		t.Errorf("a renamed file is also shown as deleted:\n%s", stdout)
+	}
+
+	if _, stdout := runDiff(t, dir, "--no-index", "-M80%", "old", "new"); !strings.Contains(stdout, "rename to new/moved/notes.txt") || strings.Contains(stdout, "rename to new/items.txt") {
+		t.Errorf("jit diff -M80%% renamed the wrong files:\n%s", stdout)
+	}
+	if _, stdout := runDiff(t, dir, "--no-index", "old", "new"); strings.Contains(stdout, "rename") {
+		t.Errorf("jit diff without -M detected renames:\n%s", stdout)
+	}
+	if code, _ := runDiff(t, dir, "--no-index", "--find-renames=200%", "old", "new"); code != 2 {
+		t.Errorf("jit diff --find-renames=200%% = %d, want 2", code)
+	}
+}
+
+func TestDiffNoIndexFindCopies(t *testing.T) {
+	dir := newNoIndexDirs(t)
+	writeTestFile(t, filepath.Join(dir, "new", "copy.txt"), "one\ntwo\nthree\n")
+	writeTestFile(t, filepath.Join(dir, "old", "notes.txt"), "a\nb\nc\nd\n")
+	writeTestFile(t, filepath.Join(dir, "new", "moved.txt"), "a\nb\nc\nd\n")
+	writeTestFile(t, filepath.Join(dir, "new", "unrelated.txt"), "x\ny\n")
+
+	code, stdout := runDiff(t, dir, "--no-index", "-C", "old", "new")
+	if code != 1 {
+		t.Fatalf("jit diff -C = %d, want 1", code)
+	}
+	for _, want := range []string{
+		"diff --git a/old/changed.txt b/new/copy.txt\nsimilarity index 100%\ncopy from old/changed.txt\ncopy to new/copy.txt\n",
+		"diff --git a/old/changed.txt b/new/changed.txt\n--- a/old/changed.txt\n",
+		"rename from old/notes.txt\nrename to new/moved.txt\n",
+		"diff --git a/new/unrelated.txt b/new/unrelated.txt\nnew file mode 100644\n",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("diff does not contain %q:\n%s", want, stdout)
+		}
+	}
+
+	if _, stdout := runDiff(t, dir, "--no-index", "-M", "old", "new"); strings.Contains(stdout, "copy from") {
+		t.Errorf("jit diff -M detected copies:\n%s", stdout)
+	}
+	if code, _ := runDiff(t, dir, "--no-index", "-Cx", "old", "new"); code != 2 {
+		t.Errorf("jit diff -Cx = %d, want 2", code)
+	}
+}
+
+func TestDiffNoIndexWordDiff(t *testing.T) {
+	dir := newNoIndexDirs(t)
+	writeTestFile(t, filepath.Join(dir, "old.txt"), "the quick brown fox\n")
+	writeTestFile(t, filepath.Join(dir, "new.txt"), "the slow brown fox\n")
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"plain by default", []string{"--word-diff"}, "@@ -1 +1 @@\nthe [-quick-]{+slow+} brown fox\n"},
+		{"plain", []string{"--word-diff=plain"}, "the [-quick-]{+slow+} brown fox\n"},
+		{"color", []string{"--word-diff=color"}, "the \x1b[31mquick\x1b[m\x1b[32mslow\x1b[m brown fox\n"},
+		{"color words", []string{"--color-words"}, "the \x1b[31mquick\x1b[m\x1b[32mslow\x1b[m brown fox\n"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			args := append(append([]string{"--no-index"}, tc.args...), "old.txt", "new.txt")
+			if code, stdout := runDiff(t, dir, args...); code != 1 || !strings.Contains(stdout, tc.want) {
+				t.Errorf("jit diff %v = %d, want %q in:\n%s", tc.args, code, tc.want, stdout)
+			}
+		})
+	}
+
+	if code, _ := runDiff(t, dir, "--no-index", "--word-diff=porcelain", "old.txt", "new.txt"); code != 2 {
+		t.Errorf("jit diff --word-diff=porcelain = %d, want 2", code)
+	}
+	if code, _ := runDiff(t, dir, "--no-index", "--word-diff", "--color-words", "old.txt", "new.txt"); code != 2 {
+		t.Errorf("jit diff --word-diff --color-words = %d, want 2", code)
+	}
+}
+
+func TestDiffNoIndexIgnoresWhitespace(t *testing.T) {
+	dir := newNoIndexDirs(t)
+	writeTestFile(t, filepath.Join(dir, "old.txt"), "if x {\n\treturn  1\n}\n")
+	writeTestFile(t, filepath.Join(dir, "new.txt"), "if x {\n    return 1 \n\n}\n")
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"whitespace counts by default", nil, 1},
+		{"-b keeps the blank line", []string{"-b"}, 1},
+		{"-b and --ignore-blank-lines", []string{"-b", "--ignore-blank-lines"}, 0},
+		{"-w and --ignore-blank-lines", []string{"--ignore-all-space", "--ignore-blank-lines"}, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			args := append(append([]string{"--no-index"}, tc.args...), "old.txt", "new.txt")
+			code, stdout := runDiff(t, dir, args...)
+			if code != tc.code {
+				t.Errorf("jit diff %v = %d, want %d:\n%s", tc.args, code, tc.code, stdout)
+			}
+			if code == 0 && stdout != "" {
+				t.Errorf("jit diff %v printed a diff with nothing to show:\n%s", tc.args, stdout)
+			}
+		})
+	}
+
+	if _, stdout := runDiff(t, dir, "--no-index", "-w", "old.txt", "new.txt"); !strings.Contains(stdout, "@@ -1,3 +1,4 @@\n if x {\n \treturn  1\n+\n }\n") {
+		t.Errorf("jit diff -w does not show only the blank line:\n%s", stdout)
+	}
+}
+
+func TestDiffNoIndexStat(t *testing.T) {
+	dir := newNoIndexDirs(t)
+	writeTestFile(t, filepath.Join(dir, "old", "image.bin"), "\x00\x01")
+	writeTestFile(t, filepath.Join(dir, "new", "image.bin"), "\x00\x02\x03")
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"numstat", []string{"--numstat"}, "1\t1\t{old => new}/changed.txt\n0\t1\told/deleted.txt\n-\t-\t{old => new}/image.bin\n1\t0\tnew/sub/added.txt\n"},
+		{"shortstat", []string{"--shortstat"}, " 4 files changed, 2 insertions(+), 2 deletions(-)\n"},
+		{"stat", []string{"--stat"}, "" +
+			" {old => new}/changed.txt |   2 +-\n" +
+			" old/deleted.txt          |   1 -\n" +
+			" {old => new}/image.bin   | Bin 2 -> 3 bytes\n" +
+			" new/sub/added.txt        |   1 +\n" +
+			" 4 files changed, 2 insertions(+), 2 deletions(-)\n"},
+		{"stat and shortstat", []string{"--stat", "--shortstat"}, "" +
+			" new/sub/added.txt        |   1 +\n" +
+			" 4 files changed, 2 insertions(+), 2 deletions(-)\n"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			args := append(append([]string{"--no-index"}, tc.args...), "old", "new")
+			code, stdout := runDiff(t, dir, args...)
+			if code != 1 {
+				t.Errorf("jit diff %v = %d, want 1", tc.args, code)
+			}
+			if !strings.HasSuffix(stdout, tc.want) || strings.Contains(stdout, "@@") {
+				t.Errorf("jit diff %v =\n%s\nwant\n%s", tc.args, stdout, tc.want)
+			}
+		})
+	}
+
+	if code, stdout := runDiff(t, dir, "--no-index", "--stat", "old/changed.txt", "new/changed.txt"); code != 1 || !strings.HasPrefix(stdout, " {old => new}/changed.txt |   2 +-\n") {
+		t.Errorf("jit diff --stat of two files = %d:\n%s", code, stdout)
+	}
+	if _, stdout := runDiff(t, dir, "--no-index", "--numstat", "old/deleted.txt", "new/sub/added.txt"); stdout != "1\t1\told/deleted.txt => new/sub/added.txt\n" {
+		t.Errorf("jit diff --numstat does not show the paths of both sides:\n%s", stdout)
+	}
+	if _, stdout := runDiff(t, dir, "--no-index", "--numstat", "-z", "old/deleted.txt", "new/sub/added.txt"); stdout != "1\t1\t\x00old/deleted.txt\x00new/sub/added.txt\x00" {
+		t.Errorf("jit diff --numstat -z = %q", stdout)
+	}
+}
+
+func TestDiffNoIndexBinary(t *testing.T) {
+	dir := newNoIndexDirs(t)
+	writeTestFile(t, filepath.Join(dir, "old.bin"), "\x89PNG\x00\x01")
+	writeTestFile(t, filepath.Join(dir, "new.bin"), "\x89PNG\x00\x02\x03")
+
+	if _, stdout := runDiff(t, dir, "--no-index", "old.bin", "new.bin"); !strings.HasSuffix(stdout, "Binary files a/old.bin and b/new.bin differ\n") {
+		t.Errorf("jit diff without --binary =\n%s", stdout)
+	}
+
+	code, stdout := runDiff(t, dir, "--no-index", "--binary", "old.bin", "new.bin")
+	if code != 1 {
+		t.Fatalf("jit diff --binary = %d, want 1", code)
+	}
+	_, patch, found := strings.Cut(stdout, "diff --git a/old.bin b/new.bin\n")
+	if !found || !strings.HasPrefix(patch, "GIT binary patch\nliteral 7\n") {
+		t.Fatalf("jit diff --binary =\n%s", stdout)
+	}
+	newContent, oldContent, parseErr := diff.ParseBinaryPatch(patch)
+	if parseErr != nil {
+		t.Fatalf("ParseBinaryPatch failed: %s", parseErr)
+	}
+	if string(oldContent) != "\x89PNG\x00\x01" || string(newContent) != "\x89PNG\x00\x02\x03" {
+		t.Errorf("the patch of jit diff --binary decodes to %q and %q", oldContent, newContent)
+	}
+}
@@ -0,0 +1,309 @@
+package test
+
+import (
+	"jit/internal/diff"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// lcsLength computes the length of the longest common subsequence with dynamic programming,
+// used to check that the diff algorithms produce minimal edit scripts.
+func lcsLength(a []string, b []string) int {
+	table := make([][]int, len(a)+1)
+	for i := range table {
+		table[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else {
+				table[i][j] = max(table[i+1][j], table[i][j+1])
+			}
+		}
+	}
+	return table[0][0]
+}
+
+// checkEditScript verifies that an edit script reproduces both inputs and returns its number of equal lines.
+func checkEditScript(t *testing.T, a []string, b []string, edits []diff.Edit) int {
+	t.Helper()
+	var oldLines, newLines []string
+	equal := 0
+	for _, edit := range edits {
+		switch edit.Kind {
+		case diff.Equal:
+			oldLines = append(oldLines, edit.Text)
+			newLines = append(newLines, edit.Text)
+			equal++
+		case diff.Delete:
+			oldLines = append(oldLines, edit.Text)
+		case diff.Insert:
+			newLines = append(newLines, edit.Text)
+		}
+	}
+	if strings.Join(oldLines, "") != strings.Join(a, "") {
+		t.Fatalf("Edit script does not reproduce the old version: %v", edits)
+	}
+	if strings.Join(newLines, "") != strings.Join(b, "") {
+		t.Fatalf("Edit script does not reproduce the new version: %v", edits)
+	}
+	return equal
+}
+
+func randomLines(r *rand.Rand, n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = string(rune('a'+r.Intn(4))) + "\n"
+	}
+	return lines
+}
+
+func TestMyersIsMinimal(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		a := randomLines(r, r.Intn(20))
+		b := randomLines(r, r.Intn(20))
+		edits := diff.Myers(a, b)
+		if equal := checkEditScript(t, a, b, edits); equal != lcsLength(a, b) {
+			t.Fatalf("Myers(%q, %q) kept %d lines, want %d", a, b, equal, lcsLength(a, b))
+		}
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	oldContent := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n"
+	newContent := "one\n2\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven"
+
+	expected := "" +
+		"--- a/numbers.txt\n" +
+		"+++ b/numbers.txt\n" +
+		"@@ -1,5 +1,5 @@\n" +
+		" one\n" +
+		"-two\n" +
+		"+2\n" +
+		" three\n" +
+		" four\n" +
+		" five\n" +
+		"@@ -8,3 +8,4 @@\n" +
+		" eight\n" +
+		" nine\n" +
+		" ten\n" +
+		"+eleven\n" +
+		"\\ No newline at end of file\n"
+
+	got := diff.Unified("a/numbers.txt", "b/numbers.txt", diff.Lines(oldContent, newContent), diff.DefaultContext)
+	if got != expected {
+		t.Errorf("Unified() =\n%s\nwant\n%s", got, expected)
+	}
+}
+
+func TestMakeHunks(t *testing.T) {
+	tests := []struct {
+		name     string
+		old      string
+		new      string
+		context  int
+		expected []string
+	}{
+		{"No Changes", "a\nb\n", "a\nb\n", 3, nil},
+		{"New File", "", "a\nb\n", 3, []string{"@@ -0,0 +1,2 @@"}},
+		{"Deleted File", "a\nb\n", "", 3, []string{"@@ -1,2 +0,0 @@"}},
+		{"Merged Hunks", "1\n2\n3\n4\n5\n6\n7\n", "1\nx\n3\n4\n5\n6\ny\n", 2, []string{"@@ -1,7 +1,7 @@"}},
+		{"Split Hunks", "1\n2\n3\n4\n5\n6\n7\n", "1\nx\n3\n4\n5\n6\ny\n", 1, []string{"@@ -1,3 +1,3 @@", "@@ -6,2 +6,2 @@"}},
+		{"Zero Context", "1\n2\n3\n", "1\n3\n", 0, []string{"@@ -2 +1,0 @@"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			hunks := diff.MakeHunks(diff.Lines(tc.old, tc.new), tc.context)
+			var headers []string
+			for _, hunk := range hunks {
+				headers = append(headers, hunk.Header())
+			}
+			if strings.Join(headers, "|") != strings.Join(tc.expected, "|") {
+				t.Errorf("MakeHunks() headers = %v, want %v", headers, tc.expected)
+			}
+		})
+	}
+}
+
+func TestAlternativeAlgorithmsProduceValidScripts(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for _, algorithm := range []diff.Algorithm{diff.AlgorithmMinimal, diff.AlgorithmPatience, diff.AlgorithmHistogram} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			for i := 0; i < 300; i++ {
+				a := randomLines(r, r.Intn(25))
+				b := randomLines(r, r.Intn(25))
+				checkEditScript(t, a, b, diff.Compute(algorithm, a, b))
+			}
+		})
+	}
+}
+
+func TestPatienceAnchorsOnUniqueLines(t *testing.T) {
+	// Myers matches the braces of the moved function; patience anchors on the unique signatures.
+	oldContent := "func a() {\n\treturn 1\n}\n\nfunc b() {\n\treturn 2\n}\n"
+	newContent := "func b() {\n\treturn 2\n}\n\nfunc a() {\n\treturn 1\n}\n"
+
+	for _, algorithm := range []diff.Algorithm{diff.AlgorithmPatience, diff.AlgorithmHistogram} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			a, b := diff.SplitLines(oldContent), diff.SplitLines(newContent)
+			edits := diff.Compute(algorithm, a, b)
+			checkEditScript(t, a, b, edits)
+
+			// The whole of one function must be kept intact as unchanged lines.
+			var kept []string
+			for _, edit := range edits {
+				if edit.Kind == diff.Equal {
+					kept = append(kept, edit.Text)
+				}
+			}
+			if !strings.Contains(strings.Join(kept, ""), "func a() {\n\treturn 1\n}\n") &&
+				!strings.Contains(strings.Join(kept, ""), "func b() {\n\treturn 2\n}\n") {
+				t.Errorf("Expected one function to be kept intact, kept %q", kept)
+			}
+		})
+	}
+}
+
+func TestParseAlgorithm(t *testing.T) {
+	tests := []struct {
+		name     string
+		wantErr  bool
+		expected diff.Algorithm
+	}{
+		{"", false, diff.AlgorithmMyers},
+		{"default", false, diff.AlgorithmMyers},
+		{"Histogram", false, diff.AlgorithmHistogram},
+		{"patience", false, diff.AlgorithmPatience},
+		{"minimal", false, diff.AlgorithmMinimal},
+		{"quantum", true, ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			algorithm, err := diff.ParseAlgorithm(tc.name)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseAlgorithm(%q) error = %v, wantErr %v", tc.name, err, tc.wantErr)
+			}
+			if algorithm != tc.expected {
+				t.Errorf("ParseAlgorithm(%q) = %s, want %s", tc.name, algorithm, tc.expected)
+			}
+		})
+	}
+}
+
+func TestWordDiff(t *testing.T) {
+	oldContent := "The quick brown fox\njumps over\nthe lazy dog\n"
+	newContent := "The quick red fox\njumps over\nthe lazy  cat today\n"
+	edits := diff.Lines(oldContent, newContent)
+
+	expected := "" +
+		"--- a/story.txt\n" +
+		"+++ b/story.txt\n" +
+		"@@ -1,3 +1,3 @@\n" +
+		"The quick [-brown-]{+red+} fox\n" +
+		"jumps over\n" +
+		"the lazy  [-dog-]{+cat today+}\n"
+
+	got := diff.WordDiff("a/story.txt", "b/story.txt", edits, diff.WordDiffPlain, diff.DefaultContext)
+	if got != expected {
+		t.Errorf("WordDiff() =\n%q\nwant\n%q", got, expected)
+	}
+
+	colored := diff.WordDiff("a/story.txt", "b/story.txt", edits, diff.WordDiffColor, diff.DefaultContext)
+	if !strings.Contains(colored, "The quick \x1b[31mbrown\x1b[m\x1b[32mred\x1b[m fox\n") {
+		t.Errorf("Expected colored word changes, got %q", colored)
+	}
+}
+
+func TestWordDiffAddedAndRemovedLines(t *testing.T) {
+	edits := diff.Lines("keep\ndrop this line\n", "keep\nbrand new line\nand another\n")
+	expected := "" +
+		"--- a/f\n" +
+		"+++ b/f\n" +
+		"@@ -1,2 +1,3 @@\n" +
+		"keep\n" +
+		"[-drop this-]{+brand new+} line\n" +
+		"{+and another+}\n"
+
+	if got := diff.WordDiff("a/f", "b/f", edits, diff.WordDiffPlain, diff.DefaultContext); got != expected {
+		t.Errorf("WordDiff() =\n%q\nwant\n%q", got, expected)
+	}
+}
+
+func TestWordDiffDeletions(t *testing.T) {
+	tests := []struct {
+		name     string
+		old      string
+		new      string
+		expected string
+	}{
+		{"Inner Word", "a b c\n", "a c\n", "a [-b-] c\n"},
+		{"Last Word", "a b\n", "a\n", "a [-b-]\n"},
+		{"Whole Line", "keep\ndrop this line\n", "keep\n", "keep\n[-drop this line-]\n"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := diff.WordDiff("a/f", "b/f", diff.Lines(tc.old, tc.new), diff.WordDiffPlain, diff.DefaultContext)
+			body := got[strings.Index(got, "@@ -"):]
+			body = body[strings.Index(body, "\n")+1:]
+			if body != tc.expected {
+				t.Errorf("WordDiff() body = %q, want %q", body, tc.expected)
+			}
+		})
+	}
+}
+
+func TestCompareLinesWhitespaceOptions(t *testing.T) {
+	oldContent := "func main() {\n\tx := 1\n\n\treturn\n}\n"
+	newContent := "func main()  {\n    x:=   1\n\treturn  \n}\n"
+
+	tests := []struct {
+		name     string
+		ws       diff.WhitespaceOptions
+		expected string
+	}{
+		{
+			name:     "Ignore all space",
+			ws:       diff.WhitespaceOptions{IgnoreAllSpace: true},
+			expected: "--- a\n+++ b\n@@ -1,5 +1,4 @@\n func main() {\n \tx := 1\n-\n \treturn\n }\n",
+		},
+		{
+			name:     "Ignore space change",
+			ws:       diff.WhitespaceOptions{IgnoreSpaceChange: true},
+			expected: "--- a\n+++ b\n@@ -1,5 +1,4 @@\n func main() {\n-\tx := 1\n-\n+    x:=   1\n \treturn\n }\n",
+		},
+		{
+			name:     "Ignore all space and blank lines",
+			ws:       diff.WhitespaceOptions{IgnoreAllSpace: true, IgnoreBlankLines: true},
+			expected: "",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			edits := diff.CompareLines(diff.AlgorithmMyers, diff.SplitLines(oldContent), diff.SplitLines(newContent), tc.ws)
+			if got := diff.Unified("a", "b", edits, diff.DefaultContext); got != tc.expected {
+				t.Errorf("Unified() =\n%s\nwant\n%s", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestIgnoreBlankLinesKeepsOtherChanges(t *testing.T) {
+	oldContent := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	newContent := "a\n\nb\nc\nd\ne\nf\ng\nh\ni\nJ\n"
+
+	edits := diff.CompareLines(diff.AlgorithmMyers, diff.SplitLines(oldContent), diff.SplitLines(newContent), diff.WhitespaceOptions{IgnoreBlankLines: true})
+	hunks := diff.MakeHunks(edits, 1)
+	if len(hunks) != 1 {
+		t.Fatalf("Expected only the non-blank change to produce a hunk, got %d hunks", len(hunks))
+	}
+	if header := hunks[0].Header(); header != "@@ -9,2 +10,2 @@" {
+		t.Errorf("Unexpected hunk header %s", header)
+	}
+}
@@ -0,0 +1,127 @@
+package test
+
+import (
+	"jit/internal"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestParseFileMode(t *testing.T) {
+	tests := []struct {
+		mode     string
+		wantErr  bool
+		expected internal.FileMode
+	}{
+		{"100644", false, internal.ModeRegular},
+		{"100755", false, internal.ModeExecutable},
+		{"100600", true, 0},
+		{"garbage", true, 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.mode, func(t *testing.T) {
+			mode, err := internal.ParseFileMode(tc.mode)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseFileMode(%q) error = %v, wantErr %v", tc.mode, err, tc.wantErr)
+			}
+			if !tc.wantErr && (mode != tc.expected || mode.String() != tc.mode) {
+				t.Errorf("ParseFileMode(%q) = %s, want %s", tc.mode, mode, tc.expected)
+			}
+		})
+	}
+}
+
+func TestFileModeRoundTrip(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the executable bit is not represented on windows")
+	}
+
+	tempDir, tempDirErr := os.MkdirTemp("", "filemode")
+	if tempDirErr != nil {
+		t.Fatalf("Failed to create temporary directory: %v", tempDirErr)
+	}
+	defer func(path string) {
+		_ = os.RemoveAll(path)
+	}(tempDir)
+
+	script := filepath.Join(tempDir, "run.sh")
+	if writeErr := os.WriteFile(script, []byte("#!/bin/sh\n"), 0644); writeErr != nil {
+		t.Fatalf("Failed to write file: %v", writeErr)
+	}
+
+	for _, tc := range []struct {
+		perm os.FileMode
+		want internal.FileMode
+	}{
+		{0755, internal.ModeExecutable},
+		{0744, internal.ModeExecutable},
+		{0654, internal.ModeRegular},
+		{0644, internal.ModeRegular},
+	} {
+		if err := os.Chmod(script, tc.perm); err != nil {
+			t.Fatalf("Chmod failed: %v", err)
+		}
+		info, _ := os.Stat(script)
+		if mode := internal.FileModeFromInfo(info, true, 0); mode != tc.want {
+			t.Errorf("FileModeFromInfo() of a %o file = %s, want %s", tc.perm, mode, tc.want)
+		}
+	}
+	if !internal.IsModeChange(internal.ModeRegular, internal.ModeExecutable) {
+		t.Errorf("Expected regular -> executable to be a mode change")
+	}
+
+	// With core.fileMode=false the mode on disk is ignored.
+	if err := os.Chmod(script, 0755); err != nil {
+		t.Fatalf("Chmod failed: %v", err)
+	}
+	info, _ := os.Stat(script)
+	if mode := internal.FileModeFromInfo(info, false, internal.ModeRegular); mode != internal.ModeRegular {
+		t.Errorf("FileModeFromInfo() with untrusted mode = %s, want %s", mode, internal.ModeRegular)
+	}
+}
+
+func TestSymlinkRoundTrip(t *testing.T) {
+	tempDir, tempDirErr := os.MkdirTemp("", "symlink")
+	if tempDirErr != nil {
+		t.Fatalf("Failed to create temporary directory: %v", tempDirErr)
+	}
+	defer func(path string) {
+		_ = os.RemoveAll(path)
+	}(tempDir)
+
+	link := filepath.Join(tempDir, "current")
+	if err := os.Symlink("releases/v1", link); err != nil {
+		t.Skipf("symbolic links are not supported: %v", err)
+	}
+
+	content, mode, err := internal.ReadWorkTreeContent(link)
+	if err != nil {
+		t.Fatalf("ReadWorkTreeContent failed: %v", err)
+	}
+	if mode != internal.ModeSymlink {
+		t.Errorf("Expected mode %s, got %s", internal.ModeSymlink, mode)
+	}
+	if string(content) != "releases/v1" {
+		t.Errorf("Expected link target 'releases/v1', got %q", content)
+	}
+
+	// A plain file holding the target, as written where links are not supported, is a regular file.
+	if err := os.Remove(link); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if err := os.WriteFile(link, []byte("releases/v2"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	content, mode, err = internal.ReadWorkTreeContent(link)
+	if err != nil {
+		t.Fatalf("ReadWorkTreeContent failed: %v", err)
+	}
+	if mode == internal.ModeSymlink || string(content) != "releases/v2" {
+		t.Errorf("Expected plain file containing 'releases/v2', got mode %s and %q", mode, content)
+	}
+	if !internal.IsTypeChange(internal.ModeSymlink, mode) {
+		t.Errorf("Expected symlink -> regular to be a type change")
+	}
+}
@@ -0,0 +1,135 @@
+package test
+
+import (
+	"errors"
+	"io/fs"
+	"jit/internal"
+	"jit/pkg/repo"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// filesLock serializes the tests that replace internal.Files, which the whole test binary shares.
+var filesLock sync.Mutex
+
+// lockFiles holds filesLock until the test ends and then restores internal.Files, so a test can
+// replace it, or enable tracing, without affecting another test.
+func lockFiles(t *testing.T) {
+	filesLock.Lock()
+	previous := internal.Files
+	t.Cleanup(func() {
+		internal.Files = previous
+		filesLock.Unlock()
+	})
+}
+
+func TestMemFileSystemRepository(t *testing.T) {
+	lockFiles(t)
+	memFS := internal.NewMemFileSystem()
+	internal.Files = memFS
+
+	workTree := filepath.Join(string(filepath.Separator), "mem", "project")
+	if err := memFS.MkdirAll(filepath.Join(workTree, "src"), 0755); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+	if _, err := internal.CreateJitDir(workTree, false, false, 0755); err != nil {
+		t.Fatalf("CreateJitDir failed: %v", err)
+	}
+	jitDir := filepath.Join(workTree, ".jit")
+	if _, err := internal.SetUpInitialBranch(jitDir, "main"); err != nil {
+		t.Fatalf("SetUpInitialBranch failed: %v", err)
+	}
+	if _, err := os.Stat(workTree); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("the repository was written to disk")
+	}
+
+	t.Run("discovery", func(t *testing.T) {
+		found, err := internal.FindJitDir(filepath.Join(workTree, "src"))
+		if err != nil || found != jitDir {
+			t.Errorf("FindJitDir() = %q, %v, want %q", found, err, jitDir)
+		}
+		head, _ := memFS.ReadFile(filepath.Join(jitDir, "head"))
+		if string(head) != "ref: branches/main\n" {
+			t.Errorf("head = %q", head)
+		}
+	})
+
+	t.Run("config", func(t *testing.T) {
+		configPath := filepath.Join(jitDir, "config")
+		if err := internal.SetConfigValue(configPath, "user.name", "Ada", false); err != nil {
+			t.Fatalf("SetConfigValue failed: %v", err)
+		}
+		config, err := internal.LoadConfigFile(configPath, internal.ScopeLocal)
+		if err != nil {
+			t.Fatalf("LoadConfigFile failed: %v", err)
+		}
+		if value, _ := config.Get("user.name"); value != "Ada" {
+			t.Errorf("user.name = %q, want Ada", value)
+		}
+	})
+
+	t.Run("library", func(t *testing.T) {
+		repository, err := repo.Open(filepath.Join(workTree, "src"))
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		if bare, err := repo.Open(jitDir); err != nil || bare.JitDir() != jitDir {
+			t.Errorf("Open(%q) = %v, want the jit directory itself", jitDir, err)
+		}
+		iter, err := repository.Branches()
+		if err != nil {
+			t.Fatalf("Branches failed: %v", err)
+		}
+		if ref, err := iter.Next(); err != nil || ref.Name != "main" {
+			t.Errorf("Next() = %+v, %v, want the main branch", ref, err)
+		}
+	})
+
+	t.Run("ignore rules", func(t *testing.T) {
+		if err := memFS.WriteFile(filepath.Join(workTree, ".jitignore"), []byte("*.o\n"), 0644); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		matcher, err := internal.NewIgnoreMatcher(workTree, jitDir, "", true)
+		if err != nil {
+			t.Fatalf("NewIgnoreMatcher failed: %v", err)
+		}
+		if !matcher.IsIgnored("src/main.o", false) || matcher.IsIgnored("src/main.c", false) {
+			t.Errorf("IsIgnored() did not use the in-memory .jitignore")
+		}
+	})
+
+	t.Run("symbolic links", func(t *testing.T) {
+		link := filepath.Join(workTree, "link")
+		if err := memFS.Symlink("src", link); err != nil {
+			t.Fatalf("Symlink failed: %v", err)
+		}
+		content, mode, err := internal.ReadWorkTreeContent(link)
+		if err != nil || string(content) != "src" || mode != internal.ModeSymlink {
+			t.Errorf("ReadWorkTreeContent() = %q, %v, %v", content, mode, err)
+		}
+		if info, err := memFS.Stat(filepath.Join(link, "..", "link")); err != nil || !info.IsDir() {
+			t.Errorf("Stat() through the link = %v, %v", info, err)
+		}
+	})
+
+	t.Run("templates", func(t *testing.T) {
+		templateDir := filepath.Join(string(filepath.Separator), "mem", "template")
+		if err := memFS.MkdirAll(filepath.Join(templateDir, "hooks"), 0755); err != nil {
+			t.Fatalf("MkdirAll failed: %v", err)
+		}
+		_ = memFS.WriteFile(filepath.Join(templateDir, "hooks", "pre-commit"), []byte("#!/bin/sh\n"), 0755)
+		_ = memFS.WriteFile(filepath.Join(templateDir, "config"), []byte("[core]\n"), 0644)
+		if err := internal.CopyTemplate(templateDir, jitDir); err != nil {
+			t.Fatalf("CopyTemplate failed: %v", err)
+		}
+		info, err := memFS.Stat(filepath.Join(jitDir, "hooks", "pre-commit"))
+		if err != nil || info.Mode().Perm() != 0755 {
+			t.Errorf("pre-commit = %v, %v, want an executable file", info, err)
+		}
+		if config, _ := memFS.ReadFile(filepath.Join(jitDir, "config")); string(config) == "[core]\n" {
+			t.Errorf("CopyTemplate() overwrote the config file")
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package test
+
+import (
+	"bytes"
+	"errors"
+	"jit/cmd"
+	"jit/internal"
+	"jit/pkg/repo"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRepositoryFormatVersion(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "format_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for format, want := range map[string]string{"sha1": "0", "sha256": "1"} {
+		workTree := filepath.Join(tempDir, format)
+		if err := os.Mkdir(workTree, 0755); err != nil {
+			t.Fatalf("Mkdir failed: %s", err)
+		}
+		if _, err := internal.InitializeJitRepository(map[string]any{"quiet": true, "object-format": format}, workTree); err != nil {
+			t.Fatalf("InitializeJitRepository failed: %s", err)
+		}
+		config, _ := internal.LoadConfigFile(filepath.Join(workTree, ".jit", "config"), internal.ScopeLocal)
+		if version, _ := config.Get("core.repositoryFormatVersion"); version != want {
+			t.Errorf("%s repository has format version %q, want %s", format, version, want)
+		}
+		if err := internal.CheckRepositoryFormat(filepath.Join(workTree, ".jit")); err != nil {
+			t.Errorf("CheckRepositoryFormat() of a new %s repository = %v", format, err)
+		}
+	}
+	if value, _, _ := mustOpen(t, filepath.Join(tempDir, "sha256")).Config("extensions.objectFormat"); value != "sha256" {
+		t.Errorf("extensions.objectFormat = %q, want sha256", value)
+	}
+
+	workTree := filepath.Join(tempDir, "sha256")
+	configPath := filepath.Join(workTree, ".jit", "config")
+	tests := []struct {
+		name    string
+		key     string
+		value   string
+		wantErr bool
+	}{
+		{"unknown extension", "extensions.worktreeConfig", "true", true},
+		{"unknown extension in version 0", "core.repositoryFormatVersion", "0", false},
+		{"future version", "core.repositoryFormatVersion", "2", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := internal.SetConfigValue(configPath, tt.key, tt.value, false); err != nil {
+				t.Fatalf("SetConfigValue failed: %s", err)
+			}
+			jitDir, _, discoverErr := internal.DiscoverRepository(workTree)
+			if tt.wantErr != errors.Is(discoverErr, internal.ErrUnsupportedFormat) {
+				t.Errorf("DiscoverRepository() error = %v, want ErrUnsupportedFormat: %v", discoverErr, tt.wantErr)
+			}
+			if jitDir != filepath.Join(workTree, ".jit") {
+				t.Errorf("DiscoverRepository() = %q, want the repository even when unsupported", jitDir)
+			}
+			if _, openErr := repo.Open(workTree); tt.wantErr != errors.Is(openErr, repo.ErrUnsupportedFormat) {
+				t.Errorf("Open() error = %v, want ErrUnsupportedFormat: %v", openErr, tt.wantErr)
+			}
+		})
+	}
+
+	// Commands refuse the repository, but config still reads it so it can be inspected.
+	var stdout, stderr bytes.Buffer
+	if code := cmd.Run([]string{"-C", workTree, "hook", "list"}, strings.NewReader(""), &stdout, &stderr); code == 0 || !strings.Contains(stderr.String(), "upgrade jit") {
+		t.Errorf("jit hook list = %d, %q; want a failure telling to upgrade", code, stderr.String())
+	}
+	stdout.Reset()
+	if code := cmd.Run([]string{"-C", workTree, "config", "get", "core.repositoryFormatVersion"}, strings.NewReader(""), &stdout, &stderr); code != 0 || strings.TrimSpace(stdout.String()) != "2" {
+		t.Errorf("jit config get = %d, %q; want 0 and 2", code, stdout.String())
+	}
+}
+
+func mustOpen(t *testing.T, path string) *repo.Repository {
+	t.Helper()
+	opened, err := repo.Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %s", err)
+	}
+	return opened
+}
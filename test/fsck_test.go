@@ -0,0 +1,94 @@
+package test
+
+import (
+	"bytes"
+	"jit/cmd"
+	"jit/internal"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckRepositoryRepairsHead(t *testing.T) {
+	workTree, err := os.MkdirTemp("", "fsck_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(workTree)
+	if _, err := internal.InitializeJitRepository(map[string]any{"quiet": true, "initial-branch": "main"}, workTree); err != nil {
+		t.Fatalf("InitializeJitRepository failed: %s", err)
+	}
+	jitDir := filepath.Join(workTree, ".jit")
+
+	// Break the repository: a missing directory, HEAD naming a deleted branch and a stale lock.
+	if err := os.RemoveAll(filepath.Join(jitDir, "hooks")); err != nil {
+		t.Fatalf("RemoveAll failed: %s", err)
+	}
+	if err := os.Remove(filepath.Join(jitDir, "branches", "main")); err != nil {
+		t.Fatalf("Remove failed: %s", err)
+	}
+	writeTestFile(t, filepath.Join(jitDir, "branches", "release", "v1"), "")
+	lockPath := filepath.Join(jitDir, "config.lock")
+	writeTestFile(t, lockPath, "")
+	old := time.Now().Add(-2 * internal.StaleLockAge)
+	if err := os.Chtimes(lockPath, old, old); err != nil {
+		t.Fatalf("Chtimes failed: %s", err)
+	}
+
+	problems, checkErr := internal.CheckRepository(jitDir, false)
+	if checkErr != nil {
+		t.Fatalf("CheckRepository failed: %s", checkErr)
+	}
+	if len(problems) != 3 {
+		t.Fatalf("CheckRepository() found %v, want hooks, HEAD and the lock", problems)
+	}
+	if _, statErr := os.Stat(filepath.Join(jitDir, "hooks")); statErr == nil {
+		t.Errorf("CheckRepository() without repair recreated hooks")
+	}
+
+	problems, checkErr = internal.CheckRepository(jitDir, true)
+	if checkErr != nil {
+		t.Fatalf("CheckRepository with repair failed: %s", checkErr)
+	}
+	for _, problem := range problems {
+		if !problem.Repaired {
+			t.Errorf("problem not repaired: %s", problem)
+		}
+	}
+	if branch, _, headErr := internal.ReadHead(jitDir); headErr != nil || branch != "release/v1" {
+		t.Errorf("HEAD = %q, %v after repair, want release/v1", branch, headErr)
+	}
+
+	if problems, _ := internal.CheckRepository(jitDir, false); len(problems) != 0 {
+		t.Errorf("CheckRepository() after repair found %v", problems)
+	}
+}
+
+func TestFsckCommand(t *testing.T) {
+	workTree, err := os.MkdirTemp("", "fsck_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(workTree)
+	if _, err := internal.InitializeJitRepository(map[string]any{"quiet": true, "initial-branch": "main"}, workTree); err != nil {
+		t.Fatalf("InitializeJitRepository failed: %s", err)
+	}
+	writeTestFile(t, filepath.Join(workTree, ".jit", "head"), "ref: branches/gone\n")
+
+	run := func(args ...string) (int, string) {
+		var stdout, stderr bytes.Buffer
+		code := cmd.Run(append([]string{"-C", workTree, "fsck"}, args...), strings.NewReader(""), &stdout, &stderr)
+		return code, stdout.String()
+	}
+	if code, out := run(); code != cmd.ExitFailure || !strings.Contains(out, "branch gone, which does not exist") {
+		t.Errorf("jit fsck = %d, %q; want 1 and the missing branch", code, out)
+	}
+	if code, out := run("--repair"); code != cmd.ExitSuccess || !strings.Contains(out, "now points to main (repaired)") {
+		t.Errorf("jit fsck --repair = %d, %q; want 0 and HEAD repaired", code, out)
+	}
+	if code, out := run(); code != cmd.ExitSuccess || out != "" {
+		t.Errorf("jit fsck after repair = %d, %q; want 0 and no output", code, out)
+	}
+}
@@ -0,0 +1,104 @@
+package test
+
+import (
+	"errors"
+	"jit/internal"
+	"jit/pkg/repo"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHeadSurvivesMovingTheRepository(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "head_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	original := filepath.Join(tempDir, "original")
+	if err := os.Mkdir(original, 0755); err != nil {
+		t.Fatalf("Mkdir failed: %s", err)
+	}
+	if _, err := repo.Init(original, repo.InitOptions{InitialBranch: "feature/login"}); err != nil {
+		t.Fatalf("Init failed: %s", err)
+	}
+	moved := filepath.Join(tempDir, "moved")
+	if err := os.Rename(original, moved); err != nil {
+		t.Fatalf("Rename failed: %s", err)
+	}
+
+	opened, openErr := repo.Open(moved)
+	if openErr != nil {
+		t.Fatalf("Open failed: %s", openErr)
+	}
+	if branch, branchErr := opened.CurrentBranch(); branchErr != nil || branch != "feature/login" {
+		t.Errorf("CurrentBranch() = %q, %v, want feature/login", branch, branchErr)
+	}
+}
+
+func TestReadHead(t *testing.T) {
+	jitDir, err := os.MkdirTemp("", "head_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(jitDir)
+	headPath := filepath.Join(jitDir, "head")
+
+	tests := []struct {
+		name   string
+		head   string
+		branch string
+		legacy bool
+		err    error
+	}{
+		{"symbolic", "ref: branches/main\n", "main", false, nil},
+		{"nested symbolic", "ref: branches/feature/login", "feature/login", false, nil},
+		{"legacy absolute path", filepath.Join(jitDir, "branches", "trunk"), "trunk", true, nil},
+		{"legacy path of a moved repository", filepath.Join(string(filepath.Separator), "old", "place", ".jit", "branches", "dev"), "dev", true, nil},
+		{"empty", "", "", false, internal.ErrNoBranch},
+		{"outside the branches", "ref: config", "", false, internal.ErrNoBranch},
+		{"invalid branch name", "ref: branches/../config", "", false, internal.ErrNoBranch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeTestFile(t, headPath, tt.head)
+			branch, legacy, readErr := internal.ReadHead(jitDir)
+			if !errors.Is(readErr, tt.err) || (tt.err == nil && readErr != nil) {
+				t.Fatalf("ReadHead() error = %v, want %v", readErr, tt.err)
+			}
+			if branch != tt.branch || legacy != tt.legacy {
+				t.Errorf("ReadHead() = %q, %v, want %q, %v", branch, legacy, tt.branch, tt.legacy)
+			}
+		})
+	}
+}
+
+func TestMigrateHead(t *testing.T) {
+	workTree, err := os.MkdirTemp("", "head_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(workTree)
+
+	options := map[string]any{"quiet": true, "initial-branch": "main"}
+	if _, err := internal.InitializeJitRepository(options, workTree); err != nil {
+		t.Fatalf("InitializeJitRepository failed: %s", err)
+	}
+	jitDir := filepath.Join(workTree, ".jit")
+	headPath := filepath.Join(jitDir, "head")
+
+	if migrated, migrateErr := internal.MigrateHead(jitDir); migrated || migrateErr != nil {
+		t.Errorf("MigrateHead() of a symbolic HEAD = %v, %v, want false, nil", migrated, migrateErr)
+	}
+
+	// Re-initializing a repository created by an earlier version migrates its HEAD.
+	writeTestFile(t, headPath, filepath.Join(jitDir, "branches", "main"))
+	if _, err := internal.InitializeJitRepository(map[string]any{"quiet": true}, workTree); err != nil {
+		t.Fatalf("Re-init failed: %s", err)
+	}
+	if content, _ := os.ReadFile(headPath); string(content) != "ref: branches/main\n" {
+		t.Errorf("head after re-init = %q, want a symbolic reference", content)
+	}
+}
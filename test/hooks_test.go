@@ -0,0 +1,120 @@
+package test
+
+import (
+	"errors"
+	"jit/internal"
+	"jit/pkg/util"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeHook(t *testing.T, jitDir string, name string, script string, perm os.FileMode) {
+	t.Helper()
+	hooksDir := filepath.Join(jitDir, util.HOOKS)
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		t.Fatalf("Failed to create hooks dir: %s", err)
+	}
+	if err := os.WriteFile(filepath.Join(hooksDir, name), []byte("#!/bin/sh\n"+script), perm); err != nil {
+		t.Fatalf("Failed to write hook: %s", err)
+	}
+}
+
+func TestRunHook(t *testing.T) {
+	workTree, err := os.MkdirTemp("", "hooks_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(workTree)
+	jitDir := filepath.Join(workTree, util.JitDirName)
+
+	writeHook(t, jitDir, internal.HookCommitMsg, `echo "$1 $JIT_DIR $(pwd)" > hook-output; cat >> hook-output`, 0755)
+	writeHook(t, jitDir, internal.HookPreCommit, "exit 3", 0755)
+	writeHook(t, jitDir, internal.HookPostCommit, "touch should-not-run", 0644)
+
+	hooks := internal.NewHooks(jitDir, workTree, nil)
+	ran, runErr := hooks.Run(internal.HookCommitMsg, []string{"MSG"}, strings.NewReader("from stdin\n"))
+	if !ran || runErr != nil {
+		t.Fatalf("Run() = %v, %v", ran, runErr)
+	}
+	output, _ := os.ReadFile(filepath.Join(workTree, "hook-output"))
+	if expected := "MSG " + jitDir + " " + workTree + "\nfrom stdin\n"; string(output) != expected {
+		t.Errorf("hook output = %q, want %q", output, expected)
+	}
+
+	_, runErr = hooks.Run(internal.HookPreCommit, nil, nil)
+	var hookErr *internal.HookError
+	if !errors.As(runErr, &hookErr) || hookErr.ExitCode != 3 {
+		t.Errorf("Run() error = %v, want a HookError with exit code 3", runErr)
+	}
+
+	if ran, runErr = hooks.Run(internal.HookPostCommit, nil, nil); ran || runErr != nil {
+		t.Errorf("Run() ran a non-executable hook: %v, %v", ran, runErr)
+	}
+	if ran, runErr = hooks.Run(internal.HookPostMerge, []string{"0"}, nil); ran || runErr != nil {
+		t.Errorf("Run() for a missing hook = %v, %v", ran, runErr)
+	}
+}
+
+func TestHooksPath(t *testing.T) {
+	workTree, err := os.MkdirTemp("", "hooks_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(workTree)
+	jitDir := filepath.Join(workTree, util.JitDirName)
+
+	configFile := filepath.Join(jitDir, util.CONFIG)
+	writeTestFile(t, configFile, "[core]\n\thooksPath = shared-hooks\n")
+	config, loadErr := internal.LoadConfigFile(configFile, internal.ScopeLocal)
+	if loadErr != nil {
+		t.Fatalf("LoadConfigFile failed: %s", loadErr)
+	}
+
+	hooks := internal.NewHooks(jitDir, workTree, config)
+	if expected := filepath.Join(workTree, "shared-hooks"); hooks.Dir != expected {
+		t.Errorf("Dir = %s, want %s", hooks.Dir, expected)
+	}
+
+	writeHook(t, workTree, "pre-push.sample", "exit 0", 0755)
+	_ = os.Rename(filepath.Join(workTree, util.HOOKS), hooks.Dir)
+	writeHook(t, jitDir, internal.HookPrePush, "exit 0", 0755)
+	if err := os.WriteFile(filepath.Join(hooks.Dir, internal.HookPreCommit), []byte("#!/bin/sh\nexit 0\n"), 0755); err != nil {
+		t.Fatalf("Failed to write hook: %s", err)
+	}
+
+	names, listErr := hooks.List()
+	if listErr != nil {
+		t.Fatalf("List failed: %s", listErr)
+	}
+	if len(names) != 1 || names[0] != internal.HookPreCommit {
+		t.Errorf("List() = %v, want only the hook in core.hooksPath", names)
+	}
+}
+
+func TestHooksAreFoundThroughFiles(t *testing.T) {
+	lockFiles(t)
+	memFS := internal.NewMemFileSystem()
+	internal.Files = memFS
+
+	jitDir := filepath.Join(string(filepath.Separator), "mem", "project", util.JitDirName)
+	hooksDir := filepath.Join(jitDir, util.HOOKS)
+	if err := memFS.MkdirAll(hooksDir, 0755); err != nil {
+		t.Fatalf("MkdirAll failed: %s", err)
+	}
+	if err := memFS.WriteFile(filepath.Join(hooksDir, internal.HookPreCommit), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("WriteFile failed: %s", err)
+	}
+	if err := memFS.WriteFile(filepath.Join(hooksDir, internal.HookPostCommit), []byte("#!/bin/sh\n"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %s", err)
+	}
+
+	hooks := internal.NewHooks(jitDir, filepath.Dir(jitDir), nil)
+	if names, listErr := hooks.List(); listErr != nil || len(names) != 1 || names[0] != internal.HookPreCommit {
+		t.Errorf("List() = %v, %v, want [%s]", names, listErr, internal.HookPreCommit)
+	}
+	if _, ok := hooks.Find(internal.HookPostCommit); ok {
+		t.Errorf("Find() returned a hook that is not executable")
+	}
+}
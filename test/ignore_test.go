@@ -0,0 +1,159 @@
+package test
+
+import (
+	"jit/internal"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, file string, content string) {
+	t.Helper()
+	if mkErr := os.MkdirAll(filepath.Dir(file), 0755); mkErr != nil {
+		t.Fatalf("Failed to create directory for %s: %v", file, mkErr)
+	}
+	if writeErr := os.WriteFile(file, []byte(content), 0644); writeErr != nil {
+		t.Fatalf("Failed to write %s: %v", file, writeErr)
+	}
+}
+
+func TestIgnorePatternMatches(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		base    string
+		path    string
+		isDir   bool
+		matches bool
+	}{
+		{"Basename Glob", "*.o", "", "src/lib/main.o", false, true},
+		{"Basename Glob Miss", "*.o", "", "src/main.c", false, false},
+		{"Anchored", "/build", "", "build", true, true},
+		{"Anchored Not Nested", "/build", "", "src/build", true, false},
+		{"Dir Only On File", "logs/", "", "logs", false, false},
+		{"Dir Only On Dir", "logs/", "", "a/logs", true, true},
+		{"Leading Double Star", "**/tmp", "", "a/b/tmp", false, true},
+		{"Leading Double Star Root", "**/tmp", "", "tmp", false, true},
+		{"Middle Double Star", "a/**/z", "", "a/b/c/z", false, true},
+		{"Middle Double Star Zero Dirs", "a/**/z", "", "a/z", false, true},
+		{"Trailing Double Star", "vendor/**", "", "vendor/x/y.go", false, true},
+		{"Question Mark", "file?.txt", "", "file1.txt", false, true},
+		{"Character Class", "file[0-9].txt", "", "filea.txt", false, false},
+		{"Negated Class", "file[!0-9].txt", "", "filea.txt", false, true},
+		{"Nested Base", "/out", "sub", "sub/out", true, true},
+		{"Nested Base Other Dir", "/out", "sub", "other/out", true, false},
+		{"Escaped Hash", `\#notes`, "", "#notes", false, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			patterns := internal.ParseIgnorePatterns(tc.pattern, tc.base)
+			if len(patterns) != 1 {
+				t.Fatalf("Expected one pattern from %q, got %d", tc.pattern, len(patterns))
+			}
+			if got := patterns[0].Matches(tc.path, tc.isDir); got != tc.matches {
+				t.Errorf("%q.Matches(%q) = %v, want %v", tc.pattern, tc.path, got, tc.matches)
+			}
+		})
+	}
+}
+
+func TestIgnoreMatcherPrecedence(t *testing.T) {
+	workTree, tempDirErr := os.MkdirTemp("", "worktree")
+	if tempDirErr != nil {
+		t.Fatalf("Failed to create temporary directory: %v", tempDirErr)
+	}
+	defer func(path string) {
+		_ = os.RemoveAll(path)
+	}(workTree)
+
+	jitDir := filepath.Join(workTree, ".jit")
+	globalFile := filepath.Join(workTree, "global-ignore")
+
+	writeTestFile(t, globalFile, "*.swp\n*.log\n")
+	writeTestFile(t, filepath.Join(jitDir, "info", "exclude"), "secret.txt\n")
+	writeTestFile(t, filepath.Join(workTree, ".jitignore"), "# comment\n\n*.tmp\n!keep.tmp\nbuild/\n!important.log\n")
+	writeTestFile(t, filepath.Join(workTree, "docs", ".jitignore"), "!draft.tmp\n")
+
+	matcher, err := internal.NewIgnoreMatcher(workTree, jitDir, globalFile, false)
+	if err != nil {
+		t.Fatalf("NewIgnoreMatcher failed: %v", err)
+	}
+
+	tests := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"notes.swp", false, true},
+		{"debug.log", false, true},
+		{"important.log", false, false},
+		{"secret.txt", false, true},
+		{"a.tmp", false, true},
+		{"keep.tmp", false, false},
+		{"docs/draft.tmp", false, false},
+		{"docs/other.tmp", false, true},
+		{"build", true, true},
+		{"build", false, false},
+		{"build/out.bin", false, true},
+		{"src/build/out.bin", false, true},
+		{"main.go", false, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			if got := matcher.IsIgnored(tc.path, tc.isDir); got != tc.ignored {
+				t.Errorf("IsIgnored(%q) = %v, want %v", tc.path, got, tc.ignored)
+			}
+		})
+	}
+}
+
+func TestGitFilesFallback(t *testing.T) {
+	workTree, tempDirErr := os.MkdirTemp("", "worktree")
+	if tempDirErr != nil {
+		t.Fatalf("Failed to create temporary directory: %v", tempDirErr)
+	}
+	defer func(path string) {
+		_ = os.RemoveAll(path)
+	}(workTree)
+
+	writeTestFile(t, filepath.Join(workTree, ".gitignore"), "*.o\n")
+	writeTestFile(t, filepath.Join(workTree, "lib", ".gitignore"), "*.a\n")
+	writeTestFile(t, filepath.Join(workTree, "lib", ".jitignore"), "*.so\n")
+
+	configFile := filepath.Join(workTree, "config")
+	writeTestFile(t, configFile, "[core]\n\treadGitFiles = false\n")
+	config, loadErr := internal.LoadConfigFile(configFile, internal.ScopeLocal)
+	if loadErr != nil {
+		t.Fatalf("LoadConfigFile failed: %v", loadErr)
+	}
+	if !internal.ReadGitFiles(nil) || internal.ReadGitFiles(config) {
+		t.Errorf("ReadGitFiles() did not honor core.readGitFiles")
+	}
+
+	tests := []struct {
+		name         string
+		readGitFiles bool
+		path         string
+		ignored      bool
+	}{
+		{"fallback to .gitignore", true, "main.o", true},
+		{".jitignore takes precedence", true, "lib/x.a", false},
+		{".jitignore still applies", true, "lib/x.so", true},
+		{"root .gitignore still applies below", true, "lib/x.o", true},
+		{"fallback disabled", false, "main.o", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ignoreMatcher, ignoreErr := internal.NewIgnoreMatcher(workTree, "", "", tc.readGitFiles)
+			if ignoreErr != nil {
+				t.Fatalf("NewIgnoreMatcher failed: %v", ignoreErr)
+			}
+			if got := ignoreMatcher.IsIgnored(tc.path, false); got != tc.ignored {
+				t.Errorf("IsIgnored(%q) = %v, want %v", tc.path, got, tc.ignored)
+			}
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package test
 
 import (
+	"errors"
 	"jit/internal"
+	"jit/pkg/util"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -44,9 +46,9 @@ func TestInitialBranchSetupWithValidRepoRoot(t *testing.T) {
 	if readErr != nil {
 		t.Fatalf("Failed to read head file: %v", readErr)
 	}
-	expectedPath := filepath.Join(tempDir, "branches", "main")
-	if string(content) != expectedPath {
-		t.Errorf("Expected head content to be '%s', got '%s'", expectedPath, string(content))
+	expectedRef := "ref: branches/main\n"
+	if string(content) != expectedRef {
+		t.Errorf("Expected head content to be '%s', got '%s'", expectedRef, string(content))
 	}
 }
 
@@ -61,8 +63,8 @@ func TestWriteToConfigFileWithValidRepoRoot(t *testing.T) {
 	}(tempDir) // Clean up after the test.
 
 	config := map[string]string{
-		"TEMPLATE": "/usr/template",
-		"BRANCH":   "main",
+		"init.templateDir":   "/usr/template",
+		"init.defaultBranch": "main",
 	}
 
 	_, err := internal.WriteToConfigFile(config, tempDir)
@@ -76,7 +78,7 @@ func TestWriteToConfigFileWithValidRepoRoot(t *testing.T) {
 	}
 
 	// Checking for the exact key-value pair format.
-	expectedContent := []string{"TEMPLATE=/usr/template", "BRANCH=main"}
+	expectedContent := []string{"[init]", "templateDir = /usr/template", "defaultBranch = main"}
 	for _, expected := range expectedContent {
 		if !strings.Contains(string(content), expected) {
 			t.Errorf("Expected config to contain '%s', but it was not found", expected)
@@ -332,6 +334,8 @@ func TestInitializeJitRepository(t *testing.T) {
 	defer func(path string) {
 		_ = os.RemoveAll(path)
 	}(sepDir) // Cleanup for working directory
+	t.Setenv(util.EnvTemplateDir, "")
+	t.Setenv(util.EnvConfigGlobal, filepath.Join(wkDir, "jitconfig"))
 	// Define test cases
 	tests := []struct {
 		name    string
@@ -421,7 +425,227 @@ func TestInitializeJitRepository(t *testing.T) {
 				t.Errorf("Test %s: InitializeJitRepository() was not successful", tc.name)
 			}
 
-			// Add additional checks here to verify the creation of files and directories
+			if tc.wantErr {
+				return
+			}
+			jitDir, findErr := internal.FindJitDir(tc.dir)
+			if findErr != nil {
+				t.Fatalf("FindJitDir failed: %v", findErr)
+			}
+			config, loadErr := internal.LoadConfigFile(filepath.Join(jitDir, util.CONFIG), internal.ScopeLocal)
+			if loadErr != nil {
+				t.Fatalf("LoadConfigFile failed: %v", loadErr)
+			}
+			_, hasTemplate := config.Get("init.templateDir")
+			if _, givenTemplate := tc.options["template"]; hasTemplate != givenTemplate {
+				t.Errorf("init.templateDir set = %v, want %v", hasTemplate, givenTemplate)
+			}
+		})
+	}
+}
+
+func TestInitializeJitRepositoryTypedErrors(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "testrepo")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer func(path string) {
+		_ = os.RemoveAll(path)
+	}(tempDir)
+
+	options := map[string]any{"quiet": true, "initial-branch": "main", "perm": "0755"}
+	if _, err := internal.InitializeJitRepository(options, tempDir); err != nil {
+		t.Fatalf("InitializeJitRepository() failed: %v", err)
+	}
+
+	// A file named .jit is not a repository that can be re-initialized.
+	blocked := filepath.Join(tempDir, "blocked")
+	writeTestFile(t, filepath.Join(blocked, ".jit"), "not a repository")
+
+	tests := []struct {
+		name    string
+		options map[string]any
+		dir     string
+		wantErr error
+	}{
+		{".jit is a file", options, blocked, internal.ErrRepositoryExists},
+		{"invalid initial branch", map[string]any{"quiet": true, "initial-branch": "../config", "force": true}, tempDir, internal.ErrInvalidRefName},
+		{"different object format", map[string]any{"quiet": true, "object-format": "sha256"}, tempDir, internal.ErrInvalidOption},
+		{"option of the wrong type", map[string]any{"quiet": "yes"}, tempDir, internal.ErrInvalidOption},
+		{"permission that is not octal", map[string]any{"perm": "rwx"}, tempDir, internal.ErrInvalidOption},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := internal.InitializeJitRepository(tc.options, tc.dir); !errors.Is(err, tc.wantErr) {
+				t.Errorf("InitializeJitRepository() error = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestInitializeJitRepositoryRollback(t *testing.T) {
+	tempDir, tempDirErr := os.MkdirTemp("", "rollback")
+	if tempDirErr != nil {
+		t.Fatalf("Failed to create temporary directory: %v", tempDirErr)
+	}
+	defer func(path string) {
+		_ = os.RemoveAll(path)
+	}(tempDir)
+
+	tests := []struct {
+		name     string
+		options  func(dir string) map[string]any
+		keep     []string // Files written before init that must survive the rollback.
+		removed  []string // Paths init creates that must be gone after it fails.
+		separate bool
+	}{
+		{
+			name:    "standard",
+			options: func(dir string) map[string]any { return map[string]any{"initial-branch": ""} },
+			keep:    []string{"notes.txt"},
+			removed: []string{".jit"},
+		},
+		{
+			name:    "bare",
+			options: func(dir string) map[string]any { return map[string]any{"bare": true, "initial-branch": ""} },
+			keep:    []string{"notes.txt"},
+			removed: []string{"head", "config", "branches", "objects"},
+		},
+		{
+			name: "separate",
+			options: func(dir string) map[string]any {
+				return map[string]any{"separate-jit-dir": filepath.Join(dir, "storage"), "initial-branch": ""}
+			},
+			keep:     []string{"notes.txt", "storage/keep.txt"},
+			removed:  []string{".jit", "storage/head", "storage/branches"},
+			separate: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := filepath.Join(tempDir, tc.name)
+			for _, file := range tc.keep {
+				writeTestFile(t, filepath.Join(dir, file), "keep\n")
+			}
+			// An empty initial branch is not a valid name, so setting it up fails after everything
+			// else has been created.
+			if _, err := internal.InitializeJitRepository(tc.options(dir), dir); err == nil {
+				t.Fatalf("Expected InitializeJitRepository to fail")
+			}
+			for _, file := range tc.keep {
+				if _, statErr := os.Stat(filepath.Join(dir, file)); statErr != nil {
+					t.Errorf("Rollback removed %s, which existed before init: %v", file, statErr)
+				}
+			}
+			for _, path := range tc.removed {
+				if _, statErr := os.Lstat(filepath.Join(dir, path)); !errors.Is(statErr, os.ErrNotExist) {
+					t.Errorf("Rollback left %s behind (%v)", path, statErr)
+				}
+			}
 		})
 	}
+
+	// A failed re-initialization must not remove the existing repository.
+	existing := filepath.Join(tempDir, "existing")
+	if err := os.Mkdir(existing, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if _, err := internal.InitializeJitRepository(map[string]any{"initial-branch": "main"}, existing); err != nil {
+		t.Fatalf("InitializeJitRepository failed: %v", err)
+	}
+	if _, err := internal.InitializeJitRepository(map[string]any{"object-format": "sha256"}, existing); err == nil {
+		t.Fatalf("Expected re-init with another object format to fail")
+	}
+	if _, statErr := os.Stat(filepath.Join(existing, ".jit", "head")); statErr != nil {
+		t.Errorf("Failed re-init removed the existing repository: %v", statErr)
+	}
+}
+
+func TestInitializeJitRepositoryReinitialize(t *testing.T) {
+	tempDir, tempDirErr := os.MkdirTemp("", "reinit")
+	if tempDirErr != nil {
+		t.Fatalf("Failed to create temporary directory: %v", tempDirErr)
+	}
+	defer func(path string) {
+		_ = os.RemoveAll(path)
+	}(tempDir)
+
+	jitDir := filepath.Join(tempDir, ".jit")
+	if _, err := internal.InitializeJitRepository(map[string]any{"quiet": true, "initial-branch": "main"}, tempDir); err != nil {
+		t.Fatalf("InitializeJitRepository failed: %v", err)
+	}
+	if err := internal.SetConfigValue(filepath.Join(jitDir, "config"), "user.name", "Ada", false); err != nil {
+		t.Fatalf("SetConfigValue failed: %v", err)
+	}
+	writeTestFile(t, filepath.Join(jitDir, "stage"), "staged\n")
+	if err := os.RemoveAll(filepath.Join(jitDir, "hooks")); err != nil {
+		t.Fatalf("Failed to remove hooks: %v", err)
+	}
+	head, _ := os.ReadFile(filepath.Join(jitDir, "head"))
+
+	// Without --force, re-init restores missing entries and keeps everything else.
+	if _, err := internal.InitializeJitRepository(map[string]any{"quiet": true, "initial-branch": "trunk"}, tempDir); err != nil {
+		t.Fatalf("Re-init failed: %v", err)
+	}
+	if info, err := os.Stat(filepath.Join(jitDir, "hooks")); err != nil || !info.IsDir() {
+		t.Errorf("Re-init did not recreate hooks (%v)", err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(jitDir, "stage")); string(content) != "staged\n" {
+		t.Errorf("Re-init truncated stage: %q", content)
+	}
+	if content, _ := os.ReadFile(filepath.Join(jitDir, "head")); string(content) != string(head) {
+		t.Errorf("Re-init changed head to %q, want %q", content, head)
+	}
+	config, loadErr := internal.LoadConfigFile(filepath.Join(jitDir, "config"), internal.ScopeLocal)
+	if loadErr != nil {
+		t.Fatalf("LoadConfigFile failed: %v", loadErr)
+	}
+	if name, _ := config.Get("user.name"); name != "Ada" {
+		t.Errorf("Re-init lost user.name, got %q", name)
+	}
+	if branch, _ := config.Get("init.defaultBranch"); branch != "main" {
+		t.Errorf("Re-init changed init.defaultBranch to %q", branch)
+	}
+
+	// With --force, HEAD is switched to the branch given.
+	options := map[string]any{"quiet": true, "initial-branch": "trunk", "force": true}
+	if _, err := internal.InitializeJitRepository(options, tempDir); err != nil {
+		t.Fatalf("Forced re-init failed: %v", err)
+	}
+	if branch, _, err := internal.ReadHead(jitDir); err != nil || branch != "trunk" {
+		t.Errorf("Forced re-init left head at %q (%v), want trunk", branch, err)
+	}
+	if _, err := os.Stat(filepath.Join(jitDir, "branches", "main")); err != nil {
+		t.Errorf("Forced re-init removed the main branch: %v", err)
+	}
+}
+
+func TestInitializeJitRepositoryReportsFailures(t *testing.T) {
+	tempDir, tempDirErr := os.MkdirTemp("", "initfailures")
+	if tempDirErr != nil {
+		t.Fatalf("Failed to create temporary directory: %v", tempDirErr)
+	}
+	defer func(path string) {
+		_ = os.RemoveAll(path)
+	}(tempDir)
+
+	// A file where a directory belongs makes CreateJitDir fail instead of carrying on.
+	blocked := filepath.Join(tempDir, "blocked")
+	writeTestFile(t, filepath.Join(blocked, "objects"), "not a directory")
+	if _, err := internal.CreateJitDir(blocked, false, true, 0755); err == nil || !strings.Contains(err.Error(), "objects") {
+		t.Errorf("CreateJitDir() = %v, want an error about objects", err)
+	}
+
+	// A config file locked by another process makes init fail instead of logging the error.
+	locked := filepath.Join(tempDir, "locked")
+	writeTestFile(t, filepath.Join(locked, "config.lock"), "")
+	_, err := internal.InitializeJitRepository(map[string]any{"bare": true, "initial-branch": "main"}, locked)
+	if !errors.Is(err, internal.ErrLocked) {
+		t.Errorf("InitializeJitRepository() with a locked config = %v, want ErrLocked", err)
+	}
+	if _, statErr := os.Stat(filepath.Join(locked, "head")); !errors.Is(statErr, os.ErrNotExist) {
+		t.Errorf("Failed init left head behind (%v)", statErr)
+	}
 }
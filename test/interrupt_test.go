@@ -0,0 +1,82 @@
+package test
+
+import (
+	"errors"
+	"jit/internal"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestInterruptReleasesLocks(t *testing.T) {
+	dir, err := os.MkdirTemp("", "interrupt_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	held, lockErr := internal.LockFile(filepath.Join(dir, "held"))
+	if lockErr != nil {
+		t.Fatalf("LockFile failed: %s", lockErr)
+	}
+	released, lockErr := internal.LockFile(filepath.Join(dir, "released"))
+	if lockErr != nil {
+		t.Fatalf("LockFile failed: %s", lockErr)
+	}
+	if err := released.Commit([]byte("content")); err != nil {
+		t.Fatalf("Commit failed: %s", err)
+	}
+
+	var order []string
+	internal.OnInterrupt(func() { order = append(order, "first") })
+	remove := internal.OnInterrupt(func() { order = append(order, "removed") })
+	internal.OnInterrupt(func() { order = append(order, "last") })
+	remove()
+
+	internal.RunInterruptCleanups()
+	if _, statErr := os.Stat(filepath.Join(dir, "held.lock")); !errors.Is(statErr, os.ErrNotExist) {
+		t.Errorf("held.lock survived the interrupt (%v)", statErr)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dir, "released")); string(content) != "content" {
+		t.Errorf("released = %q, want content", content)
+	}
+	if len(order) != 2 || order[0] != "last" || order[1] != "first" {
+		t.Errorf("cleanups ran as %v, want [last first]", order)
+	}
+	if err := held.Rollback(); err == nil {
+		t.Errorf("Rollback() of a lock removed by the interrupt succeeded")
+	}
+}
+
+func TestHandleSignals(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals cannot be sent to the own process on Windows")
+	}
+	cleaned := make(chan struct{}, 1)
+	internal.OnInterrupt(func() { cleaned <- struct{}{} })
+
+	codes := make(chan int, 1)
+	stop := internal.HandleSignals(func(code int) { codes <- code })
+	defer stop()
+	self, _ := os.FindProcess(os.Getpid())
+	if err := self.Signal(syscall.SIGTERM); err != nil {
+		t.Fatalf("Signal failed: %s", err)
+	}
+
+	select {
+	case code := <-codes:
+		if code != 128+int(syscall.SIGTERM) {
+			t.Errorf("exit code = %d, want %d", code, 128+int(syscall.SIGTERM))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("SIGTERM was not handled")
+	}
+	select {
+	case <-cleaned:
+	default:
+		t.Errorf("the cleanup did not run before exit")
+	}
+}
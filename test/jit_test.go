@@ -5,7 +5,9 @@ import (
 	"jit/cmd"
 	"jit/pkg/util"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -18,48 +20,313 @@ func TestJitVersionFlag(t *testing.T) {
 	}{
 		{
 			name:     "version flag",
-			args:     []string{"jit", "-v"},
+			args:     []string{"-v"},
 			expected: util.JitVersion,
 		},
 		{
 			name:     "long version flag",
-			args:     []string{"jit", "--version"},
+			args:     []string{"--version"},
 			expected: util.JitVersion,
 		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			// Backup and defer restoration of os.Args and os.Stdout
-			oldArgs := os.Args
-			oldStdout := os.Stdout
-			defer func() {
-				os.Args = oldArgs
-				os.Stdout = oldStdout
-			}()
-
-			// Set up the arguments for the test
-			os.Args = tc.args
-
-			// Capture the stdout output
-			r, w, _ := os.Pipe()
-			os.Stdout = w
-
-			// Call the Jit function
-			go func() {
-				cmd.Jit()
-				_ = w.Close()
-			}()
-
-			// Read the output
-			var buf bytes.Buffer
-			_, _ = buf.ReadFrom(r)
+			var stdout, stderr bytes.Buffer
+			if code := cmd.Run(tc.args, strings.NewReader(""), &stdout, &stderr); code != 0 {
+				t.Fatalf("Run() = %d, stderr %q", code, stderr.String())
+			}
 
 			// Check the output
-			if got := buf.String(); !strings.Contains(got, tc.expected) {
-				t.Errorf("Jit() = %q, want %q", got, tc.expected)
+			if got := stdout.String(); !strings.Contains(got, tc.expected) {
+				t.Errorf("Run() = %q, want %q", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	workTree, tempDirErr := os.MkdirTemp("", "worktree")
+	if tempDirErr != nil {
+		t.Fatalf("Failed to create temporary directory: %v", tempDirErr)
+	}
+	defer func(path string) {
+		_ = os.RemoveAll(path)
+	}(workTree)
+
+	oldDir, _ := os.Getwd()
+	if err := os.Chdir(workTree); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldDir) }()
+
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{name: "init", args: []string{"init", "-q"}},
+		{name: "config set", args: []string{"config", "set", "user.name", "Ada"}},
+		{name: "config get", args: []string{"config", "get", "user.name"}, stdout: "Ada\n"},
+		{name: "missing key", args: []string{"config", "get", "user.email"}, code: 1},
+		{name: "nothing to unset", args: []string{"config", "unset", "user.email"}, code: 5},
+		{name: "invalid config key", args: []string{"config", "set", "my section.key", "v"}, code: 2, stderr: "invalid section name: my section.key"},
+		{name: "unknown flag", args: []string{"config", "--bogus"}, code: 2, stderr: "flag provided but not defined: -bogus"},
+		{name: "unknown command", args: []string{"bogus"}, code: 2, stderr: "invalid command bogus"},
+		{name: "missing argument", args: []string{"config", "get"}, code: 2, stderr: "usage: jit config get"},
+		{name: "unknown global flag", args: []string{"--bogus"}, code: 2},
+		{name: "define alias", args: []string{"config", "set", "alias.hi", "!echo hi"}},
+		{name: "shell alias", args: []string{"hi", "there"}, stdout: "hi there\n"},
+		{name: "hook list", args: []string{"hook", "list"}},
+		{name: "help", args: []string{"-h"}, stdout: "jit - A versatile version control system."},
+		{name: "help command", args: []string{"help", "config"}, stdout: "jit config [options] get [--all] <key>"},
+		{name: "command -h", args: []string{"hook", "-h"}, stderr: "jit-hook - Run or list hooks"},
+		{name: "unknown help topic", args: []string{"help", "bogus"}, code: 2, stderr: "no help topic for bogus"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := cmd.Run(tc.args, strings.NewReader(""), &stdout, &stderr)
+			if code != tc.code {
+				t.Errorf("Run(%v) = %d, want %d (stderr %q)", tc.args, code, tc.code, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tc.stdout) {
+				t.Errorf("Run(%v) stdout = %q, want %q", tc.args, stdout.String(), tc.stdout)
+			}
+			if !strings.Contains(stderr.String(), tc.stderr) {
+				t.Errorf("Run(%v) stderr = %q, want %q", tc.args, stderr.String(), tc.stderr)
 			}
+		})
+	}
+}
 
+func TestRunChangeDirectory(t *testing.T) {
+	tempDir, tempDirErr := os.MkdirTemp("", "changedir")
+	if tempDirErr != nil {
+		t.Fatalf("Failed to create temporary directory: %v", tempDirErr)
+	}
+	defer func(path string) {
+		_ = os.RemoveAll(path)
+	}(tempDir)
+	if err := os.MkdirAll(filepath.Join(tempDir, "project", "src"), 0755); err != nil {
+		t.Fatalf("Failed to create directories: %v", err)
+	}
+	cwd, _ := os.Getwd()
+
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stdout string
+	}{
+		{"init elsewhere", []string{"-C", filepath.Join(tempDir, "project"), "init", "-q"}, 0, ""},
+		{"set from a subdirectory", []string{"-C", filepath.Join(tempDir, "project"), "-C", "src", "config", "set", "user.name", "Ada"}, 0, ""},
+		{"empty path ignored", []string{"-C", "", "-C", filepath.Join(tempDir, "project"), "config", "get", "user.name"}, 0, "Ada\n"},
+		{"missing directory", []string{"-C", filepath.Join(tempDir, "missing"), "config", "--list"}, 1, ""},
+		{"set outside a repository", []string{"-C", tempDir, "config", "set", "user.name", "Ada"}, 128, ""},
+		{"unset outside a repository", []string{"-C", tempDir, "config", "--local", "unset", "user.name"}, 128, ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := cmd.Run(tc.args, strings.NewReader(""), &stdout, &stderr); code != tc.code {
+				t.Errorf("Run(%v) = %d, want %d (stderr %q)", tc.args, code, tc.code, stderr.String())
+			}
+			if stdout.String() != tc.stdout {
+				t.Errorf("Run(%v) stdout = %q, want %q", tc.args, stdout.String(), tc.stdout)
+			}
+			if after, _ := os.Getwd(); after != cwd {
+				t.Errorf("Run() left the working directory at %s", after)
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "project", ".jit")); err != nil {
+		t.Errorf("-C did not create the repository in the given directory: %v", err)
+	}
+}
+
+func TestRunReportsInvalidJitFile(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTestFile(t, filepath.Join(tempDir, ".jit"), "not a jit file\n")
+
+	for _, args := range [][]string{
+		{"-C", tempDir, "config", "--list"},
+		{"-C", tempDir, "some-alias"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := cmd.Run(args, strings.NewReader(""), &stdout, &stderr); code != 1 {
+			t.Errorf("Run(%v) = %d, want 1 (stderr %q)", args, code, stderr.String())
+		}
+		if !strings.Contains(stderr.String(), "invalid .jit file") {
+			t.Errorf("Run(%v) stderr = %q, want the .jit file error", args, stderr.String())
+		}
+	}
+}
+
+func TestRunRepositoryOptions(t *testing.T) {
+	tempDir, tempDirErr := os.MkdirTemp("", "jitdiroption")
+	if tempDirErr != nil {
+		t.Fatalf("Failed to create temporary directory: %v", tempDirErr)
+	}
+	defer func(path string) {
+		_ = os.RemoveAll(path)
+	}(tempDir)
+	t.Setenv("JIT_DIR", "")
+	t.Setenv("JIT_WORK_TREE", "")
+	if err := os.Unsetenv("JIT_DIR"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Unsetenv("JIT_WORK_TREE"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Mkdir(filepath.Join(tempDir, "project"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	var stdout, stderr bytes.Buffer
+	if code := cmd.Run([]string{"init", "-q", filepath.Join(tempDir, "project")}, strings.NewReader(""), &stdout, &stderr); code != 0 {
+		t.Fatalf("init failed: %s", stderr.String())
+	}
+
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stdout string
+	}{
+		{"set through --jit-dir", []string{"--jit-dir", filepath.Join(tempDir, "project", ".jit"), "config", "set", "user.name", "Ada"}, 0, ""},
+		{"relative to -C", []string{"-C", tempDir, "--jit-dir", "project/.jit", "--work-tree", "elsewhere", "config", "get", "user.name"}, 0, "Ada\n"},
+		{"missing repository", []string{"--jit-dir", filepath.Join(tempDir, "missing"), "hook", "list"}, 128, ""},
+		{"set a shell alias", []string{"--jit-dir", filepath.Join(tempDir, "project", ".jit"), "config", "set", "alias.where", "!echo \"$JIT_DIR\""}, 0, ""},
+		{"shell alias sees --jit-dir", []string{"-C", tempDir, "--jit-dir", "project/.jit", "where"}, 0, filepath.Join(tempDir, "project", ".jit") + "\n"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout.Reset()
+			stderr.Reset()
+			if code := cmd.Run(tc.args, strings.NewReader(""), &stdout, &stderr); code != tc.code {
+				t.Errorf("Run(%v) = %d, want %d (stderr %q)", tc.args, code, tc.code, stderr.String())
+			}
+			if stdout.String() != tc.stdout {
+				t.Errorf("Run(%v) stdout = %q, want %q", tc.args, stdout.String(), tc.stdout)
+			}
+			for _, name := range []string{"JIT_DIR", "JIT_WORK_TREE"} {
+				if value, set := os.LookupEnv(name); set {
+					t.Errorf("Run() left %s=%s in the environment", name, value)
+				}
+			}
+		})
+	}
+}
+
+func TestRunCallsMayOverlap(t *testing.T) {
+	tempDir := t.TempDir()
+	names := []string{"Ada", "Grace"}
+	for _, name := range names {
+		if err := os.Mkdir(filepath.Join(tempDir, name), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %s", err)
+		}
+		var stdout, stderr bytes.Buffer
+		if code := cmd.Run([]string{"init", "-q", filepath.Join(tempDir, name)}, strings.NewReader(""), &stdout, &stderr); code != 0 {
+			t.Fatalf("init failed: %s", stderr.String())
+		}
+		if code := cmd.Run([]string{"-C", filepath.Join(tempDir, name), "config", "set", "user.name", name}, strings.NewReader(""), &stdout, &stderr); code != 0 {
+			t.Fatalf("config set failed: %s", stderr.String())
+		}
+	}
+
+	// Each call resolves -C on its own, so concurrent calls read their own repositories.
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		name := names[i%len(names)]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var stdout, stderr bytes.Buffer
+			code := cmd.Run([]string{"-C", filepath.Join(tempDir, name), "config", "get", "user.name"}, strings.NewReader(""), &stdout, &stderr)
+			if code != 0 || stdout.String() != name+"\n" {
+				t.Errorf("Run(-C %s config get user.name) = %d, %q", name, code, stdout.String())
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestRunVerbosity(t *testing.T) {
+	tempDir, tempDirErr := os.MkdirTemp("", "verbosity")
+	if tempDirErr != nil {
+		t.Fatalf("Failed to create temporary directory: %v", tempDirErr)
+	}
+	defer func(path string) {
+		_ = os.RemoveAll(path)
+	}(tempDir)
+
+	tests := []struct {
+		name     string
+		flags    []string
+		code     int
+		contains []string
+		absent   []string
+	}{
+		{"normal", nil, 0, []string{"Successfully initialized"}, []string{"Created repository directory"}},
+		{"quiet", []string{"-q"}, 0, nil, []string{"Successfully initialized"}},
+		{"verbose", []string{"--verbose"}, 0, []string{"Created repository directory", "Successfully initialized"}, nil},
+		{"conflicting", []string{"--quiet", "--verbose"}, 2, []string{"cannot be used together"}, nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := filepath.Join(tempDir, tc.name)
+			if err := os.Mkdir(dir, 0755); err != nil {
+				t.Fatalf("Failed to create directory: %v", err)
+			}
+			var stdout, stderr bytes.Buffer
+			args := append(tc.flags, "init", dir)
+			if code := cmd.Run(args, strings.NewReader(""), &stdout, &stderr); code != tc.code {
+				t.Errorf("Run(%v) = %d, want %d (stderr %q)", args, code, tc.code, stderr.String())
+			}
+			for _, text := range tc.contains {
+				if !strings.Contains(stderr.String(), text) {
+					t.Errorf("Run(%v) stderr = %q, want it to contain %q", args, stderr.String(), text)
+				}
+			}
+			for _, text := range tc.absent {
+				if strings.Contains(stderr.String(), text) {
+					t.Errorf("Run(%v) stderr = %q, want it not to contain %q", args, stderr.String(), text)
+				}
+			}
+		})
+	}
+}
+
+func TestCommandHelpListsFlags(t *testing.T) {
+	tests := map[string][]string{
+		"init":   {"-b, --initial-branch <value>", "--bare", "--separate-jit-dir <value>", "-q, --quiet", "(default: main)"},
+		"config": {"--global", "-l, --list", "--add"},
+		"hook":   {"--ignore-missing"},
+		"fsck":   {"--repair", "jit fsck [--repair]"},
+		"branch": {"-m", "-C", "jit branch (-m | -M) [<old-branch>] <new-branch>"},
+		"diff":   {"--no-index", "-U, --unified <n>", "--histogram", "-M, --find-renames", "-C, --find-copies", "--word-diff", "--color-words", "-w, --ignore-all-space", "--ignore-blank-lines", "--binary", "--stat", "--numstat", "--shortstat", "-z", "jit diff --no-index [<options>] <path> <path>"},
+		"status": {"-u, --untracked-files", "-z", "jit status [-z] [-u<mode> | --untracked-files=<mode>]"},
+		"help":   {"jit help [<command>]"},
+	}
+
+	for command, expected := range tests {
+		t.Run(command, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := cmd.Run([]string{"help", command}, strings.NewReader(""), &stdout, &stderr); code != 0 {
+				t.Fatalf("jit help %s = %d (stderr %q)", command, code, stderr.String())
+			}
+			for _, text := range expected {
+				if !strings.Contains(stdout.String(), text) {
+					t.Errorf("jit help %s does not mention %q:\n%s", command, text, stdout.String())
+				}
+			}
 		})
 	}
 }
@@ -0,0 +1,180 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"jit/internal"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLockFile(t *testing.T) {
+	dir, err := os.MkdirTemp("", "lockfile_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "HEAD")
+	writeTestFile(t, file, "old\n")
+
+	lock, lockErr := internal.LockFile(file)
+	if lockErr != nil {
+		t.Fatalf("LockFile failed: %s", lockErr)
+	}
+	if _, secondErr := internal.LockFile(file); !errors.Is(secondErr, internal.ErrLocked) {
+		t.Errorf("Second LockFile() = %v, want ErrLocked", secondErr)
+	}
+	if writeErr := internal.WriteLocked(file, []byte("other\n")); !errors.Is(writeErr, internal.ErrLocked) {
+		t.Errorf("WriteLocked() on a locked file = %v, want ErrLocked", writeErr)
+	}
+	if commitErr := lock.Commit([]byte("new\n")); commitErr != nil {
+		t.Fatalf("Commit failed: %s", commitErr)
+	}
+	if content, _ := os.ReadFile(file); string(content) != "new\n" {
+		t.Errorf("File after Commit = %q, want %q", content, "new\n")
+	}
+	if _, statErr := os.Stat(file + internal.LockSuffix); !errors.Is(statErr, os.ErrNotExist) {
+		t.Errorf("Expected the lock file to be gone after Commit, got %v", statErr)
+	}
+
+	lock, lockErr = internal.LockFile(file)
+	if lockErr != nil {
+		t.Fatalf("LockFile after Commit failed: %s", lockErr)
+	}
+	if rollbackErr := lock.Rollback(); rollbackErr != nil {
+		t.Fatalf("Rollback failed: %s", rollbackErr)
+	}
+	if content, _ := os.ReadFile(file); string(content) != "new\n" {
+		t.Errorf("File after Rollback = %q, want it unchanged", content)
+	}
+
+	// A lock left by a crashed process is removed once it is old enough.
+	writeTestFile(t, file+internal.LockSuffix, "12345")
+	old := time.Now().Add(-2 * internal.StaleLockAge)
+	if chtimesErr := os.Chtimes(file+internal.LockSuffix, old, old); chtimesErr != nil {
+		t.Fatalf("Failed to age the lock file: %s", chtimesErr)
+	}
+	if writeErr := internal.WriteLocked(file, []byte("recovered\n")); writeErr != nil {
+		t.Errorf("WriteLocked() with a stale lock failed: %s", writeErr)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Expected only HEAD to be left after removing the stale lock, got %d entries", len(entries))
+	}
+}
+
+func TestStaleLockIsTakenOverOnce(t *testing.T) {
+	dir, err := os.MkdirTemp("", "lockfile_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "HEAD")
+	writeTestFile(t, file+internal.LockSuffix, "12345")
+	old := time.Now().Add(-2 * internal.StaleLockAge)
+	if chtimesErr := os.Chtimes(file+internal.LockSuffix, old, old); chtimesErr != nil {
+		t.Fatalf("Failed to age the lock file: %s", chtimesErr)
+	}
+
+	// Every process finds the same stale lock; only one may end up holding the file.
+	var wg sync.WaitGroup
+	locks := make(chan *internal.Lockfile, 8)
+	for i := 0; i < cap(locks); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if lock, lockErr := internal.LockFile(file); lockErr == nil {
+				locks <- lock
+			}
+		}()
+	}
+	wg.Wait()
+	close(locks)
+
+	held := 0
+	for lock := range locks {
+		held++
+		_ = lock.Rollback()
+	}
+	if held != 1 {
+		t.Errorf("%d processes took over the stale lock, want 1", held)
+	}
+}
+
+func TestConfigRespectsLock(t *testing.T) {
+	dir, err := os.MkdirTemp("", "config_lock_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	configPath := filepath.Join(dir, "config")
+
+	lock, lockErr := internal.LockFile(configPath)
+	if lockErr != nil {
+		t.Fatalf("LockFile failed: %s", lockErr)
+	}
+	if setErr := internal.SetConfigValue(configPath, "user.name", "Ada", false); !errors.Is(setErr, internal.ErrLocked) {
+		t.Errorf("SetConfigValue() on a locked config = %v, want ErrLocked", setErr)
+	}
+	_ = lock.Rollback()
+	if setErr := internal.SetConfigValue(configPath, "user.name", "Ada", false); setErr != nil {
+		t.Errorf("SetConfigValue() after Rollback failed: %s", setErr)
+	}
+}
+
+func TestRepositoryWritesAreAtomic(t *testing.T) {
+	lockFiles(t)
+	dir, err := os.MkdirTemp("", "atomic_write_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	traceFile := filepath.Join(dir, "trace.log")
+	jitDir := filepath.Join(dir, ".jit")
+	if mkErr := os.MkdirAll(filepath.Join(jitDir, "branches"), 0755); mkErr != nil {
+		t.Fatalf("Failed to create directories: %s", mkErr)
+	}
+
+	if enableErr := internal.EnableTrace(traceFile); enableErr != nil {
+		t.Fatalf("EnableTrace failed: %s", enableErr)
+	}
+	if _, writeErr := internal.WriteToConfigFile(map[string]string{"init.defaultBranch": "main"}, jitDir); writeErr != nil {
+		t.Errorf("WriteToConfigFile failed: %s", writeErr)
+	}
+	if _, setupErr := internal.SetUpInitialBranch(jitDir, "main"); setupErr != nil {
+		t.Errorf("SetUpInitialBranch failed: %s", setupErr)
+	}
+	internal.DisableTrace()
+
+	content, readErr := os.ReadFile(traceFile)
+	if readErr != nil {
+		t.Fatalf("Failed to read trace: %s", readErr)
+	}
+	renamed := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		var event internal.TraceEvent
+		if jsonErr := json.Unmarshal([]byte(line), &event); jsonErr != nil {
+			t.Fatalf("trace line is not JSON: %q", line)
+		}
+		path, _ := event.Data["path"].(string)
+		switch event.Name {
+		case "write":
+			if !strings.HasSuffix(path, internal.LockSuffix) {
+				t.Errorf("%s was written in place instead of through its lock file", path)
+			}
+			if event.Data["sync"] != true {
+				t.Errorf("%s was not flushed before being renamed", path)
+			}
+		case "rename":
+			renamed[filepath.Base(path)] = true
+		}
+	}
+	for _, name := range []string{"config", "head", "main"} {
+		if !renamed[name] {
+			t.Errorf("%s was not renamed into place", name)
+		}
+	}
+}
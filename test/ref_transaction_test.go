@@ -0,0 +1,119 @@
+package test
+
+import (
+	"errors"
+	"jit/internal"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRefTransaction(t *testing.T) {
+	jitDir, err := os.MkdirTemp("", "ref_transaction_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(jitDir)
+	branch := func(name string) string {
+		return filepath.Join(jitDir, "branches", filepath.FromSlash(name))
+	}
+	writeTestFile(t, branch("main"), "c1")
+	writeTestFile(t, branch("old"), "c0")
+
+	tests := []struct {
+		name     string
+		queue    func(tx *internal.RefTransaction)
+		err      error
+		expected map[string]string // Branch contents after the transaction; "-" for a missing branch.
+	}{
+		{
+			name: "stale expected value changes nothing",
+			queue: func(tx *internal.RefTransaction) {
+				tx.Create("feature/login", "c2")
+				tx.Update("main", "c2", "c0")
+			},
+			err:      internal.ErrRefConflict,
+			expected: map[string]string{"main": "c1", "feature/login": "-"},
+		},
+		{
+			name: "create of an existing ref is rejected",
+			queue: func(tx *internal.RefTransaction) {
+				tx.Update("main", "c2", "c1")
+				tx.Create("old", "c2")
+			},
+			err:      internal.ErrRefConflict,
+			expected: map[string]string{"main": "c1", "old": "c0"},
+		},
+		{
+			name: "delete of a missing ref is rejected",
+			queue: func(tx *internal.RefTransaction) {
+				tx.Delete("missing/branch", "c0")
+			},
+			err:      internal.ErrRefConflict,
+			expected: map[string]string{"main": "c1"},
+		},
+		{
+			name: "invalid ref name is rejected",
+			queue: func(tx *internal.RefTransaction) {
+				tx.Update("main", "c2", "c1")
+				tx.Create("../config", "c2")
+			},
+			err:      internal.ErrInvalidRefName,
+			expected: map[string]string{"main": "c1"},
+		},
+		{
+			name: "all updates applied",
+			queue: func(tx *internal.RefTransaction) {
+				tx.Update("main", "c2", "c1")
+				tx.Create("feature/login", "c2")
+				tx.Delete("old", "c0")
+			},
+			expected: map[string]string{"main": "c2", "feature/login": "c2", "old": "-"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := internal.NewRefTransaction(jitDir)
+			tt.queue(tx)
+			if commitErr := tx.Commit(); !errors.Is(commitErr, tt.err) || (tt.err == nil && commitErr != nil) {
+				t.Fatalf("Commit() = %v, want %v", commitErr, tt.err)
+			}
+			for name, want := range tt.expected {
+				content, readErr := os.ReadFile(branch(name))
+				got := string(content)
+				if errors.Is(readErr, os.ErrNotExist) {
+					got = "-"
+				}
+				if got != want {
+					t.Errorf("branch %s = %q, want %q", name, got, want)
+				}
+				if _, statErr := os.Stat(branch(name) + internal.LockSuffix); statErr == nil {
+					t.Errorf("lock file of %s left behind", name)
+				}
+			}
+		})
+	}
+
+	locked, lockErr := internal.LockFile(branch("main"))
+	if lockErr != nil {
+		t.Fatalf("LockFile failed: %s", lockErr)
+	}
+	tx := internal.NewRefTransaction(jitDir)
+	tx.Update("feature/login", "c3", "c2")
+	tx.Update("main", "c3", "c2")
+	if commitErr := tx.Commit(); !errors.Is(commitErr, internal.ErrLocked) {
+		t.Errorf("Commit() with a locked ref = %v, want ErrLocked", commitErr)
+	}
+	_ = locked.Rollback()
+	if content, _ := os.ReadFile(branch("feature/login")); string(content) != "c2" {
+		t.Errorf("feature/login changed by a failed transaction: %q", content)
+	}
+
+	tx = internal.NewRefTransaction(jitDir)
+	tx.Update("main", "c3", "c2")
+	tx.Update("main", "c4", "c3")
+	if commitErr := tx.Commit(); commitErr == nil {
+		t.Errorf("Expected two updates of the same ref to be rejected")
+	}
+}
@@ -0,0 +1,27 @@
+package test
+
+import (
+	"errors"
+	"jit/internal"
+	"testing"
+)
+
+func TestCheckRefName(t *testing.T) {
+	valid := []string{"main", "feature/login", "release-1.0", "v2", "fix_bug", "user@host", "a.b/c.d", "console", "com10", "nullable"}
+	for _, name := range valid {
+		if err := internal.CheckRefName(name); err != nil {
+			t.Errorf("CheckRefName(%q) = %v, want nil", name, err)
+		}
+	}
+
+	invalid := []string{
+		"", "@", "-branch", "has space", "a..b", "../config", "/etc/passwd", "trailing/", "a//b",
+		"ends.", "main.lock", "feature/x.lock/y", ".hidden", "dir/.hidden", "tab\tname", "del\x7f",
+		"a~1", "a^", "a:b", "what?", "star*", "[x", "back\\slash", "head@{1}", "nul", "CON", "feature/aux.txt", "com1", "LPT9.log",
+	}
+	for _, name := range invalid {
+		if err := internal.CheckRefName(name); !errors.Is(err, internal.ErrInvalidRefName) {
+			t.Errorf("CheckRefName(%q) = %v, want ErrInvalidRefName", name, err)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package test
+
+import (
+	"jit/internal"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenameAndCopyBranchConfig(t *testing.T) {
+	dir, err := os.MkdirTemp("", "remote_config_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	configFile := filepath.Join(dir, "config")
+	writeTestFile(t, configFile, "[branch \"main\"]\n\tremote = origin\n\tmerge = refs/heads/main\n[branch \"trunk\"]\n\tremote = stale\n")
+
+	get := func(key string) string {
+		config, loadErr := internal.LoadConfigFile(configFile, internal.ScopeLocal)
+		if loadErr != nil {
+			t.Fatalf("LoadConfigFile failed: %s", loadErr)
+		}
+		value, _ := config.Get(key)
+		return value
+	}
+
+	if copyErr := internal.CopyBranchConfig(configFile, "main", "backup"); copyErr != nil {
+		t.Fatalf("CopyBranchConfig failed: %s", copyErr)
+	}
+	if get("branch.backup.remote") != "origin" || get("branch.backup.merge") != "refs/heads/main" || get("branch.main.remote") != "origin" {
+		t.Errorf("CopyBranchConfig() did not copy the section of main")
+	}
+
+	if renameErr := internal.RenameBranchConfig(configFile, "main", "trunk"); renameErr != nil {
+		t.Fatalf("RenameBranchConfig failed: %s", renameErr)
+	}
+	if get("branch.trunk.remote") != "origin" || get("branch.main.remote") != "" {
+		t.Errorf("RenameBranchConfig() did not move the section of main over trunk")
+	}
+
+	if renameErr := internal.RenameBranchConfig(configFile, "missing", "other"); renameErr != nil {
+		t.Errorf("RenameBranchConfig() of a branch without a section = %v, want nil", renameErr)
+	}
+}
@@ -0,0 +1,114 @@
+package test
+
+import (
+	"jit/internal/diff"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSimilarityThreshold(t *testing.T) {
+	tests := []struct {
+		value    string
+		wantErr  bool
+		expected int
+	}{
+		{"", false, 50},
+		{"5", false, 50},
+		{"05", false, 5},
+		{"75%", false, 75},
+		{"100%", false, 100},
+		{"101%", true, 0},
+		{"abc", true, 0},
+		{"-5", true, 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.value, func(t *testing.T) {
+			threshold, err := diff.ParseSimilarityThreshold(tc.value)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseSimilarityThreshold(%q) error = %v, wantErr %v", tc.value, err, tc.wantErr)
+			}
+			if threshold != tc.expected {
+				t.Errorf("ParseSimilarityThreshold(%q) = %d, want %d", tc.value, threshold, tc.expected)
+			}
+		})
+	}
+}
+
+func TestDetectRenames(t *testing.T) {
+	body := strings.Repeat("a line of the readme that does not change\n", 10)
+
+	deleted := map[string]string{
+		"docs/README.md": body,
+		"src/util.go":    "package util\n\nfunc A() {}\n\nfunc B() {}\n",
+		"old/unrelated":  "completely different\n",
+	}
+	added := map[string]string{
+		"README.md":        body + "one more line\n",
+		"pkg/util/util.go": "package util\n\nfunc A() {}\n\nfunc B() {}\n",
+		"new/other":        "nothing in common here\n",
+	}
+
+	renames, remainingDeleted, remainingAdded := diff.DetectRenames(deleted, added, diff.DefaultRenameThreshold)
+
+	expected := []diff.Rename{
+		{OldPath: "src/util.go", NewPath: "pkg/util/util.go", Similarity: 100},
+	}
+	if len(renames) != 2 {
+		t.Fatalf("Expected 2 renames, got %+v", renames)
+	}
+	if renames[1] != expected[0] {
+		t.Errorf("Expected exact rename %+v, got %+v", expected[0], renames[1])
+	}
+	if renames[0].OldPath != "docs/README.md" || renames[0].NewPath != "README.md" || renames[0].Similarity < 90 || renames[0].Similarity == 100 {
+		t.Errorf("Expected docs/README.md -> README.md with high similarity, got %+v", renames[0])
+	}
+	if !reflect.DeepEqual(remainingDeleted, []string{"old/unrelated"}) {
+		t.Errorf("Expected old/unrelated to remain deleted, got %v", remainingDeleted)
+	}
+	if !reflect.DeepEqual(remainingAdded, []string{"new/other"}) {
+		t.Errorf("Expected new/other to remain added, got %v", remainingAdded)
+	}
+}
+
+func TestDetectRenamesPrefersSameName(t *testing.T) {
+	content := "identical\n"
+	deleted := map[string]string{"a/config.yml": content}
+	added := map[string]string{"b/settings.yml": content, "c/config.yml": content}
+
+	renames, _, remainingAdded := diff.DetectRenames(deleted, added, diff.DefaultRenameThreshold)
+	if len(renames) != 1 || renames[0].NewPath != "c/config.yml" {
+		t.Errorf("Expected rename to c/config.yml, got %+v", renames)
+	}
+	if !reflect.DeepEqual(remainingAdded, []string{"b/settings.yml"}) {
+		t.Errorf("Expected b/settings.yml to remain added, got %v", remainingAdded)
+	}
+}
+
+func TestDetectCopies(t *testing.T) {
+	license := strings.Repeat("Permission is hereby granted, free of charge\n", 20)
+	sources := map[string]string{
+		"LICENSE":     license,
+		"src/main.go": "package main\n",
+	}
+	added := map[string]string{
+		"vendor/a/LICENSE": license,
+		"vendor/b/COPYING": license + "with an extra clause\n",
+		"notes.txt":        "unrelated\n",
+	}
+
+	copies, remainingAdded := diff.DetectCopies(sources, added, diff.DefaultRenameThreshold)
+	if len(copies) != 2 {
+		t.Fatalf("Expected 2 copies, got %+v", copies)
+	}
+	if copies[0] != (diff.Copy{OldPath: "LICENSE", NewPath: "vendor/a/LICENSE", Similarity: 100}) {
+		t.Errorf("Unexpected exact copy %+v", copies[0])
+	}
+	if copies[1].OldPath != "LICENSE" || copies[1].NewPath != "vendor/b/COPYING" || copies[1].Similarity < 90 {
+		t.Errorf("Unexpected modified copy %+v", copies[1])
+	}
+	if !reflect.DeepEqual(remainingAdded, []string{"notes.txt"}) {
+		t.Errorf("Expected notes.txt to remain added, got %v", remainingAdded)
+	}
+}
@@ -0,0 +1,124 @@
+package test
+
+import (
+	"errors"
+	"jit/pkg/repo"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRepositoryInitAndOpen(t *testing.T) {
+	tempDir, tempDirErr := os.MkdirTemp("", "repo")
+	if tempDirErr != nil {
+		t.Fatalf("Failed to create temporary directory: %v", tempDirErr)
+	}
+	defer func(path string) {
+		_ = os.RemoveAll(path)
+	}(tempDir)
+	tempDir, _ = filepath.EvalSymlinks(tempDir)
+
+	workTree := filepath.Join(tempDir, "project")
+	if mkdirErr := os.MkdirAll(filepath.Join(workTree, "src", "pkg"), 0755); mkdirErr != nil {
+		t.Fatalf("Failed to create work tree: %v", mkdirErr)
+	}
+
+	created, initErr := repo.Init(workTree, repo.InitOptions{InitialBranch: "trunk"})
+	if initErr != nil {
+		t.Fatalf("Init failed: %v", initErr)
+	}
+	if created.WorkTree() != workTree || created.JitDir() != filepath.Join(workTree, ".jit") || created.IsBare() {
+		t.Errorf("Init() = %q %q, want the project work tree", created.JitDir(), created.WorkTree())
+	}
+
+	opened, openErr := repo.Open(filepath.Join(workTree, "src", "pkg"))
+	if openErr != nil {
+		t.Fatalf("Open failed: %v", openErr)
+	}
+	if opened.JitDir() != created.JitDir() {
+		t.Errorf("Open() = %q, want %q", opened.JitDir(), created.JitDir())
+	}
+	if branch, branchErr := opened.CurrentBranch(); branchErr != nil || branch != "trunk" {
+		t.Errorf("CurrentBranch() = %q, %v, want trunk", branch, branchErr)
+	}
+	if value, ok, configErr := opened.Config("init.defaultBranch"); configErr != nil || !ok || value != "trunk" {
+		t.Errorf("Config(init.defaultBranch) = %q, %v, %v", value, ok, configErr)
+	}
+
+	bareDir := filepath.Join(tempDir, "bare")
+	if mkdirErr := os.Mkdir(bareDir, 0755); mkdirErr != nil {
+		t.Fatalf("Failed to create bare directory: %v", mkdirErr)
+	}
+	bare, bareErr := repo.Init(bareDir, repo.InitOptions{Bare: true})
+	if bareErr != nil {
+		t.Fatalf("Init bare failed: %v", bareErr)
+	}
+	if !bare.IsBare() || bare.JitDir() != bareDir {
+		t.Errorf("Init(bare) = %q %q, want a bare repository", bare.JitDir(), bare.WorkTree())
+	}
+
+	if _, err := repo.Open(tempDir); !errors.Is(err, repo.ErrNotARepository) {
+		t.Errorf("Open() outside a repository = %v, want ErrNotARepository", err)
+	}
+}
+
+func TestRefIter(t *testing.T) {
+	workTree, tempDirErr := os.MkdirTemp("", "repo")
+	if tempDirErr != nil {
+		t.Fatalf("Failed to create temporary directory: %v", tempDirErr)
+	}
+	defer func(path string) {
+		_ = os.RemoveAll(path)
+	}(workTree)
+
+	repository, initErr := repo.Init(workTree, repo.InitOptions{})
+	if initErr != nil {
+		t.Fatalf("Init failed: %v", initErr)
+	}
+	branches := filepath.Join(repository.JitDir(), "branches")
+	writeTestFile(t, filepath.Join(branches, "feature", "login"), "1111111111111111111111111111111111111111\n")
+	writeTestFile(t, filepath.Join(branches, "feature", "api"), "2222222222222222222222222222222222222222\n")
+	writeTestFile(t, filepath.Join(branches, "dev"), "")
+
+	collect := func(iter *repo.RefIter, limit int) (names []string) {
+		err := iter.ForEach(func(ref repo.Ref) error {
+			if len(names) == limit {
+				return repo.ErrStop
+			}
+			names = append(names, ref.Name+"="+ref.Target)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("ForEach failed: %v", err)
+		}
+		return names
+	}
+
+	tests := []struct {
+		name     string
+		filter   func(repo.Ref) bool
+		limit    int
+		expected string
+	}{
+		{"all branches in order", nil, -1, "dev= feature/api=2222222222222222222222222222222222222222 feature/login=1111111111111111111111111111111111111111 main="},
+		{"filtered", func(ref repo.Ref) bool { return strings.HasPrefix(ref.Name, "feature/") }, -1,
+			"feature/api=2222222222222222222222222222222222222222 feature/login=1111111111111111111111111111111111111111"},
+		{"stopped early", nil, 2, "dev= feature/api=2222222222222222222222222222222222222222"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			iter, iterErr := repository.Branches()
+			if iterErr != nil {
+				t.Fatalf("Branches failed: %v", iterErr)
+			}
+			if tc.filter != nil {
+				iter = iter.Filter(tc.filter)
+			}
+			if got := strings.Join(collect(iter, tc.limit), " "); got != tc.expected {
+				t.Errorf("refs = %q, want %q", got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package test
+
+import (
+	"errors"
+	"io/fs"
+	"jit/internal"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestParseSharedRepository(t *testing.T) {
+	tests := map[string]fs.FileMode{"": 0, "umask": 0, "false": 0, "group": 0660, "true": 0660, "all": 0664, "0640": 0640, "0777": 0666}
+	for value, want := range tests {
+		if mode, err := internal.ParseSharedRepository(value); err != nil || mode != want {
+			t.Errorf("ParseSharedRepository(%q) = %o, %v, want %o", value, mode, err, want)
+		}
+	}
+	for _, value := range []string{"friends", "0460", "01777"} {
+		if _, err := internal.ParseSharedRepository(value); !errors.Is(err, internal.ErrInvalidSharedMode) {
+			t.Errorf("ParseSharedRepository(%q) = %v, want ErrInvalidSharedMode", value, err)
+		}
+	}
+}
+
+func TestSharedRepositoryPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not supported on Windows")
+	}
+	workTree, err := os.MkdirTemp("", "shared_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(workTree)
+
+	options := map[string]any{"quiet": true, "shared": "group"}
+	if _, err := internal.InitializeJitRepository(options, workTree); err != nil {
+		t.Fatalf("InitializeJitRepository failed: %s", err)
+	}
+	jitDir := filepath.Join(workTree, ".jit")
+
+	// Files written and directories created after init keep the shared modes.
+	if err := internal.SetConfigValue(filepath.Join(jitDir, "config"), "user.name", "Ada", false); err != nil {
+		t.Fatalf("SetConfigValue failed: %s", err)
+	}
+	transaction := internal.NewRefTransaction(jitDir)
+	transaction.Create("feature/login", "c1")
+	if err := transaction.Commit(); err != nil {
+		t.Fatalf("Commit failed: %s", err)
+	}
+
+	wantDir := fs.ModeDir | fs.ModeSetgid | 0770
+	for _, dir := range []string{"", "branches", "hooks", "branches/feature"} {
+		if info, _ := os.Stat(filepath.Join(jitDir, dir)); info == nil || info.Mode() != wantDir {
+			t.Errorf("mode of %q = %v, want %v", dir, info.Mode(), wantDir)
+		}
+	}
+	for _, file := range []string{"config", "head", "stage", "branches/main", "branches/feature/login"} {
+		if info, _ := os.Stat(filepath.Join(jitDir, file)); info == nil || info.Mode() != 0660 {
+			t.Errorf("mode of %s = %v, want %v", file, info.Mode(), fs.FileMode(0660))
+		}
+	}
+	if value, _, _ := mustOpen(t, workTree).Config("core.sharedRepository"); value != "group" {
+		t.Errorf("core.sharedRepository = %q, want group", value)
+	}
+}
+
+func TestInitPermissionAppliesToFiles(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not supported on Windows")
+	}
+	workTree, err := os.MkdirTemp("", "shared_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(workTree)
+
+	if _, err := internal.CreateJitDir(workTree, false, false, 0700); err != nil {
+		t.Fatalf("CreateJitDir failed: %s", err)
+	}
+	if info, _ := os.Stat(filepath.Join(workTree, ".jit", "stage")); info == nil || info.Mode().Perm() != 0600 {
+		t.Errorf("stage of a repository created with --perm 0700 has mode %v, want 0600", info.Mode())
+	}
+}
@@ -0,0 +1,80 @@
+package test
+
+import (
+	"jit/internal/diff"
+	"strings"
+	"testing"
+)
+
+func TestStat(t *testing.T) {
+	stats := []diff.FileStat{
+		{Path: "README.md", Insertions: 3, Deletions: 1},
+		{Path: "internal/diff/stat.go", Insertions: 10},
+		{Path: "logo.png", Binary: true, OldSize: 120, NewSize: 340},
+	}
+
+	expected := "" +
+		" README.md             |   4 +++-\n" +
+		" internal/diff/stat.go |  10 ++++++++++\n" +
+		" logo.png              | Bin 120 -> 340 bytes\n" +
+		" 3 files changed, 13 insertions(+), 1 deletion(-)\n"
+	if got := diff.Stat(stats, diff.DefaultStatWidth); got != expected {
+		t.Errorf("Stat() =\n%s\nwant\n%s", got, expected)
+	}
+}
+
+func TestStatScalesBars(t *testing.T) {
+	stats := []diff.FileStat{
+		{Path: "big.txt", Insertions: 1000, Deletions: 1000},
+		{Path: "small.txt", Insertions: 1},
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(diff.Stat(stats, 60), "\n"), "\n") {
+		if len(line) > 60 {
+			t.Errorf("Line exceeds width 60: %q", line)
+		}
+	}
+	lines := strings.Split(diff.Stat(stats, 60), "\n")
+	if !strings.HasSuffix(lines[1], "1 +") {
+		t.Errorf("Expected a small change to keep a visible bar, got %q", lines[1])
+	}
+	if strings.Count(lines[0], "+") != strings.Count(lines[0], "-") {
+		t.Errorf("Expected a balanced bar for equal insertions and deletions, got %q", lines[0])
+	}
+}
+
+func TestNumstatAndShortstat(t *testing.T) {
+	stats := []diff.FileStat{
+		{Path: "a.txt", Insertions: 2, Deletions: 0},
+		{Path: "b.bin", Binary: true},
+	}
+	if got := diff.Numstat(stats); got != "2\t0\ta.txt\n-\t-\tb.bin\n" {
+		t.Errorf("Numstat() = %q", got)
+	}
+	renamed := append(stats, diff.FileStat{Path: "{old => new}/c.txt", OldPath: "old/c.txt", NewPath: "new/c.txt", Insertions: 1})
+	if got := diff.NumstatZ(renamed); got != "2\t0\ta.txt\x00-\t-\tb.bin\x001\t0\t\x00old/c.txt\x00new/c.txt\x00" {
+		t.Errorf("NumstatZ() = %q", got)
+	}
+
+	tests := []struct {
+		stats    []diff.FileStat
+		expected string
+	}{
+		{stats, " 2 files changed, 2 insertions(+)\n"},
+		{[]diff.FileStat{{Path: "x", Deletions: 1}}, " 1 file changed, 1 deletion(-)\n"},
+		{[]diff.FileStat{{Path: "x"}}, " 1 file changed, 0 insertions(+), 0 deletions(-)\n"},
+		{nil, ""},
+	}
+	for _, tc := range tests {
+		if got := diff.Shortstat(tc.stats); got != tc.expected {
+			t.Errorf("Shortstat() = %q, want %q", got, tc.expected)
+		}
+	}
+}
+
+func TestCountChanges(t *testing.T) {
+	insertions, deletions := diff.CountChanges(diff.Lines("a\nb\nc\n", "a\nB\nc\nd\n"))
+	if insertions != 2 || deletions != 1 {
+		t.Errorf("CountChanges() = %d, %d; want 2, 1", insertions, deletions)
+	}
+}
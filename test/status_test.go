@@ -0,0 +1,136 @@
+package test
+
+import (
+	"bytes"
+	"jit/cmd"
+	"jit/internal"
+	"jit/pkg/util"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newStatusTestRepo creates a repository with untracked files, an ignored file and a deep
+// untracked directory.
+func newStatusTestRepo(t *testing.T) (workTree string) {
+	workTree, err := os.MkdirTemp("", "status_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(workTree) })
+	t.Setenv(util.EnvConfigGlobal, filepath.Join(workTree, "gitconfig"))
+	t.Setenv("XDG_CONFIG_HOME", workTree)
+
+	if _, err := internal.InitializeJitRepository(map[string]any{"quiet": true}, workTree); err != nil {
+		t.Fatalf("InitializeJitRepository failed: %s", err)
+	}
+	writeTestFile(t, filepath.Join(workTree, ".jitignore"), "*.log\ngitconfig\n")
+	writeTestFile(t, filepath.Join(workTree, "main.go"), "package main\n")
+	writeTestFile(t, filepath.Join(workTree, "debug.log"), "ignored\n")
+	writeTestFile(t, filepath.Join(workTree, "node_modules", "left-pad", "index.js"), "module.exports = 1\n")
+	writeTestFile(t, filepath.Join(workTree, "node_modules", "left-pad", "package.json"), "{}\n")
+	return workTree
+}
+
+func runStatus(t *testing.T, dir string, args ...string) (code int, stdout string) {
+	t.Helper()
+	var out, stderr bytes.Buffer
+	code = cmd.Run(append([]string{"-C", dir, "status"}, args...), strings.NewReader(""), &out, &stderr)
+	return code, out.String()
+}
+
+func TestStatusUntrackedFiles(t *testing.T) {
+	workTree := newStatusTestRepo(t)
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"normal by default", nil, "On branch main\n\nUntracked files:\n\t.jitignore\n\tmain.go\n\tnode_modules/\n"},
+		{"normal", []string{"--untracked-files=normal"}, "On branch main\n\nUntracked files:\n\t.jitignore\n\tmain.go\n\tnode_modules/\n"},
+		{"all", []string{"-uall"}, "On branch main\n\nUntracked files:\n\t.jitignore\n\tmain.go\n\tnode_modules/left-pad/index.js\n\tnode_modules/left-pad/package.json\n"},
+		{"-u alone means all", []string{"-u"}, "\tnode_modules/left-pad/index.js\n"},
+		{"no", []string{"-uno"}, "On branch main\n\nnothing to commit (use -u to show untracked files)\n"},
+		{"long option with one dash", []string{"-untracked-files=no"}, "nothing to commit (use -u to show untracked files)\n"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			code, stdout := runStatus(t, workTree, tc.args...)
+			if code != 0 {
+				t.Fatalf("jit status %v = %d", tc.args, code)
+			}
+			if !strings.Contains(stdout, tc.want) {
+				t.Errorf("jit status %v =\n%s\nwant\n%s", tc.args, stdout, tc.want)
+			}
+		})
+	}
+}
+
+func TestStatusReadsShowUntrackedFiles(t *testing.T) {
+	workTree := newStatusTestRepo(t)
+
+	writeTestFile(t, filepath.Join(workTree, "gitconfig"), "[status]\n\tshowUntrackedFiles = no\n")
+	if _, stdout := runStatus(t, workTree); strings.Contains(stdout, "Untracked files:") {
+		t.Errorf("jit status with status.showUntrackedFiles = no lists untracked files:\n%s", stdout)
+	}
+	if _, stdout := runStatus(t, workTree, "-unormal"); !strings.Contains(stdout, "\tnode_modules/\n") {
+		t.Errorf("-unormal does not override status.showUntrackedFiles:\n%s", stdout)
+	}
+
+	writeTestFile(t, filepath.Join(workTree, "gitconfig"), "[status]\n\tshowUntrackedFiles = some\n")
+	if code, _ := runStatus(t, workTree); code != 1 {
+		t.Errorf("jit status with an invalid status.showUntrackedFiles = %d, want 1", code)
+	}
+}
+
+func TestStatusPathsAreRelativeToTheCurrentDirectory(t *testing.T) {
+	workTree := newStatusTestRepo(t)
+	writeTestFile(t, filepath.Join(workTree, "src", "app.go"), "package src\n")
+
+	if _, stdout := runStatus(t, filepath.Join(workTree, "node_modules"), "-uall"); !strings.Contains(stdout, "\t../main.go\n\tleft-pad/index.js\n") {
+		t.Errorf("jit status from node_modules =\n%s", stdout)
+	}
+	if _, stdout := runStatus(t, filepath.Join(workTree, "node_modules")); !strings.Contains(stdout, "\t./\n\t../src/\n") {
+		t.Errorf("jit status from a collapsed directory =\n%s", stdout)
+	}
+}
+
+func TestStatusNulTerminated(t *testing.T) {
+	workTree := newStatusTestRepo(t)
+	writeTestFile(t, filepath.Join(workTree, "new\nline.txt"), "x\n")
+
+	_, stdout := runStatus(t, filepath.Join(workTree, "node_modules"), "-z", "-uall")
+	want := "?? .jitignore\x00?? main.go\x00?? new\nline.txt\x00?? node_modules/left-pad/index.js\x00?? node_modules/left-pad/package.json\x00"
+	if stdout != want {
+		t.Errorf("jit status -z -uall = %q, want %q", stdout, want)
+	}
+}
+
+func TestStatusErrors(t *testing.T) {
+	workTree := newStatusTestRepo(t)
+	outside, err := os.MkdirTemp("", "status_outside")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(outside) })
+
+	tests := []struct {
+		name string
+		dir  string
+		args []string
+		code int
+	}{
+		{"unknown mode", workTree, []string{"--untracked-files=some"}, 2},
+		{"pathspec", workTree, []string{"main.go"}, 2},
+		{"outside a repository", outside, nil, 128},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if code, _ := runStatus(t, tc.dir, tc.args...); code != tc.code {
+				t.Errorf("jit status %v = %d, want %d", tc.args, code, tc.code)
+			}
+		})
+	}
+}
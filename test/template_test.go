@@ -0,0 +1,60 @@
+package test
+
+import (
+	"jit/internal"
+	"jit/pkg/util"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInitializeWithTemplate(t *testing.T) {
+	templateDir, err := os.MkdirTemp("", "template_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(templateDir)
+
+	hook := filepath.Join(templateDir, util.HOOKS, internal.HookPreCommit)
+	writeTestFile(t, hook, "#!/bin/sh\nexit 0\n")
+	if chmodErr := os.Chmod(hook, 0755); chmodErr != nil {
+		t.Fatalf("Failed to chmod hook: %s", chmodErr)
+	}
+	writeTestFile(t, filepath.Join(templateDir, util.INFO, util.EXCLUDE), "*.log\n")
+	writeTestFile(t, filepath.Join(templateDir, "description"), "Template repository\n")
+	writeTestFile(t, filepath.Join(templateDir, util.CONFIG), "[core]\n\tbare = true\n")
+
+	repoDir, err := os.MkdirTemp("", "template_repo")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(repoDir)
+
+	options := map[string]any{"quiet": true, "template": templateDir, "initial-branch": "main", "perm": "0755"}
+	if _, initErr := internal.InitializeJitRepository(options, repoDir); initErr != nil {
+		t.Fatalf("InitializeJitRepository failed: %s", initErr)
+	}
+	jitDir := filepath.Join(repoDir, util.JitDirName)
+
+	info, statErr := os.Stat(filepath.Join(jitDir, util.HOOKS, internal.HookPreCommit))
+	if statErr != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("Expected the hook to be copied as executable, got %v, %v", info, statErr)
+	}
+	for file, expected := range map[string]string{
+		filepath.Join(util.INFO, util.EXCLUDE): "*.log\n",
+		"description":                          "Template repository\n",
+	} {
+		content, readErr := os.ReadFile(filepath.Join(jitDir, file))
+		if readErr != nil || string(content) != expected {
+			t.Errorf("%s = %q, %v; want %q", file, content, readErr, expected)
+		}
+	}
+
+	config, loadErr := internal.LoadConfigFile(filepath.Join(jitDir, util.CONFIG), internal.ScopeLocal)
+	if loadErr != nil {
+		t.Fatalf("LoadConfigFile failed: %s", loadErr)
+	}
+	if _, ok := config.Get("core.bare"); ok {
+		t.Errorf("Expected the template config not to be copied")
+	}
+}
@@ -0,0 +1,82 @@
+package test
+
+import (
+	"bufio"
+	"encoding/json"
+	"jit/internal"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	lockFiles(t)
+	tempDir, tempDirErr := os.MkdirTemp("", "trace")
+	if tempDirErr != nil {
+		t.Fatalf("Failed to create temporary directory: %v", tempDirErr)
+	}
+	defer func(path string) {
+		_ = os.RemoveAll(path)
+	}(tempDir)
+
+	if err := internal.EnableTrace("trace.log"); err == nil {
+		internal.DisableTrace()
+		t.Errorf("EnableTrace() accepted a relative path")
+	}
+
+	traceFile := filepath.Join(tempDir, "trace.log")
+	configFile := filepath.Join(tempDir, "config")
+	writeTestFile(t, configFile, "[core]\n\teditor = vi\n")
+
+	if err := internal.EnableTrace(traceFile); err != nil {
+		t.Fatalf("EnableTrace failed: %v", err)
+	}
+	internal.TraceStart([]string{"jit", "config", "get", "core.editor"})
+	leave := internal.TraceRegion("command", "config")
+	if _, err := internal.LoadConfigFile(configFile, internal.ScopeLocal); err != nil {
+		t.Fatalf("LoadConfigFile failed: %v", err)
+	}
+	leave()
+	internal.TraceExit(0)
+	internal.DisableTrace()
+
+	if internal.TraceEnabled() {
+		t.Errorf("TraceEnabled() = true after DisableTrace")
+	}
+	if _, isOS := internal.Files.(internal.OSFileSystem); !isOS {
+		t.Errorf("DisableTrace() did not restore the filesystem, Files = %T", internal.Files)
+	}
+
+	file, openErr := os.Open(traceFile)
+	if openErr != nil {
+		t.Fatalf("Failed to open trace: %v", openErr)
+	}
+	defer func() { _ = file.Close() }()
+
+	var events []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event internal.TraceEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("trace line is not JSON: %q", scanner.Text())
+		}
+		if event.Time == "" {
+			t.Errorf("event without a time: %q", scanner.Text())
+		}
+		name := event.Event + ":" + event.Category + ":" + event.Name
+		if event.Category == "fs" && event.Data["path"] != configFile {
+			t.Errorf("fs event for %v, want %s", event.Data["path"], configFile)
+		}
+		events = append(events, name)
+	}
+
+	expected := []string{"start::", "region_enter:command:config", "data:fs:read", "region_leave:command:config", "exit::"}
+	if len(events) != len(expected) {
+		t.Fatalf("trace events = %v, want %v", events, expected)
+	}
+	for i := range expected {
+		if events[i] != expected[i] {
+			t.Errorf("trace event %d = %s, want %s", i, events[i], expected[i])
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"jit/internal"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestUntrackedFiles(t *testing.T) {
+	workTree, err := os.MkdirTemp("", "untracked_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(workTree)
+
+	for _, file := range []string{
+		"main.go", "notes.txt", "src/app.go", "src/new.go",
+		"node_modules/a/index.js", "node_modules/b/index.js",
+		"logs/today.log", "empty/only/.keep.log",
+	} {
+		writeTestFile(t, filepath.Join(workTree, file), "content\n")
+	}
+	writeTestFile(t, filepath.Join(workTree, ".jitignore"), "*.log\n")
+	tracked := []string{"src/app.go", "main.go", ".jitignore"}
+
+	matcher, matcherErr := internal.NewIgnoreMatcher(workTree, "", "", false)
+	if matcherErr != nil {
+		t.Fatalf("NewIgnoreMatcher failed: %s", matcherErr)
+	}
+
+	tests := []struct {
+		mode     string
+		expected []string
+	}{
+		{mode: "no", expected: nil},
+		{mode: "", expected: []string{"node_modules/", "notes.txt", "src/new.go"}},
+		{mode: "normal", expected: []string{"node_modules/", "notes.txt", "src/new.go"}},
+		{mode: "all", expected: []string{"node_modules/a/index.js", "node_modules/b/index.js", "notes.txt", "src/new.go"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			mode, parseErr := internal.ParseUntrackedMode(tt.mode)
+			if parseErr != nil {
+				t.Fatalf("ParseUntrackedMode(%q) failed: %s", tt.mode, parseErr)
+			}
+			untracked, untrackedErr := internal.UntrackedFiles(context.Background(), workTree, tracked, mode, matcher)
+			if untrackedErr != nil {
+				t.Fatalf("UntrackedFiles failed: %s", untrackedErr)
+			}
+			if !reflect.DeepEqual(untracked, tt.expected) {
+				t.Errorf("UntrackedFiles(%q) = %v, want %v", tt.mode, untracked, tt.expected)
+			}
+		})
+	}
+
+	if _, parseErr := internal.ParseUntrackedMode("some"); !errors.Is(parseErr, internal.ErrInvalidUntrackedMode) {
+		t.Errorf("ParseUntrackedMode(\"some\") = %v, want ErrInvalidUntrackedMode", parseErr)
+	}
+}
@@ -0,0 +1,80 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"jit/internal"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWalkWorkTree(t *testing.T) {
+	workTree, err := os.MkdirTemp("", "walk_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(workTree)
+
+	var expected []string
+	for i := 0; i < 20; i++ {
+		for _, name := range []string{"a.go", "b.log"} {
+			file := fmt.Sprintf("pkg%02d/sub/%s", i, name)
+			writeTestFile(t, filepath.Join(workTree, file), "content\n")
+			if name == "a.go" {
+				expected = append(expected, file)
+			}
+		}
+	}
+	writeTestFile(t, filepath.Join(workTree, ".jitignore"), "*.log\nbuild/\n")
+	writeTestFile(t, filepath.Join(workTree, "build", "out.o"), "binary")
+	writeTestFile(t, filepath.Join(workTree, ".jit", "config"), "")
+	writeTestFile(t, filepath.Join(workTree, "vendor", "lib", ".jit", "HEAD"), "")
+	writeTestFile(t, filepath.Join(workTree, "vendor", "lib", "lib.go"), "package lib\n")
+	if err := os.Symlink("pkg00", filepath.Join(workTree, "link")); err != nil {
+		t.Fatalf("Failed to create symlink: %s", err)
+	}
+	expected = append([]string{".jitignore", "link"}, expected...)
+	expected = append(expected, "vendor/lib/lib.go")
+
+	matcher, matcherErr := internal.NewIgnoreMatcher(workTree, "", "", false)
+	if matcherErr != nil {
+		t.Fatalf("NewIgnoreMatcher failed: %s", matcherErr)
+	}
+
+	for _, workers := range []int{1, 8, 0} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			entries, walkErr := internal.WalkWorkTree(context.Background(), workTree, internal.WalkOptions{Ignore: matcher, Workers: workers})
+			if walkErr != nil {
+				t.Fatalf("WalkWorkTree failed: %s", walkErr)
+			}
+			var paths []string
+			for _, entry := range entries {
+				paths = append(paths, entry.Path)
+			}
+			if !reflect.DeepEqual(paths, expected) {
+				t.Errorf("WalkWorkTree() = %v, want %v", paths, expected)
+			}
+		})
+	}
+
+	entries, walkErr := internal.WalkWorkTree(context.Background(), workTree, internal.WalkOptions{})
+	if walkErr != nil {
+		t.Fatalf("WalkWorkTree without ignore rules failed: %s", walkErr)
+	}
+	if len(entries) != len(expected)+21 {
+		t.Errorf("WalkWorkTree() without ignore rules found %d entries, want %d", len(entries), len(expected)+21)
+	}
+
+	if _, missingErr := internal.WalkWorkTree(context.Background(), filepath.Join(workTree, "missing"), internal.WalkOptions{}); missingErr == nil {
+		t.Errorf("Expected an error walking a missing directory")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, canceledErr := internal.WalkWorkTree(ctx, workTree, internal.WalkOptions{}); !errors.Is(canceledErr, context.Canceled) {
+		t.Errorf("WalkWorkTree() with a canceled context = %v, want context.Canceled", canceledErr)
+	}
+}